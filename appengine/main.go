@@ -27,8 +27,41 @@ import (
 	"github.com/rsesek/usda-ndb/ndb"
 )
 
+// The files that dbio writes. The mapped database is served if it was uploaded,
+// since it starts faster and uses less memory than the GOB.
+const (
+	kGOBFile         = "./asciidb.gob.gz"
+	kMappedFile      = "./asciidb.mmap"
+	kSearchIndexFile = "./asciidb.search"
+)
+
 func init() {
-	f, err := os.Open("./asciidb.gob.gz")
+	var db ndb.Reader
+	if _, err := os.Stat(kMappedFile); err == nil {
+		db = openMappedDB()
+	} else if os.IsNotExist(err) {
+		db = readGOB()
+	} else {
+		panic(err)
+	}
+
+	server := frontend.NewServer(db, "__served_by_appengine__")
+	http.Handle("/", server)
+}
+
+func openMappedDB() *ndb.MappedDB {
+	db, err := ndb.OpenMappedDB(kMappedFile)
+	if err != nil {
+		panic(err)
+	}
+	if err := db.OpenSearchIndex(kSearchIndexFile); err != nil {
+		panic(err)
+	}
+	return db
+}
+
+func readGOB() *ndb.ASCIIDB {
+	f, err := os.Open(kGOBFile)
 	if err != nil {
 		panic(err)
 	}
@@ -46,8 +79,21 @@ func init() {
 		panic(err)
 	}
 
-	db.RebuildSearchIndex()
+	// Load the search index that dbio wrote, if there is one, since rebuilding
+	// it slows down startup.
+	if err := readSearchIndex(db, kSearchIndexFile); os.IsNotExist(err) {
+		db.RebuildSearchIndex()
+	} else if err != nil {
+		panic(err)
+	}
+	return db
+}
 
-	server := frontend.NewServer(db, "__served_by_appengine__")
-	http.Handle("/", server)
+func readSearchIndex(db *ndb.ASCIIDB, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return db.ReadSearchIndex(f)
 }
@@ -17,30 +17,94 @@
 
 package bst
 
-type Node struct {
-	left   *Node
-	right  *Node
-	value  string
-	tokens []string
+// A Node holds the values of the tree for a key.
+type Node[K any, V comparable] struct {
+	left   *Node[K, V]
+	right  *Node[K, V]
+	key    K
+	values []V
 }
 
-func MakeNode(p Pair) *Node {
-	n := &Node{value: p.Value}
+// MakeNode returns a Node for the search-key of |p|, with a posting of its
+// token.
+//
+// Deprecated: Nodes are made by inserting into a Tree or an OrderedTree.
+func MakeNode(p Pair) *Node[string, *Posting] {
+	n := &Node[string, *Posting]{key: p.Value}
 	n.InsertPair(p)
 	return n
 }
 
-func (n *Node) Value() string {
-	return n.value
+func (n *Node[K, V]) Key() K {
+	return n.key
 }
 
-func (n *Node) InsertPair(p Pair) {
-	if n.value != p.Value {
+// Value returns the key of the node.
+//
+// Deprecated: Use Key.
+func (n *Node[K, V]) Value() K {
+	return n.key
+}
+
+// InsertPair appends a posting of the token of |p| to a Node[string, *Posting]
+// for the search-key of |p|. A repeated token gets another posting. It panics
+// for any other Node.
+//
+// Deprecated: Insert into a Tree, which counts a repeated token in one posting.
+func (n *Node[K, V]) InsertPair(p Pair) {
+	if key, ok := any(n.key).(string); !ok || key != p.Value {
 		panic("Cannot insert a Pair into a Node that does not match Value")
 	}
-	n.tokens = append(n.tokens, p.Token)
+	posting, ok := any(&Posting{Token: p.Token, Frequency: 1}).(V)
+	if !ok {
+		panic("Cannot insert a Pair into a Node that does not hold Postings")
+	}
+	n.values = append(n.values, posting)
+}
+
+// Values returns the values of the node, in insertion order. The slice must not
+// be modified.
+func (n *Node[K, V]) Values() []V {
+	return n.values
+}
+
+// RemoveValue removes every occurrence of |value| from the node, and returns the
+// number removed.
+func (n *Node[K, V]) RemoveValue(value V) int {
+	values := n.values[:0]
+	for _, v := range n.values {
+		if v != value {
+			values = append(values, v)
+		}
+	}
+	removed := len(n.values) - len(values)
+	var zero V
+	for i := len(values); i < len(n.values); i++ {
+		n.values[i] = zero
+	}
+	n.values = values
+	return removed
+}
+
+// A Posting is the occurrences of a search-key in the document with an app-token.
+type Posting struct {
+	Token string
+	// The number of times the search-key was inserted for the token.
+	Frequency int
+	// Where the search-key occurs in the document, for the insertions that gave
+	// one.
+	Positions []Position
+}
+
+// A Position is where a search-key occurs in a document.
+type Position struct {
+	// The part of the document, as numbered by the app.
+	Field int
+	// The index of the word within the field.
+	Offset int
 }
 
+// A Pair is a search-key and app-token for a Tree.
 type Pair struct {
 	Value string
 	Token string
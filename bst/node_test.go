@@ -18,33 +18,34 @@
 package bst
 
 import (
+	"reflect"
 	"testing"
 )
 
 func TestMakeNode(t *testing.T) {
 	n := MakeNode(Pair{Value: "hello", Token: "world"})
 	expected := "hello"
-	if n.value != expected {
-		t.Errorf("Expected Value to be %q, got %q", expected, n.value)
+	if n.Value() != expected {
+		t.Errorf("Expected Value to be %q, got %q", expected, n.Value())
 	}
-	if len(n.tokens) != 1 {
-		t.Errorf("Expected 1 Tokens, got %d", len(n.tokens))
+	if len(n.values) != 1 {
+		t.Errorf("Expected 1 Tokens, got %d", len(n.values))
 	} else {
 		expected = "world"
-		if n.tokens[0] != expected {
-			t.Errorf("Expected Token to be %q, got %q", expected, n.tokens[0])
+		if n.values[0].Token != expected {
+			t.Errorf("Expected Token to be %q, got %q", expected, n.values[0].Token)
 		}
 	}
 }
 
 func TestNodeInsertPair(t *testing.T) {
 	const kValue = "hello"
-	node := &Node{value: kValue}
+	node := &Node[string, *Posting]{key: kValue}
 	node.InsertPair(Pair{Value: kValue, Token: "doc1"})
 	node.InsertPair(Pair{Value: kValue, Token: "doc2"})
 	node.InsertPair(Pair{Value: kValue, Token: "doc1"})
-	if len(node.tokens) != 3 {
-		t.Errorf("Expected 3 tokens, got %d", len(node.tokens))
+	if len(node.values) != 3 {
+		t.Errorf("Expected 3 tokens, got %d", len(node.values))
 	}
 
 	expectations := []struct {
@@ -57,11 +58,56 @@ func TestNodeInsertPair(t *testing.T) {
 	}
 	for i := 0; i < len(expectations); i++ {
 		expected := expectations[i]
-		actual := node.tokens[expected.index]
+		actual := node.values[expected.index].Token
 		if actual != expected.expected {
 			t.Errorf("At token index %d, expected %q, got %q", expected.index, expected.expected, actual)
 		}
 	}
+
+	for _, bad := range []func(){
+		func() { node.InsertPair(Pair{Value: "other", Token: "doc1"}) },
+		func() { (&Node[string, string]{key: kValue}).InsertPair(Pair{Value: kValue, Token: "doc1"}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected InsertPair to panic")
+				}
+			}()
+			bad()
+		}()
+	}
+}
+
+func TestNodeInsert(t *testing.T) {
+	const kValue = "hello"
+	tree := NewOrderedTree[string, string]()
+	tree.Insert(kValue, "doc1")
+	tree.Insert(kValue, "doc2")
+	tree.Insert(kValue, "doc1")
+	node := tree.root
+	if node.Key() != kValue {
+		t.Errorf("Expected Key to be %q, got %q", kValue, node.Key())
+	}
+	if len(node.Values()) != 3 {
+		t.Errorf("Expected 3 values, got %d", len(node.Values()))
+	}
+
+	expectations := []struct {
+		index    int
+		expected string
+	}{
+		{0, "doc1"},
+		{1, "doc2"},
+		{2, "doc1"},
+	}
+	for i := 0; i < len(expectations); i++ {
+		expected := expectations[i]
+		actual := node.values[expected.index]
+		if actual != expected.expected {
+			t.Errorf("At value index %d, expected %q, got %q", expected.index, expected.expected, actual)
+		}
+	}
 }
 
 func TestPairCompare(t *testing.T) {
@@ -87,9 +133,22 @@ func TestPairCompare(t *testing.T) {
 
 	p2.Value = "abd"
 	if c := p1.Less(p2); c {
-		t.Errorf("p1 is the same as p2, not less, got %d", c)
+		t.Errorf("p1 is the same as p2, not less, got %t", c)
 	}
 	if c := p1.Equal(p2); !c {
 		t.Errorf("p1 is the same as p2")
 	}
 }
+
+func TestNodeRemoveValue(t *testing.T) {
+	node := &Node[string, string]{key: "hello", values: []string{"doc1", "doc2", "doc1", "doc3"}}
+	if n := node.RemoveValue("doc1"); n != 2 {
+		t.Errorf("Expected to remove 2 values, removed %d", n)
+	}
+	if expected := []string{"doc2", "doc3"}; !reflect.DeepEqual(expected, node.values) {
+		t.Errorf("Expected values %v, got %v", expected, node.values)
+	}
+	if n := node.RemoveValue("doc4"); n != 0 {
+		t.Errorf("Expected to remove no values, removed %d", n)
+	}
+}
@@ -0,0 +1,244 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bst
+
+import (
+	"iter"
+	"slices"
+)
+
+// A Tree is an OrderedTree of search-keys and the postings of app-tokens for
+// them. Each token appears once per search-key, however many times it is
+// inserted, with a count of the insertions.
+type Tree struct {
+	tree *OrderedTree[string, *Posting]
+	// The postings of the search-keys that have more than kIndexedPostings,
+	// by token, so that inserting into them does not scan the postings.
+	index map[string]map[string]*Posting
+}
+
+// Search-keys with up to this many postings are scanned for a token rather than
+// indexed.
+const kIndexedPostings = 8
+
+func NewTree() *Tree {
+	return &Tree{
+		tree:  NewOrderedTree[string, *Posting](),
+		index: make(map[string]map[string]*Posting),
+	}
+}
+
+// Find returns the tokens for |value|, in the order they were first inserted, or
+// nil if there are none.
+func (t *Tree) Find(value string) []string {
+	return tokens(t.tree.Find(value))
+}
+
+// FindPostings returns copies of the postings for |value|, in the order their
+// tokens were first inserted, or nil if there are none.
+func (t *Tree) FindPostings(value string) []Posting {
+	postings := t.tree.Find(value)
+	if len(postings) == 0 {
+		return nil
+	}
+	copies := make([]Posting, len(postings))
+	for i, p := range postings {
+		copies[i] = Posting{
+			Token:     p.Token,
+			Frequency: p.Frequency,
+			Positions: slices.Clone(p.Positions),
+		}
+	}
+	return copies
+}
+
+// Insert adds an occurrence of the token for the search-key, without a position.
+func (t *Tree) Insert(p Pair) {
+	t.posting(p).Frequency++
+}
+
+// InsertAt adds an occurrence of the token for the search-key at |pos|.
+func (t *Tree) InsertAt(p Pair, pos Position) {
+	posting := t.posting(p)
+	posting.Frequency++
+	posting.Positions = append(posting.Positions, pos)
+}
+
+// InsertPosting adds the occurrences in |p| for the search-key |value|, as if
+// each had been inserted, for restoring a tree that was saved with AllPostings.
+func (t *Tree) InsertPosting(value string, p Posting) {
+	posting := t.posting(Pair{Value: value, Token: p.Token})
+	posting.Frequency += p.Frequency
+	posting.Positions = append(posting.Positions, p.Positions...)
+}
+
+// posting returns the posting for the pair, adding an empty one if needed.
+func (t *Tree) posting(p Pair) *Posting {
+	n := t.tree.nodeFor(p.Value)
+	// A token is usually inserted at several positions in a row.
+	if last := len(n.values) - 1; last >= 0 && n.values[last].Token == p.Token {
+		return n.values[last]
+	}
+	if posting := t.findPosting(n, p.Token); posting != nil {
+		return posting
+	}
+
+	posting := &Posting{Token: p.Token}
+	n.values = append(n.values, posting)
+	if index := t.index[p.Value]; index != nil {
+		index[p.Token] = posting
+	} else if len(n.values) > kIndexedPostings {
+		index = make(map[string]*Posting, len(n.values))
+		for _, q := range n.values {
+			index[q.Token] = q
+		}
+		t.index[p.Value] = index
+	}
+	return posting
+}
+
+// findPosting returns the posting of |token| in the node |n|, or nil.
+func (t *Tree) findPosting(n *Node[string, *Posting], token string) *Posting {
+	if index := t.index[n.key]; index != nil {
+		return index[token]
+	}
+	for _, posting := range n.values {
+		if posting.Token == token {
+			return posting
+		}
+	}
+	return nil
+}
+
+// Delete removes the node for |value| and all of its tokens. Returns false if
+// there is no such node.
+func (t *Tree) Delete(value string) bool {
+	delete(t.index, value)
+	return t.tree.Delete(value)
+}
+
+// RemoveToken removes |token|, with all of its occurrences, from the node for
+// |value|. If that leaves the node without tokens, the node is deleted. Returns
+// false if the node did not have the token.
+func (t *Tree) RemoveToken(value, token string) bool {
+	n := t.tree.findNode(value, t.tree.root)
+	if n == nil {
+		return false
+	}
+	posting := t.findPosting(n, token)
+	if posting == nil {
+		return false
+	}
+	if index := t.index[value]; index != nil {
+		delete(index, token)
+		if len(index) <= kIndexedPostings {
+			delete(t.index, value)
+		}
+	}
+	return t.tree.RemoveValue(value, posting)
+}
+
+// Ascend calls |f| with each search-key, in order, and its tokens, until |f|
+// returns false.
+func (t *Tree) Ascend(f func(value string, tokens []string) bool) {
+	t.tree.Ascend(func(value string, postings []*Posting) bool {
+		return f(value, tokens(postings))
+	})
+}
+
+// Descend calls |f| with each search-key, in reverse order, and its tokens,
+// until |f| returns false.
+func (t *Tree) Descend(f func(value string, tokens []string) bool) {
+	t.tree.Descend(func(value string, postings []*Posting) bool {
+		return f(value, tokens(postings))
+	})
+}
+
+// All returns an iterator over the search-keys, in order, and their tokens.
+func (t *Tree) All() iter.Seq2[string, []string] {
+	return tokenSeq(t.tree.All())
+}
+
+// Backward returns an iterator over the search-keys, in reverse order, and their
+// tokens.
+func (t *Tree) Backward() iter.Seq2[string, []string] {
+	return tokenSeq(t.tree.Backward())
+}
+
+// From returns an iterator over the search-keys from |value| onwards, in order,
+// and their tokens. This finds the keys with a prefix, for example.
+func (t *Tree) From(value string) iter.Seq2[string, []string] {
+	return tokenSeq(t.tree.From(value))
+}
+
+// BackwardFrom returns an iterator over the search-keys from |value| down, in
+// reverse order, and their tokens.
+func (t *Tree) BackwardFrom(value string) iter.Seq2[string, []string] {
+	return tokenSeq(t.tree.BackwardFrom(value))
+}
+
+// AllPostings returns an iterator over the search-keys, in order, and their
+// postings. The Positions of the postings must not be modified.
+func (t *Tree) AllPostings() iter.Seq2[string, []Posting] {
+	return func(yield func(string, []Posting) bool) {
+		t.tree.Ascend(func(value string, postings []*Posting) bool {
+			copies := make([]Posting, len(postings))
+			for i, p := range postings {
+				copies[i] = *p
+			}
+			return yield(value, copies)
+		})
+	}
+}
+
+// InOrderTokens returns a closed channel that holds all of the tokens, in
+// search-key order. No goroutine sends them, so the channel need not be drained.
+//
+// Deprecated: Use All, which does not copy the tokens first.
+func (t *Tree) InOrderTokens() <-chan string {
+	var tokens []string
+	t.tree.Ascend(func(_ string, postings []*Posting) bool {
+		for _, p := range postings {
+			tokens = append(tokens, p.Token)
+		}
+		return true
+	})
+	return bufferedChan(tokens)
+}
+
+// tokens returns the tokens of |postings|, or nil if there are none.
+func tokens(postings []*Posting) []string {
+	if len(postings) == 0 {
+		return nil
+	}
+	tokens := make([]string, len(postings))
+	for i, p := range postings {
+		tokens[i] = p.Token
+	}
+	return tokens
+}
+
+func tokenSeq(seq iter.Seq2[string, []*Posting]) iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		for value, postings := range seq {
+			if !yield(value, tokens(postings)) {
+				return
+			}
+		}
+	}
+}
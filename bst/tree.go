@@ -19,79 +19,278 @@
 // 2-tuples (search-key, app-token), with the search-key being the value that all
 // values in a node have in common. The app-token is used to refer back to some
 // object that is being searched for using the tree.
+//
+// OrderedTree is the tree for any type of key and value, and Tree is the tree
+// of strings that is used for text search.
 package bst
 
-type Tree struct {
-	root *Node
+import (
+	"cmp"
+	"iter"
+)
+
+// An OrderedTree maps each key to the list of values inserted for it, and keeps
+// the keys in order.
+type OrderedTree[K any, V comparable] struct {
+	root    *Node[K, V]
+	compare func(a, b K) int
 }
 
-func NewTree() *Tree {
-	return &Tree{}
+// NewOrderedTree creates a tree whose keys are in their natural order.
+func NewOrderedTree[K cmp.Ordered, V comparable]() *OrderedTree[K, V] {
+	return NewOrderedTreeFunc[K, V](cmp.Compare[K])
 }
 
-func (t *Tree) Find(value string) []string {
-	if node := t.findNode(value, t.root); node != nil {
-		return node.tokens
-	}
-	return nil
+// NewOrderedTreeFunc creates a tree whose keys are ordered by |compare|, which
+// returns a negative number, zero or a positive number if a < b, a == b or
+// a > b.
+func NewOrderedTreeFunc[K any, V comparable](compare func(a, b K) int) *OrderedTree[K, V] {
+	return &OrderedTree[K, V]{compare: compare}
 }
 
-func (t *Tree) findNode(value string, node *Node) *Node {
-	if node == nil || node.value == value {
-		return node
+// Find returns the values for |key|, or nil if there are none. The slice must
+// not be modified.
+func (t *OrderedTree[K, V]) Find(key K) []V {
+	if node := t.findNode(key, t.root); node != nil {
+		return node.values
 	}
+	return nil
+}
 
-	if value < node.value {
-		return t.findNode(value, node.left)
+func (t *OrderedTree[K, V]) findNode(key K, node *Node[K, V]) *Node[K, V] {
+	for node != nil {
+		c := t.compare(key, node.key)
+		if c == 0 {
+			return node
+		} else if c < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
 	}
-	return t.findNode(value, node.right)
+	return nil
 }
 
-func (t *Tree) Insert(p Pair) {
+// Insert adds |value| to the values for |key|.
+func (t *OrderedTree[K, V]) Insert(key K, value V) {
 	if t.root == nil {
-		t.root = MakeNode(p)
+		t.root = &Node[K, V]{key: key, values: []V{value}}
 	} else {
-		t.insertOn(p, t.root)
+		t.insertOn(key, value, t.root)
 	}
 }
 
-func (t *Tree) insertOn(p Pair, n *Node) {
-	if p.Value == n.value {
-		n.InsertPair(p)
-	} else if p.Value < n.value {
+func (t *OrderedTree[K, V]) insertOn(key K, value V, n *Node[K, V]) {
+	c := t.compare(key, n.key)
+	if c == 0 {
+		n.values = append(n.values, value)
+	} else if c < 0 {
 		if n.left == nil {
-			n.left = MakeNode(p)
+			n.left = &Node[K, V]{key: key, values: []V{value}}
 		} else {
-			t.insertOn(p, n.left)
+			t.insertOn(key, value, n.left)
 		}
 	} else {
 		if n.right == nil {
-			n.right = MakeNode(p)
+			n.right = &Node[K, V]{key: key, values: []V{value}}
 		} else {
-			t.insertOn(p, n.right)
+			t.insertOn(key, value, n.right)
 		}
 	}
 }
 
-func (t *Tree) InOrderTokens() <-chan string {
-	c := make(chan string)
-	sendTokens := func(n *Node) {
-		for _, token := range n.tokens {
-			c <- token
+// nodeFor returns the node for |key|, adding one without values if there is
+// none, in a single descent. The caller must give a new node a value.
+func (t *OrderedTree[K, V]) nodeFor(key K) *Node[K, V] {
+	link := &t.root
+	for *link != nil {
+		c := t.compare(key, (*link).key)
+		if c == 0 {
+			return *link
+		} else if c < 0 {
+			link = &(*link).left
+		} else {
+			link = &(*link).right
 		}
 	}
-	var sendSubtree func(n *Node)
-	sendSubtree = func(n *Node) {
+	*link = &Node[K, V]{key: key}
+	return *link
+}
+
+// Delete removes the node for |key| and all of its values. Returns false if
+// there is no such node.
+func (t *OrderedTree[K, V]) Delete(key K) bool {
+	var deleted bool
+	t.root, deleted = t.deleteFrom(key, t.root)
+	return deleted
+}
+
+// deleteFrom removes the node for |key| from the subtree rooted at |n|, and
+// returns the new root of the subtree.
+func (t *OrderedTree[K, V]) deleteFrom(key K, n *Node[K, V]) (*Node[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	var deleted bool
+	if c := t.compare(key, n.key); c < 0 {
+		n.left, deleted = t.deleteFrom(key, n.left)
+		return n, deleted
+	} else if c > 0 {
+		n.right, deleted = t.deleteFrom(key, n.right)
+		return n, deleted
+	}
+
+	// With at most one child, the child takes the place of the node.
+	if n.left == nil {
+		return n.right, true
+	} else if n.right == nil {
+		return n.left, true
+	}
+
+	// With two children, the in-order successor, which is the leftmost node
+	// of the right subtree, moves into the place of the node.
+	successor := n.right
+	for successor.left != nil {
+		successor = successor.left
+	}
+	n.key, n.values = successor.key, successor.values
+	n.right, _ = t.deleteFrom(successor.key, n.right)
+	return n, true
+}
+
+// RemoveValue removes |value| from the node for |key|. If that leaves the node
+// without values, the node is deleted. Returns false if the node did not have
+// the value.
+func (t *OrderedTree[K, V]) RemoveValue(key K, value V) bool {
+	node := t.findNode(key, t.root)
+	if node == nil || node.RemoveValue(value) == 0 {
+		return false
+	}
+	if len(node.values) == 0 {
+		t.Delete(key)
+	}
+	return true
+}
+
+// Range calls |f| with each key from |lo| to |hi| inclusive, in order, and its
+// values, until |f| returns false.
+func (t *OrderedTree[K, V]) Range(lo, hi K, f func(key K, values []V) bool) {
+	var walk func(n *Node[K, V]) bool
+	walk = func(n *Node[K, V]) bool {
 		if n == nil {
-			return
+			return true
+		}
+		cmpLo, cmpHi := t.compare(n.key, lo), t.compare(n.key, hi)
+		// The left subtree only has keys in range if this key is above |lo|,
+		// and the right subtree only if this key is below |hi|.
+		if cmpLo > 0 && !walk(n.left) {
+			return false
 		}
-		sendSubtree(n.left)
-		sendTokens(n)
-		sendSubtree(n.right)
-	}
-	go func() {
-		sendSubtree(t.root)
-		close(c)
-	}()
+		if cmpLo >= 0 && cmpHi <= 0 && !f(n.key, n.values) {
+			return false
+		}
+		if cmpHi < 0 {
+			return walk(n.right)
+		}
+		return true
+	}
+	walk(t.root)
+}
+
+// Ascend calls |f| with each key, in order, and its values, until |f| returns
+// false.
+func (t *OrderedTree[K, V]) Ascend(f func(key K, values []V) bool) {
+	t.ascend(t.root, nil, f)
+}
+
+// Descend calls |f| with each key, in reverse order, and its values, until |f|
+// returns false.
+func (t *OrderedTree[K, V]) Descend(f func(key K, values []V) bool) {
+	t.descend(t.root, nil, f)
+}
+
+// All returns an iterator over the keys, in order, and their values.
+func (t *OrderedTree[K, V]) All() iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		t.ascend(t.root, nil, yield)
+	}
+}
+
+// Backward returns an iterator over the keys, in reverse order, and their values.
+func (t *OrderedTree[K, V]) Backward() iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		t.descend(t.root, nil, yield)
+	}
+}
+
+// From returns an iterator over the keys from |key| onwards, in order, and
+// their values. |key| need not be in the tree.
+func (t *OrderedTree[K, V]) From(key K) iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		t.ascend(t.root, &key, yield)
+	}
+}
+
+// BackwardFrom returns an iterator over the keys from |key| down, in reverse
+// order, and their values. |key| need not be in the tree.
+func (t *OrderedTree[K, V]) BackwardFrom(key K) iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		t.descend(t.root, &key, yield)
+	}
+}
+
+// ascend calls |f| for the nodes of the subtree |n| with keys of at least |lo|,
+// or all of them if |lo| is nil, in order. Returns false if |f| did.
+func (t *OrderedTree[K, V]) ascend(n *Node[K, V], lo *K, f func(K, []V) bool) bool {
+	if n == nil {
+		return true
+	}
+	// If the node is below |lo|, so is its left subtree.
+	inRange := lo == nil || t.compare(n.key, *lo) >= 0
+	if inRange {
+		if !t.ascend(n.left, lo, f) || !f(n.key, n.values) {
+			return false
+		}
+	}
+	return t.ascend(n.right, lo, f)
+}
+
+// descend calls |f| for the nodes of the subtree |n| with keys of at most |hi|,
+// or all of them if |hi| is nil, in reverse order. Returns false if |f| did.
+func (t *OrderedTree[K, V]) descend(n *Node[K, V], hi *K, f func(K, []V) bool) bool {
+	if n == nil {
+		return true
+	}
+	// If the node is above |hi|, so is its right subtree.
+	inRange := hi == nil || t.compare(n.key, *hi) <= 0
+	if inRange {
+		if !t.descend(n.right, hi, f) || !f(n.key, n.values) {
+			return false
+		}
+	}
+	return t.descend(n.left, hi, f)
+}
+
+// InOrderValues returns a closed channel that holds all of the values, in key
+// order. No goroutine sends them, so the channel need not be drained.
+//
+// Deprecated: Use All, which does not copy the values first.
+func (t *OrderedTree[K, V]) InOrderValues() <-chan V {
+	var values []V
+	t.Ascend(func(_ K, vs []V) bool {
+		values = append(values, vs...)
+		return true
+	})
+	return bufferedChan(values)
+}
+
+// bufferedChan returns a closed channel that holds |values|.
+func bufferedChan[V any](values []V) <-chan V {
+	c := make(chan V, len(values))
+	for _, v := range values {
+		c <- v
+	}
+	close(c)
 	return c
 }
@@ -18,10 +18,23 @@
 package bst
 
 import (
+	"iter"
 	"reflect"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 )
 
+// postings returns a posting for each token, for building nodes by hand.
+func postings(tokens ...string) []*Posting {
+	var postings []*Posting
+	for _, token := range tokens {
+		postings = append(postings, &Posting{Token: token, Frequency: 1})
+	}
+	return postings
+}
+
 // InOrderTokens is used to test everything else, so make sure it works manually.
 func TestInOrderTokens(t *testing.T) {
 	/*
@@ -31,38 +44,38 @@ func TestInOrderTokens(t *testing.T) {
 				4		7			13
 	*/
 	tree := NewTree()
-	tree.root = &Node{
-		value:  "8",
-		tokens: []string{"g"},
-		left: &Node{
-			value:  "3",
-			tokens: []string{"b"},
-			left: &Node{
-				value:  "1",
-				tokens: []string{"a"},
+	tree.tree.root = &Node[string, *Posting]{
+		key:    "8",
+		values: postings("g"),
+		left: &Node[string, *Posting]{
+			key:    "3",
+			values: postings("b"),
+			left: &Node[string, *Posting]{
+				key:    "1",
+				values: postings("a"),
 			},
-			right: &Node{
-				value:  "6",
-				tokens: []string{"d", "e"},
-				left: &Node{
-					value:  "4",
-					tokens: []string{"c"},
+			right: &Node[string, *Posting]{
+				key:    "6",
+				values: postings("d", "e"),
+				left: &Node[string, *Posting]{
+					key:    "4",
+					values: postings("c"),
 				},
-				right: &Node{
-					value:  "7",
-					tokens: []string{"f"},
+				right: &Node[string, *Posting]{
+					key:    "7",
+					values: postings("f"),
 				},
 			},
 		},
-		right: &Node{
-			value:  "10",
-			tokens: []string{"h"},
-			right: &Node{
-				value:  "14",
-				tokens: []string{"k", "l"},
-				left: &Node{
-					value:  "13",
-					tokens: []string{"i", "j"},
+		right: &Node[string, *Posting]{
+			key:    "10",
+			values: postings("h"),
+			right: &Node[string, *Posting]{
+				key:    "14",
+				values: postings("k", "l"),
+				left: &Node[string, *Posting]{
+					key:    "13",
+					values: postings("i", "j"),
 				},
 			},
 		},
@@ -118,7 +131,7 @@ func TestInsert(t *testing.T) {
 			continue
 		}
 		if actual != expected[i] {
-			t.Errorf("Token %d should be %q, got %q", expected[i], actual)
+			t.Errorf("Token %d should be %q, got %q", i, expected[i], actual)
 		}
 		i++
 	}
@@ -157,7 +170,399 @@ func TestFind(t *testing.T) {
 	for _, expected := range expectations {
 		actual := tree.Find(expected.value)
 		if !reflect.DeepEqual(expected.tokens, actual) {
-			t.Errorf("When finding %q, expected %v, got %v", expected.tokens, actual)
+			t.Errorf("When finding %q, expected %v, got %v", expected.value, expected.tokens, actual)
+		}
+	}
+}
+
+func TestFindPostings(t *testing.T) {
+	tree := NewTree()
+	tree.InsertAt(Pair{"chicken", "soup"}, Position{Field: 0, Offset: 1})
+	tree.InsertAt(Pair{"chicken", "pie"}, Position{Field: 0, Offset: 0})
+	tree.InsertAt(Pair{"chicken", "soup"}, Position{Field: 2, Offset: 4})
+	tree.Insert(Pair{"chicken", "soup"})
+
+	// Each token is found once.
+	if expected := []string{"soup", "pie"}; !reflect.DeepEqual(expected, tree.Find("chicken")) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find("chicken"))
+	}
+
+	expected := []Posting{
+		{Token: "soup", Frequency: 3, Positions: []Position{{0, 1}, {2, 4}}},
+		{Token: "pie", Frequency: 1, Positions: []Position{{0, 0}}},
+	}
+	postings := tree.FindPostings("chicken")
+	if !reflect.DeepEqual(expected, postings) {
+		t.Errorf("Expected %+v, got %+v", expected, postings)
+	}
+
+	// The postings are copies.
+	postings[0].Positions[0].Offset = 99
+	if actual := tree.FindPostings("chicken")[0].Positions[0].Offset; actual != 1 {
+		t.Errorf("Expected the tree's posting to be unchanged, got offset %d", actual)
+	}
+
+	if tree.FindPostings("egg") != nil {
+		t.Errorf("Expected no postings for egg")
+	}
+
+	// Removing a token removes all of its occurrences.
+	if !tree.RemoveToken("chicken", "soup") {
+		t.Errorf("Expected to remove soup")
+	}
+	if expected := []string{"pie"}; !reflect.DeepEqual(expected, tree.Find("chicken")) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find("chicken"))
+	}
+}
+
+func TestFindPostingsManyTokens(t *testing.T) {
+	tree := NewTree()
+	const n = 100
+	// Insert each token twice, interleaved, so that most inserts are not of
+	// the last posting.
+	for round := 0; round < 2; round++ {
+		for i := 0; i < n; i++ {
+			tree.InsertAt(Pair{"cheese", strconv.Itoa(i)}, Position{Field: round, Offset: i})
+		}
+	}
+
+	postings := tree.FindPostings("cheese")
+	if len(postings) != n {
+		t.Fatalf("Expected %d postings, got %d", n, len(postings))
+	}
+	for i, p := range postings {
+		expected := Posting{Token: strconv.Itoa(i), Frequency: 2, Positions: []Position{{0, i}, {1, i}}}
+		if !reflect.DeepEqual(expected, p) {
+			t.Fatalf("Expected %+v, got %+v", expected, p)
+		}
+	}
+
+	// Removed tokens are gone from the index too, and can be added again.
+	for i := 0; i < n-2; i++ {
+		if !tree.RemoveToken("cheese", strconv.Itoa(i)) {
+			t.Fatalf("Expected to remove %d", i)
+		}
+	}
+	if tree.RemoveToken("cheese", "0") {
+		t.Errorf("Expected 0 to be removed already")
+	}
+	tree.Insert(Pair{"cheese", "0"})
+	if expected := []string{"98", "99", "0"}; !reflect.DeepEqual(expected, tree.Find("cheese")) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find("cheese"))
+	}
+
+	tree.Delete("cheese")
+	tree.Insert(Pair{"cheese", "5"})
+	if expected := []string{"5"}; !reflect.DeepEqual(expected, tree.Find("cheese")) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find("cheese"))
+	}
+}
+
+func TestInsertPosting(t *testing.T) {
+	tree := NewTree()
+	tree.InsertAt(Pair{"moo", "cow"}, Position{Field: 1, Offset: 2})
+	tree.Insert(Pair{"bark", "dog"})
+
+	// Restoring the postings into a new tree gives the same tree.
+	restored := NewTree()
+	for value, postings := range tree.AllPostings() {
+		for _, p := range postings {
+			restored.InsertPosting(value, p)
+		}
+	}
+	for _, value := range []string{"moo", "bark"} {
+		if expected, actual := tree.FindPostings(value), restored.FindPostings(value); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("For %q, expected %+v, got %+v", value, expected, actual)
+		}
+	}
+
+	// Postings for a token that is already there are merged.
+	restored.InsertPosting("moo", Posting{Token: "cow", Frequency: 2, Positions: []Position{{0, 0}}})
+	expected := []Posting{{Token: "cow", Frequency: 3, Positions: []Position{{1, 2}, {0, 0}}}}
+	if actual := restored.FindPostings("moo"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %+v, got %+v", expected, actual)
+	}
+}
+
+// makeTree builds the tree drawn in TestInOrderTokens by insertion, with the
+// numbers replaced by letters so that they sort the same way. The tokens are the
+// node values.
+func makeTree() *Tree {
+	tree := NewTree()
+	for _, v := range []string{"h", "c", "j", "a", "f", "n", "d", "g", "m"} {
+		tree.Insert(Pair{Value: v, Token: v})
+	}
+	return tree
+}
+
+func inOrder(tree *Tree) string {
+	var tokens string
+	for _, values := range tree.All() {
+		for _, token := range values {
+			tokens += token
+		}
+	}
+	return tokens
+}
+
+func TestDelete(t *testing.T) {
+	expectations := []struct {
+		value    string
+		expected string
+		root     string
+	}{
+		// A leaf.
+		{"d", "acfghjmn", "h"},
+		// A node with one child.
+		{"n", "acdfghjm", "h"},
+		// A node with two children.
+		{"c", "adfghjmn", "h"},
+		// The root, which has two children.
+		{"h", "acdfgjmn", "j"},
+	}
+	for _, e := range expectations {
+		tree := makeTree()
+		if !tree.Delete(e.value) {
+			t.Errorf("Expected to delete %q", e.value)
+		}
+		if actual := inOrder(tree); e.expected != actual {
+			t.Errorf("After deleting %q, expected %q, got %q", e.value, e.expected, actual)
+		}
+		if tree.tree.root.key != e.root {
+			t.Errorf("After deleting %q, expected root %q, got %q", e.value, e.root, tree.tree.root.key)
+		}
+		if tree.Find(e.value) != nil {
+			t.Errorf("Expected %q to be gone", e.value)
+		}
+	}
+
+	// Deleting a node with two children moves its successor into its place.
+	tree := makeTree()
+	tree.Delete("c")
+	if n := tree.tree.root.left; n.key != "d" || n.left.key != "a" || n.right.key != "f" || n.right.left != nil {
+		t.Errorf("Expected d to replace c with children a and f, got %+v", n)
+	}
+
+	if tree.Delete("e") {
+		t.Errorf("Expected no node for e")
+	}
+
+	tree = NewTree()
+	tree.Insert(Pair{Value: "only", Token: "a"})
+	if !tree.Delete("only") || tree.tree.root != nil {
+		t.Errorf("Expected deleting the only node to empty the tree")
+	}
+}
+
+func TestRemoveToken(t *testing.T) {
+	tree := NewTree()
+	tree.Insert(Pair{Value: "moo", Token: "cow"})
+	tree.Insert(Pair{Value: "bark", Token: "dog"})
+	tree.Insert(Pair{Value: "bark", Token: "seal"})
+	tree.Insert(Pair{Value: "hoot", Token: "owl"})
+
+	if !tree.RemoveToken("bark", "dog") {
+		t.Errorf("Expected to remove dog")
+	}
+	if expected := []string{"seal"}; !reflect.DeepEqual(expected, tree.Find("bark")) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find("bark"))
+	}
+	if tree.RemoveToken("bark", "dog") || tree.RemoveToken("baa", "sheep") {
+		t.Errorf("Expected no token to remove")
+	}
+
+	// Removing the last token prunes the node.
+	if !tree.RemoveToken("bark", "seal") {
+		t.Errorf("Expected to remove seal")
+	}
+	if tree.tree.findNode("bark", tree.tree.root) != nil {
+		t.Errorf("Expected the empty node to be pruned")
+	}
+	if expected := "owlcow"; expected != inOrder(tree) {
+		t.Errorf("Expected %q, got %q", expected, inOrder(tree))
+	}
+}
+
+func TestOrderedTreeRange(t *testing.T) {
+	// A nutrient value index: values per 100 g to NDBIDs.
+	tree := NewOrderedTree[float32, string]()
+	for _, p := range []struct {
+		value float32
+		id    string
+	}{
+		{81.11, "01001"},
+		{0.85, "01001b"},
+		{28.74, "01004"},
+		{0.37, "11090"},
+		{28.74, "01009"},
+		{12.5, "19999"},
+	} {
+		tree.Insert(p.value, p.id)
+	}
+
+	var actual []string
+	tree.Range(1, 30, func(key float32, values []string) bool {
+		actual = append(actual, values...)
+		return true
+	})
+	if expected := []string{"19999", "01004", "01009"}; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	// The bounds are inclusive, and returning false stops the range.
+	var keys []float32
+	tree.Range(0.37, 81.11, func(key float32, values []string) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	if expected := []float32{0.37, 0.85, 12.5}; !reflect.DeepEqual(expected, keys) {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
+	}
+}
+
+func TestTreeFunc(t *testing.T) {
+	// Keys in descending order.
+	tree := NewOrderedTreeFunc[int, string](func(a, b int) int { return b - a })
+	for i, v := range []string{"zero", "one", "two", "three"} {
+		tree.Insert(i, v)
+	}
+	var actual []string
+	for v := range tree.InOrderValues() {
+		actual = append(actual, v)
+	}
+	if expected := []string{"three", "two", "one", "zero"}; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	if expected := []string{"two"}; !reflect.DeepEqual(expected, tree.Find(2)) {
+		t.Errorf("Expected %v, got %v", expected, tree.Find(2))
+	}
+}
+
+// keys collects the keys of |seq|, stopping after |limit| if it is positive.
+func keys(seq iter.Seq2[string, []string], limit int) string {
+	var keys string
+	for key := range seq {
+		keys += key
+		if len(keys) == limit {
+			break
+		}
+	}
+	return keys
+}
+
+func TestIterators(t *testing.T) {
+	tree := makeTree()
+	expectations := []struct {
+		name     string
+		seq      iter.Seq2[string, []string]
+		expected string
+	}{
+		{"All", tree.All(), "acdfghjmn"},
+		{"Backward", tree.Backward(), "nmjhgfdca"},
+		{"From(f)", tree.From("f"), "fghjmn"},
+		{"From(e)", tree.From("e"), "fghjmn"},
+		{"From(z)", tree.From("z"), ""},
+		{"BackwardFrom(f)", tree.BackwardFrom("f"), "fdca"},
+		{"BackwardFrom(i)", tree.BackwardFrom("i"), "hgfdca"},
+		{"BackwardFrom(0)", tree.BackwardFrom("0"), ""},
+	}
+	for _, e := range expectations {
+		if actual := keys(e.seq, 0); e.expected != actual {
+			t.Errorf("%s: expected %q, got %q", e.name, e.expected, actual)
+		}
+		// Stopping early stops the walk.
+		if len(e.expected) > 2 {
+			if actual := keys(e.seq, 2); e.expected[:2] != actual {
+				t.Errorf("%s: expected %q after a break, got %q", e.name, e.expected[:2], actual)
+			}
+		}
+	}
+
+	for key, values := range tree.From("m") {
+		if expected := []string{key}; !reflect.DeepEqual(expected, values) {
+			t.Errorf("Expected %v for %q, got %v", expected, key, values)
+		}
+	}
+
+	if actual := keys(NewTree().All(), 0); actual != "" {
+		t.Errorf("Expected an empty tree to have no keys, got %q", actual)
+	}
+}
+
+func TestAscendDescend(t *testing.T) {
+	tree := makeTree()
+	var actual string
+	tree.Ascend(func(key string, values []string) bool {
+		actual += key
+		return key != "g"
+	})
+	if expected := "acdfg"; expected != actual {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+
+	actual = ""
+	tree.Descend(func(key string, values []string) bool {
+		actual += key
+		return key != "g"
+	})
+	if expected := "nmjhg"; expected != actual {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+// goroutinesAfter runs |f| and returns how many more goroutines there are
+// afterwards, giving any that are finishing a moment to exit.
+func goroutinesAfter(f func()) int {
+	before := runtime.NumGoroutine()
+	f()
+	for i := 0; i < 50 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	return runtime.NumGoroutine() - before
+}
+
+func TestIteratorsDoNotLeak(t *testing.T) {
+	tree := makeTree()
+	const loops = 100
+
+	leaked := goroutinesAfter(func() {
+		for i := 0; i < loops; i++ {
+			for range tree.All() {
+				break
+			}
+			for range tree.Backward() {
+				break
+			}
+			for range tree.From("d") {
+				break
+			}
+			tree.Ascend(func(string, []string) bool { return false })
+
+			next, stop := iter.Pull2(tree.All())
+			next()
+			stop()
+		}
+	})
+	if leaked > 0 {
+		t.Errorf("Expected breaking out of the iterators not to leak, but %d goroutines leaked", leaked)
+	}
+
+	// The channel API fills its channel before returning it, so it does not leak
+	// either.
+	values := NewOrderedTree[string, int]()
+	values.Insert("a", 1)
+	values.Insert("b", 2)
+	leaked = goroutinesAfter(func() {
+		for i := 0; i < loops; i++ {
+			for range tree.InOrderTokens() {
+				break
+			}
+			for range values.InOrderValues() {
+				break
+			}
 		}
+	})
+	if leaked > 0 {
+		t.Errorf("Expected breaking out of the channels not to leak, but %d goroutines leaked", leaked)
 	}
 }
@@ -0,0 +1,143 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+/*
+Command dbdiff reads two ASCII database releases and reports which foods were
+added, removed, renamed, or had their nutrient or weight values change.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+var (
+	oldPath   = flag.String("old", "", "The path to the ASCII database dumps of the old release.")
+	newPath   = flag.String("new", "", "The path to the ASCII database dumps of the new release.")
+	format    = flag.String("format", "text", "The output format, either text or json.")
+	tolerance = flag.Float64("tolerance", 0, "Numeric values that differ by no more than this are considered equal.")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	if *oldPath == "" || *newPath == "" {
+		fmt.Fprintln(os.Stderr, "Both -old and -new must be specified")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown -format %q\n", *format)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	log.Printf("Reading old database from %s", *oldPath)
+	oldDB, err := ndb.ReadDatabase(*oldPath)
+	if err != nil {
+		log.Fatalf("ndb.ReadDatabase: %v", err)
+	}
+
+	log.Printf("Reading new database from %s", *newPath)
+	newDB, err := ndb.ReadDatabase(*newPath)
+	if err != nil {
+		log.Fatalf("ndb.ReadDatabase: %v", err)
+	}
+
+	diff := ndb.Diff(oldDB, newDB, *tolerance)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			log.Fatalf("json.Encode: %v", err)
+		}
+	} else {
+		writeText(w, diff)
+	}
+}
+
+// writeText prints a human-readable form of the diff.
+func writeText(w io.Writer, diff *ndb.DatabaseDiff) {
+	if diff.Empty() {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(w, "Added %d foods:\n", len(diff.Added))
+		for _, food := range diff.Added {
+			fmt.Fprintf(w, "  + %s %s\n", food.NDBID, food.LongDescription)
+		}
+	}
+
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(w, "Removed %d foods:\n", len(diff.Removed))
+		for _, food := range diff.Removed {
+			fmt.Fprintf(w, "  - %s %s\n", food.NDBID, food.LongDescription)
+		}
+	}
+
+	if len(diff.Changed) > 0 {
+		fmt.Fprintf(w, "Changed %d foods:\n", len(diff.Changed))
+		for _, fd := range diff.Changed {
+			fmt.Fprintf(w, "  ~ %s %s\n", fd.NDBID, fd.Description)
+			for _, f := range fd.Fields {
+				fmt.Fprintf(w, "      %s: %q -> %q\n", f.Field, f.Old, f.New)
+			}
+			for _, n := range fd.Nutrients {
+				switch {
+				case n.Old == nil:
+					fmt.Fprintf(w, "      Nutrient %d: added %g\n", n.NutrientID, n.New.Value)
+				case n.New == nil:
+					fmt.Fprintf(w, "      Nutrient %d: removed %g\n", n.NutrientID, n.Old.Value)
+				default:
+					fmt.Fprintf(w, "      Nutrient %d: %g -> %g (data points %d -> %d)\n",
+						n.NutrientID, n.Old.Value, n.New.Value, n.Old.DataPoints, n.New.DataPoints)
+				}
+			}
+			for _, wc := range fd.Weights {
+				switch {
+				case wc.Old == nil:
+					fmt.Fprintf(w, "      Weight %d: added %g %s = %gg\n",
+						wc.Sequence, wc.New.Amount, wc.New.Description, wc.New.WeightG)
+				case wc.New == nil:
+					fmt.Fprintf(w, "      Weight %d: removed %g %s = %gg\n",
+						wc.Sequence, wc.Old.Amount, wc.Old.Description, wc.Old.WeightG)
+				default:
+					fmt.Fprintf(w, "      Weight %d: %g %s = %gg -> %g %s = %gg\n", wc.Sequence,
+						wc.Old.Amount, wc.Old.Description, wc.Old.WeightG,
+						wc.New.Amount, wc.New.Description, wc.New.WeightG)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+/*
+Command dbexport reads an ASCII database and writes a selection of its foods,
+with their nutrient values, as a CSV or TSV spreadsheet.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rsesek/usda-ndb/export"
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+var (
+	asciidb   = flag.String("asciidb", "", "The path to the ASCII database dumps.")
+	output    = flag.String("output", "", "The path to the output file. Defaults to stdout.")
+	format    = flag.String("format", "csv", "The output format, either csv or tsv.")
+	query     = flag.String("q", "", "Only export foods matching every term of this search query.")
+	group     = flag.Int("group", 0, "Only export foods in this food group code.")
+	ids       = flag.String("ids", "", "Only export the foods with these comma-separated NDBIDs.")
+	nutrients = flag.String("nutrients", "", "The comma-separated NutrientIDs to export. Defaults to all.")
+	measure   = flag.String("measure", "", "Report values for this household measure, e.g. cup, rather than per 100 g.")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	if *asciidb == "" {
+		fmt.Fprintln(os.Stderr, "No -asciidb specified")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	comma, err := export.FormatComma(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	nutrientIDs, err := export.ParseNutrients(*nutrients)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	log.Printf("Reading database from %s", *asciidb)
+	db, err := ndb.ReadDatabase(*asciidb)
+	if err != nil {
+		log.Fatalf("ndb.ReadDatabase: %v", err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatalf("os.Create: %v", err)
+		}
+		defer out.Close()
+	}
+
+	selected := export.SelectFoods(db, export.Selection{
+		Query:     *query,
+		FoodGroup: *group,
+		IDs:       export.ParseIDs(*ids),
+	})
+	log.Printf("Exporting %d foods", len(selected))
+
+	err = export.WriteFoods(out, db, selected, export.Options{
+		Comma:     comma,
+		Nutrients: nutrientIDs,
+		Measure:   *measure,
+	})
+	if err != nil {
+		log.Fatalf("export.WriteFoods: %v", err)
+	}
+}
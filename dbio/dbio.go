@@ -19,12 +19,23 @@ package main
 
 /*
 Command dbio reads an ASCII database into a github.com/rsesek/usda-ndb/ndb.ASCII object
-and writes it back out as compressed GOB file.
+and writes it back out as compressed GOB file, with its search index alongside.
+
+The -format flag selects another output format:
+
+	gob    GOB-encoded and GZip-compressed, for the appengine server.
+	jsonl  JSON Lines: a line for each food group, nutrient and food.
+	proto  A Protocol Buffers ndb.Database message, from ndbpb/ndb.proto.
+	cbor   The ASCIIDB encoded as CBOR, like the GOB.
+	mmap   The format of ndb.OpenMappedDB, which is memory-mapped rather than
+	       decoded when it is opened.
+
+With -verify, dbio reads the output back and checks that it matches the database
+field by field. It reports the number of rows of each table in the database, and
+in the output when verifying.
 */
 
 import (
-	"compress/gzip"
-	"encoding/gob"
 	"flag"
 	"fmt"
 	"log"
@@ -35,7 +46,10 @@ import (
 
 var (
 	asciidb = flag.String("asciidb", "", "The path to the ASCII database dumps.")
-	output  = flag.String("output", "asciidb.gob.gz", "The path to the output file.")
+	output  = flag.String("output", "", "The path to the output file. Defaults to asciidb with the extension of the -format.")
+	format  = flag.String("format", "gob", "The format of the output file: gob, jsonl, proto, cbor or mmap.")
+	index   = flag.String("search-index", "asciidb.search", "The path to write the search index to, or empty for none.")
+	verify  = flag.Bool("verify", false, "Read the output back and check that it matches the database.")
 )
 
 func main() {
@@ -48,11 +62,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *output == "" {
-		fmt.Fprintln(os.Stderr, "No -output specified")
+	f, ok := formats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown -format %q\n", *format)
 		flag.Usage()
 		os.Exit(1)
 	}
+	if *output == "" {
+		*output = "asciidb" + f.extension
+	}
 
 	log.Printf("Reading database from %s", *asciidb)
 	db, err := ndb.ReadDatabase(*asciidb)
@@ -60,21 +78,62 @@ func main() {
 		log.Fatalf("ndb.ReadDatabase: %v", err)
 	}
 
-	f, err := os.Create(*output)
-	if err != nil {
-		log.Fatalf("os.Open: %v", err)
+	log.Printf("Writing %s to %s", *format, *output)
+	if err := writeFile(*output, func(w *os.File) error { return f.write(db, w) }); err != nil {
+		log.Fatal(err)
+	}
+	if dropped := unexportedFields(db); len(dropped) > 0 {
+		log.Printf("Not written, as they are unexported: %v. The search index is written by -search-index.", dropped)
 	}
-	defer f.Close()
-
-	log.Printf("Writing compressed stream to %s", *output)
 
-	w := gzip.NewWriter(f)
-	defer w.Close()
+	if *index != "" {
+		log.Printf("Writing search index to %s", *index)
+		if err := writeFile(*index, func(w *os.File) error { return db.WriteSearchIndex(w) }); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	enc := gob.NewEncoder(w)
-	if err := enc.Encode(db); err != nil {
-		log.Fatalf("gob.Encode: %v", err)
+	counts := countTables(db)
+	if *verify {
+		log.Printf("Verifying %s", *output)
+		actual, diffs, err := verifyOutput(db, f, *output)
+		if err != nil {
+			log.Fatalf("Reading %s: %v", *output, err)
+		}
+		printCounts(os.Stdout, counts, actual)
+		if len(diffs) > 0 {
+			for _, d := range diffs {
+				fmt.Println(d)
+			}
+			log.Fatalf("%s does not match the database", *output)
+		}
+		log.Print("The output matches the database")
+	} else {
+		printCounts(os.Stdout, counts, nil)
 	}
 
 	log.Print("***** Done *****")
 }
+
+// verifyOutput reads |file| back with |f|, and returns the counts of its tables
+// and up to 20 differences from |db|.
+func verifyOutput(db *ndb.ASCIIDB, f outputFormat, file string) ([]tableCount, []string, error) {
+	actual, err := f.read(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return countTables(actual), compareDatabases(db, actual, 20), nil
+}
+
+// writeFile creates |file| and calls |write| to fill it.
+func writeFile(file string, write func(f *os.File) error) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+const kTestDatabase = "../ndb/testdata/sr"
+
+// writeTestOutput writes |db| in the format |name| to a temporary file.
+func writeTestOutput(t *testing.T, db *ndb.ASCIIDB, name string) string {
+	f := formats[name]
+	file := filepath.Join(t.TempDir(), "asciidb"+f.extension)
+	if err := writeFile(file, func(w *os.File) error { return f.write(db, w) }); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return file
+}
+
+// formatNames returns the names of the formats, sorted.
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestVerifyOutput(t *testing.T) {
+	db, err := ndb.ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range formatNames() {
+		counts, diffs, err := verifyOutput(db, formats[name], writeTestOutput(t, db, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(diffs) > 0 {
+			t.Errorf("%s: expected no differences, got %v", name, diffs)
+		}
+		if expected := countTables(db); !reflect.DeepEqual(expected, counts) {
+			t.Errorf("%s: expected the counts %v, got %v", name, expected, counts)
+		}
+	}
+}
+
+func TestVerifyOutputCorrupt(t *testing.T) {
+	db, err := ndb.ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range formatNames() {
+		file := writeTestOutput(t, db, name)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A file that is cut short, or has a byte changed, fails to decode or
+		// does not match.
+		flipped := append([]byte(nil), data...)
+		flipped[len(data)/2] ^= 0xff
+		changed := map[string][]byte{
+			"cut":     data[:len(data)/2],
+			"flipped": flipped,
+		}
+		// So does one that decodes but has a wrong value, in the formats where
+		// the description is not compressed.
+		if i := bytes.Index(data, []byte("Butter, salted")); i >= 0 {
+			wrong := append([]byte(nil), data...)
+			wrong[i] = 'b'
+			changed["wrong"] = wrong
+		} else if name != "gob" {
+			t.Errorf("%s: expected the description in the file", name)
+		}
+
+		for how, data := range changed {
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, diffs, err := verifyOutput(db, formats[name], file)
+			if err == nil && len(diffs) == 0 {
+				t.Errorf("%s: expected the %s file to fail verification", name, how)
+			}
+			if how == "wrong" && err != nil {
+				t.Errorf("%s: expected the wrong value to decode, got %v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbpb"
+)
+
+// An outputFormat writes a database to a file, and reads it back for -verify.
+type outputFormat struct {
+	extension string
+	write     func(db *ndb.ASCIIDB, w io.Writer) error
+	read      func(file string) (*ndb.ASCIIDB, error)
+}
+
+var formats = map[string]outputFormat{
+	"gob":   {".gob.gz", writeGob, readGob},
+	"jsonl": {".jsonl", writeJSONL, readJSONL},
+	"proto": {".pb", writeProto, readProto},
+	"cbor":  {".cbor", writeCBOR, readCBOR},
+	"mmap":  {".mmap", ndb.WriteMappedDB, readMapped},
+}
+
+// writeGob writes |db| as a compressed GOB stream.
+func writeGob(db *ndb.ASCIIDB, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(db); err != nil {
+		return fmt.Errorf("gob.Encode: %v", err)
+	}
+	return zw.Close()
+}
+
+func readGob(file string) (*ndb.ASCIIDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var db *ndb.ASCIIDB
+	if err := gob.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("gob.Decode: %v", err)
+	}
+	return db, nil
+}
+
+// A jsonlRecord is a line of the JSON Lines format, which has one of the fields.
+type jsonlRecord struct {
+	FoodGroup *ndb.FoodGroup `json:",omitempty"`
+	Nutrient  *ndb.Nutrient  `json:",omitempty"`
+	Food      *ndb.Food      `json:",omitempty"`
+}
+
+// writeJSONL writes a line for each food group, nutrient and food, in that
+// order, with the foods sorted by NDBID.
+func writeJSONL(db *ndb.ASCIIDB, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := range db.FoodGroups {
+		if err := enc.Encode(jsonlRecord{FoodGroup: &db.FoodGroups[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range db.Nutrients {
+		if err := enc.Encode(jsonlRecord{Nutrient: &db.Nutrients[i]}); err != nil {
+			return err
+		}
+	}
+	for _, id := range db.FoodIDs() {
+		if food, ok := db.Food(id); ok {
+			if err := enc.Encode(jsonlRecord{Food: food}); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func readJSONL(file string) (*ndb.ASCIIDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := &ndb.ASCIIDB{Foods: make(map[string]*ndb.Food)}
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for line := 1; ; line++ {
+		var rec jsonlRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, line, err)
+		}
+		switch {
+		case rec.FoodGroup != nil:
+			db.FoodGroups = append(db.FoodGroups, *rec.FoodGroup)
+		case rec.Nutrient != nil:
+			db.Nutrients = append(db.Nutrients, *rec.Nutrient)
+		case rec.Food != nil:
+			db.Foods[rec.Food.NDBID] = rec.Food
+		default:
+			return nil, fmt.Errorf("%s:%d: no FoodGroup, Nutrient or Food", file, line)
+		}
+	}
+	return db, nil
+}
+
+func writeProto(db *ndb.ASCIIDB, w io.Writer) error {
+	data, err := proto.Marshal(ndbpb.FromDatabase(db))
+	if err != nil {
+		return fmt.Errorf("proto.Marshal: %v", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readProto(file string) (*ndb.ASCIIDB, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pb := &ndbpb.Database{}
+	if err := proto.Unmarshal(data, pb); err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %v", err)
+	}
+	return ndbpb.ToDatabase(pb), nil
+}
+
+func writeCBOR(db *ndb.ASCIIDB, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if err := cbor.NewEncoder(bw).Encode(db); err != nil {
+		return fmt.Errorf("cbor.Encode: %v", err)
+	}
+	return bw.Flush()
+}
+
+func readCBOR(file string) (*ndb.ASCIIDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var db *ndb.ASCIIDB
+	if err := cbor.NewDecoder(bufio.NewReader(f)).Decode(&db); err != nil {
+		return nil, fmt.Errorf("cbor.Decode: %v", err)
+	}
+	return db, nil
+}
+
+// readMapped decodes all of the foods of a mapped database.
+func readMapped(file string) (*ndb.ASCIIDB, error) {
+	mapped, err := ndb.OpenMappedDB(file)
+	if err != nil {
+		return nil, err
+	}
+	defer mapped.Close()
+
+	db := &ndb.ASCIIDB{
+		FoodGroups: mapped.FoodGroups,
+		Nutrients:  mapped.Nutrients,
+		Foods:      make(map[string]*ndb.Food, mapped.Len()),
+	}
+	for _, id := range mapped.FoodIDs() {
+		food, ok := mapped.Food(id)
+		if !ok {
+			return nil, fmt.Errorf("%s: cannot decode food %s", file, id)
+		}
+		db.Foods[id] = food
+	}
+	return db, nil
+}
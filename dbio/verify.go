@@ -0,0 +1,183 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// A tableCount is the number of rows of a table of the SR release.
+type tableCount struct {
+	table string
+	count int
+}
+
+func countTables(db *ndb.ASCIIDB) []tableCount {
+	var nutrients, weights, ingredients, footnotes, branded int
+	for _, food := range db.Foods {
+		nutrients += len(food.Nutrients)
+		weights += len(food.Weights)
+		footnotes += len(food.Footnotes)
+		if food.Ingredients != "" {
+			ingredients++
+		}
+		if food.Branded != nil {
+			branded++
+		}
+	}
+	return []tableCount{
+		{"FD_GROUP", len(db.FoodGroups)},
+		{"NUTR_DEF", len(db.Nutrients)},
+		{"FOOD_DES", len(db.Foods)},
+		{"NUT_DATA", nutrients},
+		{"WEIGHT", weights},
+		{"INGREDIENTS", ingredients},
+		{"FOOTNOTE", footnotes},
+		{"Branded foods", branded},
+	}
+}
+
+// printCounts writes a table of the |source| counts, and the |output| counts if
+// they are not nil.
+func printCounts(w io.Writer, source, output []tableCount) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	if output == nil {
+		fmt.Fprintln(tw, "Table\tRows\t")
+	} else {
+		fmt.Fprintln(tw, "Table\tSource\tOutput\t")
+	}
+	for i, c := range source {
+		if output == nil {
+			fmt.Fprintf(tw, "%s\t%d\t\n", c.table, c.count)
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t\n", c.table, c.count, output[i].count)
+		}
+	}
+	tw.Flush()
+}
+
+// unexportedFields returns the paths of the unexported fields of |v| and of the
+// types it contains, which the encoders skip without an error.
+func unexportedFields(v interface{}) []string {
+	var fields []string
+	seen := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			walk(t.Elem())
+			return
+		case reflect.Struct:
+		default:
+			return
+		}
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.IsExported() {
+				walk(f.Type)
+			} else {
+				fields = append(fields, t.Name()+"."+f.Name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(v))
+	return fields
+}
+
+// compareDatabases compares the exported fields of |expected| and |actual| and
+// returns up to |limit| differences, and a count of the rest.
+func compareDatabases(expected, actual *ndb.ASCIIDB, limit int) []string {
+	var diffs []string
+	more := 0
+	report := func(path string, e, a interface{}) {
+		if len(diffs) < limit {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %v, got %v", path, e, a))
+		} else {
+			more++
+		}
+	}
+	compareValues("ASCIIDB", reflect.ValueOf(expected).Elem(), reflect.ValueOf(actual).Elem(), report)
+	if more > 0 {
+		diffs = append(diffs, fmt.Sprintf("... and %d more differences", more))
+	}
+	return diffs
+}
+
+// compareValues calls |report| for each difference between |e| and |a|, with
+// the path to it. Nil and empty slices are the same, as not every format
+// distinguishes them. Maps must have string keys.
+func compareValues(path string, e, a reflect.Value, report func(path string, e, a interface{})) {
+	switch e.Kind() {
+	case reflect.Ptr:
+		if e.IsNil() || a.IsNil() {
+			if e.IsNil() != a.IsNil() {
+				report(path, e.Interface(), a.Interface())
+			}
+			return
+		}
+		compareValues(path, e.Elem(), a.Elem(), report)
+	case reflect.Struct:
+		for i := 0; i < e.NumField(); i++ {
+			if f := e.Type().Field(i); f.IsExported() {
+				compareValues(path+"."+f.Name, e.Field(i), a.Field(i), report)
+			}
+		}
+	case reflect.Slice:
+		if e.Len() != a.Len() {
+			report(path+" length", e.Len(), a.Len())
+			return
+		}
+		for i := 0; i < e.Len(); i++ {
+			compareValues(fmt.Sprintf("%s[%d]", path, i), e.Index(i), a.Index(i), report)
+		}
+	case reflect.Map:
+		keys := make([]string, 0, e.Len())
+		for _, k := range e.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := reflect.ValueOf(k)
+			av := a.MapIndex(key)
+			if !av.IsValid() {
+				report(fmt.Sprintf("%s[%s]", path, k), "present", "missing")
+				continue
+			}
+			compareValues(fmt.Sprintf("%s[%s]", path, k), e.MapIndex(key), av, report)
+		}
+		for _, k := range a.MapKeys() {
+			if !e.MapIndex(k).IsValid() {
+				report(fmt.Sprintf("%s[%s]", path, k), "missing", "present")
+			}
+		}
+	default:
+		if e.Interface() != a.Interface() {
+			report(path, e.Interface(), a.Interface())
+		}
+	}
+}
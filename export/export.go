@@ -0,0 +1,292 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package export writes foods and their nutrient values as spreadsheet rows,
+// in either CSV or TSV format. Rows are written as they are produced, so that
+// exporting the full database does not buffer the output in memory.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// A Selection chooses the foods to export. The criteria are combined, so a
+// food must match all of the ones that are set. An empty Selection selects the
+// whole database.
+type Selection struct {
+	// A search query. Every term of the query must match the food.
+	Query string
+	// A food group code.
+	FoodGroup int
+	// A list of NDBIDs.
+	IDs []string
+}
+
+// Options controls the columns and format of the export.
+type Options struct {
+	// The field separator, either ',' for CSV or '\t' for TSV.
+	Comma rune
+	// The NutrientIDs to write as columns, in order. If empty, all nutrients
+	// are written in their report SortOrder.
+	Nutrients []int
+	// The household measure to report values for, e.g. "cup". Values are for
+	// the first of the food's Weights whose description contains this string,
+	// and are left blank for foods without such a measure. If empty, values
+	// are per 100 grams.
+	Measure string
+}
+
+// SelectFoods returns the NDBIDs of the foods in |db| matched by |sel|, sorted
+// by NDBID.
+func SelectFoods(db ndb.Reader, sel Selection) []string {
+	var candidates map[string]bool
+
+	// Intersect the criteria, starting with the most selective.
+	intersect := func(ids []string) {
+		next := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if candidates == nil || candidates[id] {
+				next[id] = true
+			}
+		}
+		candidates = next
+	}
+
+	if len(sel.IDs) > 0 {
+		intersect(sel.IDs)
+	}
+	for _, term := range strings.Fields(strings.ToLower(sel.Query)) {
+		intersect(db.FindFood(term))
+	}
+
+	var ids []string
+	if candidates == nil {
+		ids = db.FoodIDs()
+	} else {
+		ids = make([]string, 0, len(candidates))
+		for id := range candidates {
+			ids = append(ids, id)
+		}
+	}
+
+	// Drop unknown IDs and apply the food group filter.
+	selected := ids[:0]
+	for _, id := range ids {
+		food, ok := db.Food(id)
+		if !ok {
+			continue
+		}
+		if sel.FoodGroup != 0 && food.FoodGroup != sel.FoodGroup {
+			continue
+		}
+		selected = append(selected, id)
+	}
+	sort.Strings(selected)
+	return selected
+}
+
+// A Writer writes one spreadsheet row per food, after a header row naming the
+// columns.
+type Writer struct {
+	cw          *csv.Writer
+	opts        Options
+	columns     []ndb.Nutrient
+	row         []string
+	wroteHeader bool
+}
+
+// NewWriter creates a Writer that writes to |w| using the nutrient definitions
+// from |db|. Returns an error if the Options are invalid.
+func NewWriter(w io.Writer, db ndb.Reader, opts Options) (*Writer, error) {
+	columns, err := nutrientColumns(db, opts.Nutrients)
+	if err != nil {
+		return nil, err
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+
+	return &Writer{
+		cw:      cw,
+		opts:    opts,
+		columns: columns,
+		row:     make([]string, 5+len(columns)),
+	}, nil
+}
+
+func (w *Writer) writeHeader() error {
+	w.wroteHeader = true
+	header := []string{"NDBID", "Description", "FoodGroup", "Measure", "Grams"}
+	for _, n := range w.columns {
+		header = append(header, fmt.Sprintf("%s (%s)", n.Description, n.Units))
+	}
+	return w.cw.Write(header)
+}
+
+// Write writes the row for |food|.
+func (w *Writer) Write(food *ndb.Food) error {
+	if !w.wroteHeader {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	grams := float32(100)
+	measure := "100 g"
+	if w.opts.Measure != "" {
+		if weight := food.FindWeight(w.opts.Measure); weight != nil {
+			grams = weight.WeightG
+			measure = fmt.Sprintf("%g %s", weight.Amount, weight.Description)
+		} else {
+			grams = 0
+			measure = ""
+		}
+	}
+
+	row := w.row
+	row[0] = food.NDBID
+	row[1] = food.LongDescription
+	row[2] = strconv.Itoa(food.FoodGroup)
+	row[3] = measure
+	row[4] = ""
+	if grams != 0 {
+		row[4] = formatValue(grams)
+	}
+	for i, n := range w.columns {
+		row[5+i] = ""
+		if grams == 0 {
+			continue
+		}
+		for _, fn := range food.Nutrients {
+			if fn.NutrientID == n.NutrientID {
+				// N = (V*W) / 100, see ndb.Weight.
+				row[5+i] = formatValue(float32(float64(fn.Value) * float64(grams) / 100))
+				break
+			}
+		}
+	}
+	return w.cw.Write(row)
+}
+
+// Flush writes any buffered rows, and the header if no rows were written.
+func (w *Writer) Flush() error {
+	if !w.wroteHeader {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+// WriteFoods writes the rows for the foods in |ids| to |w|, looking them up in
+// |db|, and flushes the output.
+func WriteFoods(w io.Writer, db ndb.Reader, ids []string, opts Options) error {
+	ew, err := NewWriter(w, db, opts)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if food, ok := db.Food(id); ok {
+			if err := ew.Write(food); err != nil {
+				return err
+			}
+		}
+	}
+	return ew.Flush()
+}
+
+// nutrientColumns returns the Nutrient definitions for the |ids|, or all of
+// the nutrients in SortOrder if |ids| is empty.
+func nutrientColumns(db ndb.Reader, ids []int) ([]ndb.Nutrient, error) {
+	if len(ids) == 0 {
+		columns := make([]ndb.Nutrient, len(db.AllNutrients()))
+		copy(columns, db.AllNutrients())
+		sort.Slice(columns, func(i, j int) bool {
+			return columns[i].SortOrder < columns[j].SortOrder
+		})
+		return columns, nil
+	}
+
+	columns := make([]ndb.Nutrient, 0, len(ids))
+	for _, id := range ids {
+		found := false
+		for _, n := range db.AllNutrients() {
+			if n.NutrientID == id {
+				columns = append(columns, n)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown nutrient %d", id)
+		}
+	}
+	return columns, nil
+}
+
+// FormatComma returns the field separator for the |format| name, which is
+// either "csv" or "tsv".
+func FormatComma(format string) (rune, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		return ',', nil
+	case "tsv":
+		return '\t', nil
+	}
+	return 0, fmt.Errorf("Unknown export format %q", format)
+}
+
+// ParseNutrients parses a comma-separated list of NutrientIDs.
+func ParseNutrients(list string) ([]int, error) {
+	var ids []int
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid nutrient %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ParseIDs parses a comma-separated list of NDBIDs.
+func ParseIDs(list string) []string {
+	var ids []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ids = append(ids, s)
+		}
+	}
+	return ids
+}
+
+func formatValue(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
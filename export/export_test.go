@@ -0,0 +1,116 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func testDB() *ndb.ASCIIDB {
+	db := &ndb.ASCIIDB{
+		Nutrients: []ndb.Nutrient{
+			{NutrientID: 204, Units: "g", Description: "Total lipid (fat)", SortOrder: 800},
+			{NutrientID: 203, Units: "g", Description: "Protein", SortOrder: 600},
+		},
+		Foods: map[string]*ndb.Food{
+			"01001": {
+				NDBID:           "01001",
+				FoodGroup:       100,
+				LongDescription: "Butter, salted",
+				Nutrients: []ndb.FoodNutrient{
+					{NutrientID: 203, Value: 0.85},
+					{NutrientID: 204, Value: 81.11},
+				},
+				Weights: []ndb.Weight{
+					{Sequence: 1, Amount: 1, Description: "cup", WeightG: 227},
+					{Sequence: 2, Amount: 1, Description: "tbsp", WeightG: 14.2},
+				},
+			},
+			"01004": {
+				NDBID:           "01004",
+				FoodGroup:       100,
+				LongDescription: "Cheese, blue",
+				Nutrients:       []ndb.FoodNutrient{{NutrientID: 203, Value: 21.4}},
+				Weights:         []ndb.Weight{{Sequence: 1, Amount: 1, Description: "oz", WeightG: 28.35}},
+			},
+			"11090": {
+				NDBID:           "11090",
+				FoodGroup:       1100,
+				LongDescription: "Broccoli, raw",
+			},
+		},
+	}
+	db.RebuildSearchIndex()
+	return db
+}
+
+func TestSelectFoods(t *testing.T) {
+	db := testDB()
+	expectations := []struct {
+		sel      Selection
+		expected []string
+	}{
+		{Selection{}, []string{"01001", "01004", "11090"}},
+		{Selection{FoodGroup: 100}, []string{"01001", "01004"}},
+		{Selection{Query: "cheese"}, []string{"01004"}},
+		{Selection{Query: "cheese butter"}, []string{}},
+		{Selection{IDs: []string{"11090", "01001", "99999"}}, []string{"01001", "11090"}},
+		{Selection{IDs: []string{"11090", "01001"}, FoodGroup: 1100}, []string{"11090"}},
+	}
+	for _, expected := range expectations {
+		actual := SelectFoods(db, expected.sel)
+		if !reflect.DeepEqual(expected.expected, actual) {
+			t.Errorf("SelectFoods(%+v): expected %v, got %v", expected.sel, expected.expected, actual)
+		}
+	}
+}
+
+func TestWriteFoods(t *testing.T) {
+	db := testDB()
+	ids := []string{"01001", "01004"}
+
+	var buf bytes.Buffer
+	if err := WriteFoods(&buf, db, ids, Options{Comma: '\t'}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "NDBID\tDescription\tFoodGroup\tMeasure\tGrams\tProtein (g)\tTotal lipid (fat) (g)\n" +
+		"01001\tButter, salted\t100\t100 g\t100\t0.85\t81.11\n" +
+		"01004\tCheese, blue\t100\t100 g\t100\t21.4\t\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Expected TSV:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	buf.Reset()
+	if err := WriteFoods(&buf, db, ids, Options{Nutrients: []int{203}, Measure: "tbsp"}); err != nil {
+		t.Fatal(err)
+	}
+	expected = "NDBID,Description,FoodGroup,Measure,Grams,Protein (g)\n" +
+		"01001,\"Butter, salted\",100,1 tbsp,14.2,0.1207\n" +
+		"01004,\"Cheese, blue\",100,,,\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if err := WriteFoods(&buf, db, ids, Options{Nutrients: []int{999}}); err == nil {
+		t.Errorf("Expected an error for an unknown nutrient")
+	}
+}
@@ -0,0 +1,127 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbapi"
+)
+
+// initAPI adds the handlers of the versioned API, whose responses are the types
+// of ndbapi rather than the ndb structs that the /_/ endpoints return:
+//
+//	GET /api/v1/search?q=<query>&limit=<n>
+//	GET /api/v1/foods/<ndb_id>
+//	GET /api/v1/upc/<gtin>
+//	GET /api/v1/food_groups
+//	GET /api/v1/nutrients
+func (s *server) initAPI() {
+	s.handleAPI("search", (*server).apiSearch)
+	s.handleAPI("foods/", (*server).apiFood)
+	s.handleAPI("upc/", (*server).apiUPC)
+	s.handleAPI("food_groups", (*server).apiFoodGroups)
+	s.handleAPI("nutrients", (*server).apiNutrients)
+	s.mux.HandleFunc(ndbapi.PathPrefix, func(rw http.ResponseWriter, req *http.Request) {
+		apiError(rw, http.StatusNotFound, "No API endpoint %s", req.URL.Path)
+	})
+}
+
+// handleAPI is like handleMethod for the API endpoint |name|, and only allows
+// GET and HEAD requests.
+func (s *server) handleAPI(name string, meth func(*server, http.ResponseWriter, *http.Request)) {
+	s.handleMethod(ndbapi.PathPrefix+name, func(s *server, rw http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" && req.Method != "HEAD" {
+			rw.Header().Set("Allow", "GET, HEAD")
+			apiError(rw, http.StatusMethodNotAllowed, "Method %s not allowed", req.Method)
+			return
+		}
+		meth(s, rw, req)
+	})
+}
+
+// apiError writes an ndbapi.ErrorResponse.
+func apiError(rw http.ResponseWriter, code int, format string, args ...interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	resp := ndbapi.ErrorResponse{Error: ndbapi.Error{Code: code, Message: fmt.Sprintf(format, args...)}}
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		panic(err.Error())
+	}
+}
+
+func (s *server) apiSearch(rw http.ResponseWriter, req *http.Request) {
+	query := req.FormValue("q")
+	if strings.TrimSpace(query) == "" {
+		apiError(rw, http.StatusBadRequest, "No query")
+		return
+	}
+	results := s.db.Search(query)
+	if l := req.FormValue("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			apiError(rw, http.StatusBadRequest, "Invalid limit %q", l)
+			return
+		}
+		if len(results) > limit {
+			results = results[:limit]
+		}
+	}
+	jsonResponse(rw, ndbapi.NewSearchResponse(results))
+}
+
+func (s *server) apiFood(rw http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(req.URL.Path, ndbapi.PathPrefix+"foods/")
+	if food, ok := s.db.Food(id); ok {
+		jsonResponse(rw, ndbapi.NewFood(s.db, food))
+	} else {
+		apiError(rw, http.StatusNotFound, "Could not find food with id %s", id)
+	}
+}
+
+func (s *server) apiUPC(rw http.ResponseWriter, req *http.Request) {
+	gtin := strings.TrimPrefix(req.URL.Path, ndbapi.PathPrefix+"upc/")
+	if _, ok := ndb.NormalizeGTIN(gtin); !ok {
+		apiError(rw, http.StatusBadRequest, "%q is not a UPC/GTIN barcode", gtin)
+	} else if food, ok := s.db.FindFoodByGTIN(gtin); ok {
+		jsonResponse(rw, ndbapi.NewFood(s.db, food))
+	} else {
+		apiError(rw, http.StatusNotFound, "Could not find food with UPC %s", gtin)
+	}
+}
+
+func (s *server) apiFoodGroups(rw http.ResponseWriter, req *http.Request) {
+	resp := ndbapi.FoodGroupsResponse{FoodGroups: make([]ndbapi.FoodGroup, 0, len(s.db.AllFoodGroups()))}
+	for _, g := range s.db.AllFoodGroups() {
+		resp.FoodGroups = append(resp.FoodGroups, ndbapi.NewFoodGroup(g))
+	}
+	jsonResponse(rw, resp)
+}
+
+func (s *server) apiNutrients(rw http.ResponseWriter, req *http.Request) {
+	resp := ndbapi.NutrientsResponse{Nutrients: make([]ndbapi.Nutrient, 0, len(s.db.AllNutrients()))}
+	for _, n := range s.db.AllNutrients() {
+		resp.Nutrients = append(resp.Nutrients, ndbapi.NewNutrient(n))
+	}
+	jsonResponse(rw, resp)
+}
@@ -0,0 +1,98 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package frontend
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rsesek/usda-ndb/export"
+)
+
+// export streams a spreadsheet of foods. The parameters are:
+//
+//	q         search query; every term must match
+//	group     food group code
+//	ids       comma-separated NDBIDs
+//	nutrients comma-separated NutrientIDs for the columns (default all)
+//	measure   household measure, e.g. "cup" (default per 100 g)
+//	format    csv or tsv (default csv)
+func (s *server) export(rw http.ResponseWriter, req *http.Request) {
+	comma, err := export.FormatComma(req.FormValue("format"))
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: %v", err)
+		return
+	}
+
+	nutrients, err := export.ParseNutrients(req.FormValue("nutrients"))
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: %v", err)
+		return
+	}
+
+	sel := export.Selection{
+		Query: req.FormValue("q"),
+		IDs:   export.ParseIDs(req.FormValue("ids")),
+	}
+	if group := req.FormValue("group"); group != "" {
+		sel.FoodGroup, err = strconv.Atoi(group)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(rw, "Error: Invalid food group %q", group)
+			return
+		}
+	}
+
+	w, err := export.NewWriter(rw, s.db, export.Options{
+		Comma:     comma,
+		Nutrients: nutrients,
+		Measure:   req.FormValue("measure"),
+	})
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: %v", err)
+		return
+	}
+
+	contentType, ext := "text/csv", "csv"
+	if comma == '\t' {
+		contentType, ext = "text/tab-separated-values", "tsv"
+	}
+	rw.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"foods.%s\"", ext))
+
+	// Errors after the first write cannot change the status, so they are
+	// only logged. They are usually the client going away.
+	for _, id := range export.SelectFoods(s.db, sel) {
+		food, ok := s.db.Food(id)
+		if !ok {
+			continue
+		}
+		if err := w.Write(food); err != nil {
+			log.Printf("export: %v", err)
+			return
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Printf("export: %v", err)
+	}
+}
@@ -18,26 +18,53 @@
 package frontend
 
 import (
+	"crypto/subtle"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/rsesek/usda-ndb/ingredients"
 	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbgraphql"
 )
 
 var (
 	debug = flag.Bool("debug", false, "Debug mode: log all requests")
 )
 
+// OpenAPI is the OpenAPI 3 document of the endpoints, which is served at
+// /_/openapi.json.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
 // NewServer creates a HTTP Handler that will serve static files from staticDir and
-// various API endpoints using the ASCIIDB db.
-func NewServer(db *ndb.ASCIIDB, staticDir string) http.Handler {
+// various API endpoints using db, which may be an ASCIIDB or a MappedDB.
+func NewServer(db ndb.Reader, staticDir string) http.Handler {
+	return NewServerOptions(db, staticDir, ServerOptions{})
+}
+
+// ServerOptions enables the optional features of the server.
+type ServerOptions struct {
+	// If set, custom foods can be created, replaced and deleted with POST, PUT
+	// and DELETE requests to /_/food/, which are recorded in the Journal.
+	Journal *ndb.Journal
+	// The bearer token that editing requests must have in their Authorization
+	// header. Editing is refused if this is empty.
+	APIToken string
+}
+
+// NewServerOptions is like NewServer, with the optional features in |opts|.
+func NewServerOptions(db ndb.Reader, staticDir string, opts ServerOptions) http.Handler {
 	s := &server{
 		db:        db,
 		staticDir: staticDir,
+		opts:      opts,
 		mux:       http.NewServeMux(),
 	}
 	s.init()
@@ -45,8 +72,9 @@ func NewServer(db *ndb.ASCIIDB, staticDir string) http.Handler {
 }
 
 type server struct {
-	db        *ndb.ASCIIDB
+	db        ndb.Reader
 	staticDir string
+	opts      ServerOptions
 	mux       *http.ServeMux
 }
 
@@ -55,7 +83,12 @@ func (s *server) init() {
 	s.handleMethod("/_/search", (*server).search)
 	s.handleMethod("/_/foodGroups", (*server).foodGroups)
 	s.handleMethod("/_/nutrients", (*server).nutrients)
-	s.handleMethod("/_/food/", (*server).getFood)
+	s.handleMethod("/_/food/", (*server).food)
+	s.handleMethod("/_/upc/", (*server).getUPC)
+	s.handleMethod("/_/export", (*server).export)
+	s.mux.Handle("/_/graphql", ndbgraphql.NewHandler(s.db))
+	s.handleMethod("/_/openapi.json", (*server).openAPI)
+	s.initAPI()
 }
 
 // Convience method to work around https://code.google.com/p/go/issues/detail?id=2280.
@@ -74,25 +107,176 @@ func (s *server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	s.mux.ServeHTTP(rw, req)
 }
 
+func (s *server) openAPI(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(OpenAPI)
+}
+
 func (s *server) foodGroups(rw http.ResponseWriter, req *http.Request) {
-	jsonResponse(rw, s.db.FoodGroups)
+	jsonResponse(rw, s.db.AllFoodGroups())
 }
 
 func (s *server) nutrients(rw http.ResponseWriter, req *http.Request) {
-	jsonResponse(rw, s.db.Nutrients)
+	jsonResponse(rw, s.db.AllNutrients())
+}
+
+func (s *server) food(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET", "HEAD":
+		s.getFood(rw, req)
+	case "POST", "PUT", "DELETE":
+		s.editFood(rw, req)
+	default:
+		rw.Header().Set("Allow", "GET, HEAD, POST, PUT, DELETE")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(rw, "Error: Method %s not allowed", req.Method)
+	}
 }
 
 func (s *server) getFood(rw http.ResponseWriter, req *http.Request) {
 	parts := strings.Split(req.URL.Path, "/")
 	id := parts[len(parts)-1]
-	if food, ok := s.db.Foods[id]; ok {
-		jsonResponse(rw, food)
+	if food, ok := s.db.Food(id); ok {
+		jsonResponse(rw, newFoodResponse(food))
 	} else {
 		rw.WriteHeader(http.StatusNotFound)
 		fmt.Fprintf(rw, "Error: Could not find food with id %s", id)
 	}
 }
 
+// editFood handles the requests that change custom foods:
+//
+//	POST /_/food/        creates the Food in the body.
+//	PUT /_/food/<id>     replaces the food with the Food in the body.
+//	DELETE /_/food/<id>  deletes the food.
+func (s *server) editFood(rw http.ResponseWriter, req *http.Request) {
+	if s.opts.Journal == nil {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprint(rw, "Error: Editing foods is not enabled")
+		return
+	}
+	if !s.authorized(req) {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		rw.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(rw, "Error: Not authorized")
+		return
+	}
+
+	parts := strings.Split(req.URL.Path, "/")
+	id := parts[len(parts)-1]
+	if req.Method == "POST" && id != "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(rw, "Error: POST requests must be to /_/food/")
+		return
+	} else if req.Method != "POST" && id == "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: %s requests must be to /_/food/<id>", req.Method)
+		return
+	}
+
+	if req.Method == "DELETE" {
+		if err := s.opts.Journal.Delete(id); err != nil {
+			editError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	food := &ndb.Food{}
+	if err := json.NewDecoder(req.Body).Decode(food); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: Invalid food: %v", err)
+		return
+	}
+
+	var err error
+	if req.Method == "POST" {
+		err = s.opts.Journal.Create(food)
+	} else {
+		if food.NDBID == "" {
+			food.NDBID = id
+		} else if food.NDBID != id {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(rw, "Error: NDBID %s does not match the URL", food.NDBID)
+			return
+		}
+		err = s.opts.Journal.Replace(food)
+	}
+	if err != nil {
+		editError(rw, err)
+		return
+	}
+
+	if req.Method == "POST" {
+		rw.Header().Set("Location", "/_/food/"+food.NDBID)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+	}
+	jsonResponse(rw, newFoodResponse(food))
+}
+
+// authorized checks the bearer token of |req| against the APIToken.
+func (s *server) authorized(req *http.Request) bool {
+	if s.opts.APIToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.APIToken)) == 1
+}
+
+// editError writes the response for an error from the ndb.Journal.
+func editError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ndb.ErrInvalidFood):
+		rw.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, ndb.ErrFoodNotFound):
+		rw.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, ndb.ErrFoodExists):
+		rw.WriteHeader(http.StatusConflict)
+	default:
+		log.Print(err)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(rw, "Error: %v", err)
+}
+
+// foodResponse is a Food with its ingredient statement parsed.
+type foodResponse struct {
+	*ndb.Food
+	ParsedIngredients []*ingredients.Ingredient `json:",omitempty"`
+	Allergens         []ingredients.Allergen    `json:",omitempty"`
+	Additives         []string                  `json:",omitempty"`
+}
+
+func newFoodResponse(food *ndb.Food) foodResponse {
+	resp := foodResponse{Food: food}
+	if food.Ingredients != "" {
+		st := ingredients.Parse(food.Ingredients)
+		resp.ParsedIngredients = st.Ingredients
+		resp.Allergens = st.Allergens()
+		resp.Additives = st.Additives()
+	}
+	return resp
+}
+
+func (s *server) getUPC(rw http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(req.URL.Path, "/")
+	gtin := parts[len(parts)-1]
+	if _, ok := ndb.NormalizeGTIN(gtin); !ok {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "Error: %q is not a UPC/GTIN barcode", gtin)
+	} else if food, ok := s.db.FindFoodByGTIN(gtin); ok {
+		jsonResponse(rw, newFoodResponse(food))
+	} else {
+		rw.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(rw, "Error: Could not find food with UPC %s", gtin)
+	}
+}
+
 func jsonResponse(rw http.ResponseWriter, resp interface{}) {
 	rw.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(rw)
@@ -0,0 +1,106 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package frontend
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func TestEditFood(t *testing.T) {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := ndb.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	const token = "s3cret"
+	server := httptest.NewServer(NewServerOptions(db, t.TempDir(), ServerOptions{Journal: j, APIToken: token}))
+	defer server.Close()
+	// Without an APIToken, foods can be read but not edited.
+	readOnly := httptest.NewServer(NewServerOptions(db, t.TempDir(), ServerOptions{Journal: j}))
+	defer readOnly.Close()
+
+	const muffin = `{"NDBID": "U100", "FoodGroup": 1900, "LongDescription": "Muffin, blueberry, bakery"}`
+	const bran = `{"FoodGroup": 1900, "LongDescription": "Muffin, bran, bakery"}`
+	for _, test := range []struct {
+		server *httptest.Server
+		method string
+		path   string
+		auth   string
+		body   string
+		status int
+	}{
+		{server, "POST", "/_/food/", "", muffin, http.StatusUnauthorized},
+		{server, "POST", "/_/food/", "Bearer wrong", muffin, http.StatusUnauthorized},
+		{server, "POST", "/_/food/", token, muffin, http.StatusUnauthorized},
+		{server, "POST", "/_/food/", "Basic " + token, muffin, http.StatusUnauthorized},
+		{readOnly, "POST", "/_/food/", "Bearer " + token, muffin, http.StatusUnauthorized},
+		{readOnly, "GET", "/_/food/01001", "", "", http.StatusOK},
+		{server, "POST", "/_/food/", "Bearer " + token, muffin, http.StatusCreated},
+		{server, "POST", "/_/food/", "Bearer " + token, muffin, http.StatusConflict},
+		{server, "POST", "/_/food/U100", "Bearer " + token, muffin, http.StatusBadRequest},
+		{server, "POST", "/_/food/", "Bearer " + token, "{", http.StatusBadRequest},
+		{server, "POST", "/_/food/", "Bearer " + token, `{"NDBID": "U101"}`, http.StatusBadRequest},
+		{server, "PUT", "/_/food/U100", "Bearer " + token, bran, http.StatusOK},
+		{server, "PUT", "/_/food/U100", "Bearer " + token, `{"NDBID": "U101", "LongDescription": "X"}`, http.StatusBadRequest},
+		{server, "PUT", "/_/food/U999", "Bearer " + token, bran, http.StatusNotFound},
+		{readOnly, "GET", "/_/food/U100", "", "", http.StatusOK},
+		{server, "DELETE", "/_/food/01001", "Bearer " + token, "", http.StatusBadRequest},
+		{server, "DELETE", "/_/food/U100", "Bearer " + token, "", http.StatusNoContent},
+		{server, "DELETE", "/_/food/U100", "Bearer " + token, "", http.StatusNotFound},
+		{readOnly, "GET", "/_/food/U100", "", "", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(test.method, test.server.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s %q: expected status %d, got %d: %s", test.method, test.path, test.auth, test.status, resp.StatusCode, body)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s %s %q: expected a WWW-Authenticate header", test.method, test.path, test.auth)
+		}
+	}
+
+	if food, ok := db.Food("U100"); ok {
+		t.Errorf("Expected the deleted food to be gone, got %+v", food)
+	}
+}
@@ -0,0 +1,208 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ingredients
+
+import (
+	"regexp"
+	"strings"
+)
+
+// An Allergen is one of the major food allergens that US labels must declare.
+type Allergen string
+
+const (
+	Milk      Allergen = "milk"
+	Egg       Allergen = "egg"
+	Fish      Allergen = "fish"
+	Shellfish Allergen = "shellfish"
+	TreeNuts  Allergen = "tree nuts"
+	Peanuts   Allergen = "peanuts"
+	Wheat     Allergen = "wheat"
+	Soy       Allergen = "soy"
+	Sesame    Allergen = "sesame"
+)
+
+// AllAllergens is the "big 9" list of major allergens, in the order used by
+// the FDA.
+var AllAllergens = []Allergen{Milk, Egg, Fish, Shellfish, TreeNuts, Peanuts, Wheat, Soy, Sesame}
+
+// allergenEntry maps the ingredient names that indicate an allergen. Names in
+// |not| are removed before matching, so that e.g. "cocoa butter" is not milk.
+type allergenEntry struct {
+	allergen Allergen
+	names    []string
+	not      []string
+}
+
+var allergenDictionary = []allergenEntry{
+	{
+		allergen: Milk,
+		names: []string{"milk", "cream", "butter", "buttermilk", "cheese", "whey", "casein",
+			"caseinate", "lactose", "lactalbumin", "yogurt", "ghee", "curd", "kefir", "half and half"},
+		not: []string{"cocoa butter", "cacao butter", "shea butter", "peanut butter", "nut butter",
+			"apple butter", "seed butter", "butternut", "coconut milk", "coconut cream", "almond milk",
+			"oat milk", "rice milk", "soy milk", "cream of tartar", "cream of coconut"},
+	},
+	{
+		allergen: Egg,
+		names:    []string{"egg", "albumen", "ovalbumin", "mayonnaise", "meringue"},
+		not:      []string{"eggplant"},
+	},
+	{
+		allergen: Fish,
+		names: []string{"fish", "anchovy", "anchovies", "cod", "salmon", "tuna", "tilapia",
+			"pollock", "haddock", "halibut", "sardine", "trout", "catfish", "flounder", "mackerel",
+			"herring", "swordfish", "bonito"},
+		not: []string{"shellfish"},
+	},
+	{
+		allergen: Shellfish,
+		names:    []string{"shellfish", "shrimp", "crab", "lobster", "crawfish", "crayfish", "prawn", "krill"},
+	},
+	{
+		allergen: TreeNuts,
+		names: []string{"tree nut", "almond", "cashew", "walnut", "pecan", "pistachio", "hazelnut",
+			"filbert", "macadamia", "brazil nut", "pine nut", "chestnut", "praline"},
+		not: []string{"water chestnut"},
+	},
+	{
+		allergen: Peanuts,
+		names:    []string{"peanut", "groundnut", "arachis"},
+	},
+	{
+		allergen: Wheat,
+		names: []string{"wheat", "semolina", "durum", "spelt", "farina", "bulgur", "couscous",
+			"kamut", "triticale", "einkorn", "emmer", "graham flour", "seitan"},
+		not: []string{"buckwheat"},
+	},
+	{
+		allergen: Soy,
+		names:    []string{"soy", "soya", "soybean", "tofu", "edamame", "miso", "tempeh", "tamari", "shoyu"},
+	},
+	{
+		allergen: Sesame,
+		names:    []string{"sesame", "tahini", "benne"},
+	},
+}
+
+// additiveEntry maps a pattern of ingredient names to the common name of an
+// additive.
+type additiveEntry struct {
+	name    string
+	pattern string
+}
+
+var additiveDictionary = []additiveEntry{
+	{"Monosodium glutamate", `monosodium glutamate|\bmsg\b`},
+	{"Sodium nitrite", `sodium nitrite`},
+	{"Sodium nitrate", `sodium nitrate`},
+	{"High fructose corn syrup", `high[ -]fructose corn syrup|\bhfcs\b`},
+	{"Partially hydrogenated oil", `partially hydrogenated`},
+	{"Artificial color", `artificial colou?rs?|\b(?:fd&c\s+)?(?:red|yellow|blue|green)\s+(?:no\.?\s*)?\d+\b`},
+	{"Caramel color", `caramel colou?r`},
+	{"Artificial flavor", `artificial(?: and natural)? flavou?rs?`},
+	{"BHA", `\bbha\b|butylated hydroxyanisole`},
+	{"BHT", `\bbht\b|butylated hydroxytoluene`},
+	{"TBHQ", `\btbhq\b|tert-?butylhydroquinone`},
+	{"Propyl gallate", `propyl gallate`},
+	{"Aspartame", `aspartame`},
+	{"Sucralose", `sucralose`},
+	{"Acesulfame potassium", `acesulfame|\bace-k\b`},
+	{"Saccharin", `saccharin`},
+	{"Carrageenan", `carrageenan`},
+	{"Sodium benzoate", `sodium benzoate`},
+	{"Potassium sorbate", `potassium sorbate`},
+	{"Sulfites", `sulfites?\b|sulphites?\b|bisulfite|metabisulfite|sulfur dioxide`},
+	{"Titanium dioxide", `titanium dioxide`},
+	{"Polysorbate 80", `polysorbate 80`},
+}
+
+// compiledAllergen and compiledAdditive are the dictionaries compiled into
+// regular expressions at init.
+type compiledAllergen struct {
+	allergen Allergen
+	names    *regexp.Regexp
+	not      *regexp.Regexp
+}
+
+type compiledAdditive struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var (
+	allergenMatchers []compiledAllergen
+	additiveMatchers []compiledAdditive
+)
+
+func init() {
+	for _, entry := range allergenDictionary {
+		m := compiledAllergen{
+			allergen: entry.allergen,
+			names:    wordsRegexp(entry.names),
+		}
+		if len(entry.not) > 0 {
+			m.not = wordsRegexp(entry.not)
+		}
+		allergenMatchers = append(allergenMatchers, m)
+	}
+	for _, entry := range additiveDictionary {
+		additiveMatchers = append(additiveMatchers, compiledAdditive{
+			name:    entry.name,
+			pattern: regexp.MustCompile(entry.pattern),
+		})
+	}
+}
+
+// wordsRegexp creates a regular expression that matches any of the |words| as
+// whole words, allowing for plurals.
+func wordsRegexp(words []string) *regexp.Regexp {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)(?:s|es)?\b`)
+}
+
+// matchAllergens returns the allergens indicated by the lower-cased ingredient
+// |name|.
+func matchAllergens(name string) []Allergen {
+	var allergens []Allergen
+	for _, m := range allergenMatchers {
+		s := name
+		if m.not != nil {
+			s = m.not.ReplaceAllString(s, " ")
+		}
+		if m.names.MatchString(s) {
+			allergens = append(allergens, m.allergen)
+		}
+	}
+	return allergens
+}
+
+// matchAdditives returns the names of the additives matched by the lower-cased
+// ingredient |name|.
+func matchAdditives(name string) []string {
+	var additives []string
+	for _, m := range additiveMatchers {
+		if m.pattern.MatchString(name) {
+			additives = append(additives, m.name)
+		}
+	}
+	return additives
+}
@@ -0,0 +1,242 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ingredients parses the ingredient statements printed on food labels,
+// e.g. "ENRICHED FLOUR (WHEAT FLOUR, NIACIN), SUGAR, CONTAINS 2% OR LESS OF:
+// SALT, SOY LECITHIN. CONTAINS: WHEAT, SOY.", into a tree of Ingredients, and
+// flags the major allergens and common additives that they contain.
+package ingredients
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A Statement is a parsed ingredient statement.
+type Statement struct {
+	// The top-level ingredients, in label order.
+	Ingredients []*Ingredient
+	// The allergens listed in a trailing "Contains:" sentence.
+	Declared []Allergen `json:",omitempty"`
+}
+
+// An Ingredient is a single item of an ingredient statement. Sub-ingredients
+// listed in parentheses or brackets are its Children.
+type Ingredient struct {
+	Name string
+	// The function of the ingredient, if the label gives one in parentheses,
+	// e.g. "color" for "ANNATTO (COLOR)".
+	Purpose string `json:",omitempty"`
+	// True if the ingredient follows a "contains 2% or less of" phrase.
+	Minor bool `json:",omitempty"`
+	// The allergens matched by the Name of this ingredient, but not its
+	// Children.
+	Allergens []Allergen `json:",omitempty"`
+	// The additives matched by the Name of this ingredient.
+	Additives []string      `json:",omitempty"`
+	Children  []*Ingredient `json:",omitempty"`
+}
+
+var (
+	// The "Contains: milk, soy" allergen declaration. This has to be followed
+	// by a colon to distinguish it from the "contains 2% or less of" phrase.
+	declarationRegexp = regexp.MustCompile(`(?i)(?:^|[.,;]?\s+)contains\s*:`)
+
+	// The phrase that introduces minor ingredients, in its many spellings:
+	// "contains 2% or less of", "less than 2% of", "2 percent or less of each
+	// of the following".
+	minorRegexp = regexp.MustCompile(`(?i)^(?:and\s+)?(?:contains\s+)?(?:less\s+than\s+)?\d+(?:\.\d+)?\s*(?:%|percent)\s+(?:or\s+less\s+)?of(?:\s+each\s+of)?(?:\s+the\s+following)?\s*:?\s*`)
+
+	// The function words that are used in parentheses after an ingredient.
+	purposeRegexp = regexp.MustCompile(`(?i)^(?:to|for|as)\s|^(?:an?\s+)?(?:colou?rs?|preservatives?|emulsifiers?|antioxidants?|leavening|thickeners?|stabilizers?|acidulants?|sweeteners?|flavou?r\s+enhancers?|anti-?caking\s+agents?|dough\s+conditioners?)$`)
+)
+
+// Parse parses the ingredient |statement|. Parsing is lenient: unbalanced
+// parentheses are closed at the end of the statement, and stray closing
+// parentheses are ignored.
+func Parse(statement string) *Statement {
+	s := strings.TrimSpace(statement)
+	if len(s) > 12 && strings.EqualFold(s[:12], "ingredients:") {
+		s = s[12:]
+	}
+
+	st := &Statement{}
+	if loc := declarationRegexp.FindStringIndex(s); loc != nil {
+		st.Declared = declaredAllergens(s[loc[1]:])
+		s = s[:loc[0]]
+	}
+
+	p := &parser{input: s}
+	st.Ingredients = p.parseList(0)
+	return st
+}
+
+// Allergens returns all the allergens in the statement, both declared and
+// matched against the ingredients, in the order of AllAllergens.
+func (st *Statement) Allergens() []Allergen {
+	found := make(map[Allergen]bool)
+	for _, a := range st.Declared {
+		found[a] = true
+	}
+	st.Walk(func(ing *Ingredient) {
+		for _, a := range ing.Allergens {
+			found[a] = true
+		}
+	})
+
+	var allergens []Allergen
+	for _, a := range AllAllergens {
+		if found[a] {
+			allergens = append(allergens, a)
+		}
+	}
+	return allergens
+}
+
+// Additives returns the names of all the additives in the statement, in the
+// order they first appear.
+func (st *Statement) Additives() []string {
+	found := make(map[string]bool)
+	var additives []string
+	st.Walk(func(ing *Ingredient) {
+		for _, a := range ing.Additives {
+			if !found[a] {
+				found[a] = true
+				additives = append(additives, a)
+			}
+		}
+	})
+	return additives
+}
+
+// Walk calls |f| for every ingredient in the statement, parents before their
+// children.
+func (st *Statement) Walk(f func(*Ingredient)) {
+	var walk func([]*Ingredient)
+	walk = func(list []*Ingredient) {
+		for _, ing := range list {
+			f(ing)
+			walk(ing.Children)
+		}
+	}
+	walk(st.Ingredients)
+}
+
+// parser is a recursive descent parser over the ingredient list grammar:
+//
+//	list       = ingredient { ("," | ";") ingredient }
+//	ingredient = text { group text }
+//	group      = "(" list ")" | "[" list "]" | "{" list "}"
+type parser struct {
+	input string
+	pos   int
+}
+
+// parseList parses ingredients until the |close| character or the end of the
+// input, and consumes the |close| character.
+func (p *parser) parseList(close byte) []*Ingredient {
+	var list []*Ingredient
+	var name strings.Builder
+	var children []*Ingredient
+	minor := false
+
+	flush := func() {
+		text := cleanName(name.String())
+		if loc := minorRegexp.FindStringIndex(text); loc != nil {
+			minor = true
+			text = text[loc[1]:]
+		}
+		name.Reset()
+
+		if text == "" {
+			// A parenthetical without a name, e.g. "(A, B)" at the start of
+			// the list, is flattened into the list.
+			list = append(list, children...)
+		} else {
+			list = append(list, newIngredient(text, minor, children))
+		}
+		children = nil
+	}
+
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			children = append(children, p.parseList(')')...)
+		case '[':
+			children = append(children, p.parseList(']')...)
+		case '{':
+			children = append(children, p.parseList('}')...)
+		case ')', ']', '}':
+			if c == close {
+				flush()
+				return list
+			}
+		case ',', ';':
+			flush()
+		default:
+			name.WriteByte(c)
+		}
+	}
+
+	flush()
+	return list
+}
+
+func newIngredient(name string, minor bool, children []*Ingredient) *Ingredient {
+	ing := &Ingredient{
+		Name:     name,
+		Minor:    minor,
+		Children: children,
+	}
+
+	// A single parenthetical that describes the function of the ingredient is
+	// not a sub-ingredient.
+	if len(children) == 1 && len(children[0].Children) == 0 && purposeRegexp.MatchString(children[0].Name) {
+		ing.Purpose = strings.ToLower(children[0].Name)
+		ing.Children = nil
+	}
+
+	lower := strings.ToLower(name)
+	ing.Allergens = matchAllergens(lower)
+	ing.Additives = matchAdditives(lower)
+	return ing
+}
+
+// cleanName collapses whitespace and trims the punctuation that surrounds
+// ingredient names.
+func cleanName(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Trim(s, " .*:†‡")
+}
+
+// declaredAllergens parses the list of a "Contains:" sentence.
+func declaredAllergens(s string) []Allergen {
+	var allergens []Allergen
+	found := make(map[Allergen]bool)
+	s = strings.Replace(strings.ToLower(s), " and ", ",", -1)
+	for _, part := range strings.Split(s, ",") {
+		for _, a := range matchAllergens(cleanName(part)) {
+			if !found[a] {
+				found[a] = true
+				allergens = append(allergens, a)
+			}
+		}
+	}
+	return allergens
+}
@@ -0,0 +1,121 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ingredients
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// format prints the ingredient tree in a compact form for comparisons, e.g.
+// "a(b,c),*d" where * marks minor ingredients.
+func format(list []*Ingredient) string {
+	parts := make([]string, len(list))
+	for i, ing := range list {
+		s := ing.Name
+		if ing.Minor {
+			s = "*" + s
+		}
+		if ing.Purpose != "" {
+			s += "<" + ing.Purpose + ">"
+		}
+		if len(ing.Children) > 0 {
+			s += "(" + format(ing.Children) + ")"
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestParseTree(t *testing.T) {
+	expectations := []struct {
+		statement string
+		expected  string
+	}{
+		{"Water, Sugar, Salt.", "Water,Sugar,Salt"},
+		{"INGREDIENTS: WATER, SUGAR", "WATER,SUGAR"},
+		{
+			"ENRICHED FLOUR (WHEAT FLOUR, NIACIN, REDUCED IRON), CHEESE (MILK, SALT [SEA SALT], ENZYMES)",
+			"ENRICHED FLOUR(WHEAT FLOUR,NIACIN,REDUCED IRON),CHEESE(MILK,SALT(SEA SALT),ENZYMES)",
+		},
+		{
+			"TOMATOES, WATER, CONTAINS 2% OR LESS OF: SALT, CITRIC ACID (TO PRESERVE FRESHNESS).",
+			"TOMATOES,WATER,*SALT,*CITRIC ACID<to preserve freshness>",
+		},
+		{
+			"Sugar, Less than 2% of Salt, Annatto (Color)",
+			"Sugar,*Salt,*Annatto<color>",
+		},
+		{
+			"CRUST (FLOUR, WATER, CONTAINS 2 PERCENT OR LESS OF EACH OF THE FOLLOWING: YEAST, SALT), SAUCE",
+			"CRUST(FLOUR,WATER,*YEAST,*SALT),SAUCE",
+		},
+		// Unbalanced parentheses do not lose ingredients.
+		{"OIL (CANOLA, SOYBEAN", "OIL(CANOLA,SOYBEAN)"},
+		{"OIL), SALT", "OIL,SALT"},
+	}
+	for _, expected := range expectations {
+		actual := format(Parse(expected.statement).Ingredients)
+		if actual != expected.expected {
+			t.Errorf("Parse(%q):\n\texpected %s\n\tgot      %s", expected.statement, expected.expected, actual)
+		}
+	}
+}
+
+func TestAllergens(t *testing.T) {
+	expectations := []struct {
+		statement string
+		expected  []Allergen
+	}{
+		{"WATER, SUGAR, SALT", nil},
+		{"ENRICHED FLOUR (WHEAT FLOUR), SUGAR, SOY LECITHIN", []Allergen{Wheat, Soy}},
+		{"CHEESE (PASTEURIZED MILK, CULTURES), EGGS", []Allergen{Milk, Egg}},
+		{"COCOA BUTTER, PEANUT BUTTER (PEANUTS, SALT)", []Allergen{Peanuts}},
+		{"BUCKWHEAT FLOUR, EGGPLANT, WATER CHESTNUTS", nil},
+		{"ANCHOVIES, SHRIMP, ALMONDS, TAHINI", []Allergen{Fish, Shellfish, TreeNuts, Sesame}},
+		// Declared allergens are included even if no ingredient matches.
+		{"SUGAR, NATURAL FLAVOR. CONTAINS: MILK AND SOY.", []Allergen{Milk, Soy}},
+	}
+	for _, expected := range expectations {
+		actual := Parse(expected.statement).Allergens()
+		if !reflect.DeepEqual(expected.expected, actual) {
+			t.Errorf("Allergens for %q: expected %v, got %v", expected.statement, expected.expected, actual)
+		}
+	}
+}
+
+func TestDeclared(t *testing.T) {
+	st := Parse("SUGAR, WHEY. CONTAINS: MILK, WHEAT.")
+	if actual := format(st.Ingredients); actual != "SUGAR,WHEY" {
+		t.Errorf("Expected the declaration to be removed from the ingredients, got %s", actual)
+	}
+	expected := []Allergen{Milk, Wheat}
+	if !reflect.DeepEqual(expected, st.Declared) {
+		t.Errorf("Expected declared %v, got %v", expected, st.Declared)
+	}
+}
+
+func TestAdditives(t *testing.T) {
+	st := Parse("HIGH FRUCTOSE CORN SYRUP, SALT, CONTAINS 2% OR LESS OF: SODIUM NITRITE, " +
+		"BHT (PRESERVATIVE), COLORS (RED 40, YELLOW 5 LAKE), MSG")
+	expected := []string{"High fructose corn syrup", "Sodium nitrite", "BHT", "Artificial color", "Monosodium glutamate"}
+	if actual := st.Additives(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected additives %v, got %v", expected, actual)
+	}
+}
@@ -18,31 +18,171 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc"
 
 	"github.com/rsesek/usda-ndb/frontend"
 	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbgrpc"
 )
 
 var (
-	port = flag.Int("port", 8077, "Port to listen for HTTP")
+	port     = flag.Int("port", 8077, "Port to listen for HTTP")
+	grpcPort = flag.Int("grpc-port", 0, "Port to serve the NutrientDB gRPC service on, or 0 to not serve it.")
+	fdc      = flag.String("fdc", "", "Serve a FoodData Central download instead of ./data/. Either a CSV directory or a JSON file.")
+	encoding = flag.String("encoding", "windows-1252", "The character set of the ASCII database files: windows-1252, latin1 or utf-8.")
+	overlays = flag.String("overlay", "", "A comma-separated list of overlays of custom foods and nutrient overrides to apply. Each is a JSON file or a directory in the ASCII database format.")
+	journal  = flag.String("journal", "", "A file to record the custom foods created, changed and deleted through the API. Enables editing.")
+	apiToken = flag.String("api-token", os.Getenv("NDB_API_TOKEN"), "The bearer token required to edit foods. Defaults to $NDB_API_TOKEN.")
+	lenient  = flag.Bool("lenient", false, "Skip invalid records in the ASCII database files with a warning, instead of failing.")
+	mmap     = flag.String("mmap", "", "Serve a database file written by dbio -format=mmap instead of ./data/. It is mapped rather than loaded, and cannot be edited.")
+	index    = flag.String("search-index", "", "The search index that dbio wrote for the -mmap file. Without it, searches find nothing.")
 )
 
 func main() {
 	flag.Parse()
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	db, err := ndb.ReadDatabase("./data/")
+	var db ndb.Reader
+	var mapped *ndb.MappedDB
+	var opts frontend.ServerOptions
+	var j *ndb.Journal
+	if *mmap != "" {
+		if *overlays != "" || *journal != "" {
+			log.Fatal("-overlay and -journal cannot be used with -mmap")
+		}
+		mapped = openMappedDB()
+		db = mapped
+	} else {
+		asciidb := readDatabase()
+		for _, overlay := range strings.Split(*overlays, ",") {
+			if overlay == "" {
+				continue
+			}
+			log.Printf("Applying overlay %s", overlay)
+			if err := asciidb.LoadOverlay(overlay); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if *journal != "" {
+			if *apiToken == "" {
+				log.Fatal("-journal requires an -api-token")
+			}
+			log.Printf("Replaying journal %s", *journal)
+			var err error
+			j, err = ndb.OpenJournal(*journal, asciidb)
+			if err != nil {
+				log.Fatal(err)
+			}
+			opts = frontend.ServerOptions{Journal: j, APIToken: *apiToken}
+		}
+		db = asciidb
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *grpcPort))
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Starting gRPC server on port %d", *grpcPort)
+		grpcServer = grpc.NewServer()
+		ndbgrpc.Register(grpcServer, db)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	// On an interrupt, finish the requests in progress, so that the journal
+	// can be closed after the last change.
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: frontend.NewServerOptions(db, "./static/", opts),
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		log.Print("Shutting down")
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Print(err)
+		}
+		close(stopped)
+	}()
+
+	log.Printf("Starting HTTP server on port %d", *port)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+	if j != nil {
+		if err := j.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if mapped != nil {
+		if err := mapped.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// readDatabase reads the ASCII database in ./data/, or the -fdc download.
+func readDatabase() *ndb.ASCIIDB {
+	var db *ndb.ASCIIDB
+	var err error
+	if *fdc == "" {
+		var enc ndb.Encoding
+		enc, err = ndb.ParseEncoding(*encoding)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Allow an interrupt to abort loading, which takes a while.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		db, err = ndb.ReadDatabaseContext(ctx, "./data/", &ndb.ReadOptions{Encoding: enc, Lenient: *lenient})
+		stop()
+	} else if strings.HasSuffix(*fdc, ".json") {
+		db, err = ndb.ReadFDCJSON(*fdc)
+	} else {
+		db, err = ndb.ReadFDCDatabase(*fdc)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	return db
+}
 
-	log.Printf("Starting HTTP server on port %d", *port)
-	server := frontend.NewServer(db, "./static/")
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", *port), server); err != nil {
+// openMappedDB opens the -mmap file and its -search-index.
+func openMappedDB() *ndb.MappedDB {
+	log.Printf("Mapping database %s", *mmap)
+	db, err := ndb.OpenMappedDB(*mmap)
+	if err != nil {
 		log.Fatal(err)
 	}
+	if *index != "" {
+		if err := db.OpenSearchIndex(*index); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		log.Print("No -search-index, so searches will find nothing")
+	}
+	log.Printf("... %d foods", db.Len())
+	return db
 }
@@ -18,52 +18,92 @@
 package ndb
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 	"path"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rsesek/usda-ndb/bst"
 )
 
+// An ASCIIDB is a loaded database. Once it is loaded, it is safe for concurrent
+// use through its methods, which is what the server does. Changes to the Foods
+// replace the *Food rather than modifying it, so a Food that has been read is
+// never modified. Accessing the fields directly is only safe while nothing is
+// changing the database.
 type ASCIIDB struct {
 	basePath   string
+	opts       *ReadOptions
 	FoodGroups []FoodGroup
 	Nutrients  []Nutrient
 	Foods      map[string]*Food
+
+	mu         sync.RWMutex // Protects Foods, searchTree and gtinIndex after loading.
 	searchTree *bst.Tree
+	gtinIndex  map[string]string
+
+	// The NumberText of the lines of the SR files that have any, by numberKey,
+	// for WriteDatabase.
+	numbers map[string]NumberText
 }
 
 func ReadDatabase(base string) (*ASCIIDB, error) {
+	return ReadDatabaseOptions(base, nil)
+}
+
+// ReadDatabaseOptions reads the ASCII database files in |base| using |opts| for
+// each file. The |opts| may be nil to use the defaults.
+func ReadDatabaseOptions(base string, opts *ReadOptions) (*ASCIIDB, error) {
+	return ReadDatabaseContext(context.Background(), base, opts)
+}
+
+// ReadDatabaseContext is like ReadDatabaseOptions, but stops loading when |ctx| is
+// done and returns an error that wraps ctx.Err(). If |opts| has a Progress
+// function, it receives the events for each file in turn.
+func ReadDatabaseContext(ctx context.Context, base string, opts *ReadOptions) (*ASCIIDB, error) {
 	db := &ASCIIDB{
 		basePath:   base,
+		opts:       opts,
 		Foods:      make(map[string]*Food, 8000),
 		searchTree: bst.NewTree(),
 	}
 
 	log.Print("Loading food groups")
-	if err := db.readFoodGroups(); err != nil {
+	if err := db.readFoodGroups(ctx); err != nil {
 		return nil, err
 	}
 
 	log.Print("Loading nutrient definitions")
-	if err := db.readNutrientDefinitions(); err != nil {
+	if err := db.readNutrientDefinitions(ctx); err != nil {
 		return nil, err
 	}
 
 	log.Print("Loading food database")
-	if err := db.readFoods(); err != nil {
+	if err := db.readFoods(ctx); err != nil {
 		return nil, err
 	}
 
 	log.Print("Loading food nutrients information")
-	if err := db.readFoodNutrients(); err != nil {
+	if err := db.readFoodNutrients(ctx); err != nil {
 		return nil, err
 	}
 
 	log.Print("Loading weight information")
-	if err := db.readWeights(); err != nil {
+	if err := db.readWeights(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading ingredient statements")
+	if err := db.readIngredients(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading footnotes")
+	if err := db.readFootnotes(ctx); err != nil {
 		return nil, err
 	}
 
@@ -76,173 +116,378 @@ func ReadDatabase(base string) (*ASCIIDB, error) {
 // FindFood performs a text search for Foods named |name| and returns a slice of
 // NDBIDs for matches, or nil on none.
 func (db *ASCIIDB) FindFood(name string) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	return db.searchTree.Find(name)
 }
 
-func (db *ASCIIDB) readFoodGroups() error {
-	return ReadFile(path.Join(db.basePath, "FD_GROUP.txt"), func(line string) error {
-		parts := strings.Split(line, "^")
-		if len(parts) != 2 {
-			return fmt.Errorf("Expected 2 parts, got %d from a FD_GROUP", len(parts))
-		}
-		code, err := intyString(parts[0])
-		if err != nil {
-			return fmt.Errorf("readFoodGroups: %v", err)
+// FindFoodPostings is like FindFood, but returns a posting for each match, with
+// the NDBID as the token. The positions give the SearchField and the word offset
+// within it of each occurrence of |name|.
+func (db *ASCIIDB) FindFoodPostings(name string) []bst.Posting {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.searchTree.FindPostings(name)
+}
+
+// AllFoodGroups returns the food groups, for the Reader interface.
+func (db *ASCIIDB) AllFoodGroups() []FoodGroup {
+	return db.FoodGroups
+}
+
+// AllNutrients returns the nutrient definitions, for the Reader interface.
+func (db *ASCIIDB) AllNutrients() []Nutrient {
+	return db.Nutrients
+}
+
+// Food returns the Food with NDBID |id|.
+func (db *ASCIIDB) Food(id string) (*Food, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	food, ok := db.Foods[id]
+	return food, ok
+}
+
+// FoodIDs returns the NDBIDs of all the foods, sorted.
+func (db *ASCIIDB) FoodIDs() []string {
+	db.mu.RLock()
+	ids := make([]string, 0, len(db.Foods))
+	for id := range db.Foods {
+		ids = append(ids, id)
+	}
+	db.mu.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// putFood adds |food| to the database, replacing the food with the same NDBID,
+// and updates the indexes.
+func (db *ASCIIDB) putFood(food *Food) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.reindexFood(db.Foods[food.NDBID], food)
+	db.Foods[food.NDBID] = food
+}
+
+// deleteFood removes the food |id| from the database and the indexes.
+func (db *ASCIIDB) deleteFood(id string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if old, ok := db.Foods[id]; ok {
+		db.reindexFood(old, nil)
+		delete(db.Foods, id)
+	}
+}
+
+// readFile reads the file |name| in the database directory. The loaders modify the
+// database without locking, so the lines are always processed in order, one at a
+// time.
+func (db *ASCIIDB) readFile(ctx context.Context, name string, processor LineProcessor) error {
+	var opts ReadOptions
+	if db.opts != nil {
+		opts = *db.opts
+	}
+	opts.Ordered = true
+	return ReadFileContext(ctx, path.Join(db.basePath, name), &opts, processor)
+}
+
+// The records declare all of the columns of the USDA files, so that they can be
+// written back out by WriteDatabase.
+
+type foodGroupRecord struct {
+	_           struct{} `sr:"table=FD_GROUP,fields=2"`
+	GroupCode   int      `sr:"col=1,type=text,width=4"`
+	Description string   `sr:"col=2"`
+}
+
+func (db *ASCIIDB) readFoodGroups(ctx context.Context) error {
+	return db.readFile(ctx, "FD_GROUP.txt", func(line string) error {
+		var r foodGroupRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
 		}
 		db.FoodGroups = append(db.FoodGroups, FoodGroup{
-			GroupCode:   code,
-			Description: trimString(parts[1]),
+			GroupCode:   r.GroupCode,
+			Description: r.Description,
 		})
 		return nil
 	})
 }
 
-func (db *ASCIIDB) readNutrientDefinitions() error {
-	return ReadFile(path.Join(db.basePath, "NUTR_DEF.txt"), func(line string) error {
-		parts := strings.Split(line, "^")
-		if len(parts) != 6 {
-			return fmt.Errorf("Expected 6 parts, got %d from a NUTR_DEF", len(parts))
-		}
-
-		id, err := intyString(parts[0])
-		if err != nil {
-			return fmt.Errorf("readNutrientDefinitions: %v", err)
-		}
+type nutrientRecord struct {
+	_           struct{} `sr:"table=NUTR_DEF,fields=6"`
+	NutrientID  int      `sr:"col=1,type=text,width=3"`
+	Units       string   `sr:"col=2"`
+	Tagname     string   `sr:"col=3"`
+	Description string   `sr:"col=4"`
+	Decimals    int      `sr:"col=5,type=text"`
+	SortOrder   int      `sr:"col=6,type=text"`
+}
 
-		order, err := intyString(parts[5])
-		if err != nil {
-			return fmt.Errorf("readNutrientDefinitions: %v", err)
+func (db *ASCIIDB) readNutrientDefinitions(ctx context.Context) error {
+	return db.readFile(ctx, "NUTR_DEF.txt", func(line string) error {
+		var r nutrientRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
 		}
-
 		db.Nutrients = append(db.Nutrients, Nutrient{
-			NutrientID:  id,
-			Units:       trimString(parts[1]),
-			Description: trimString(parts[3]),
-			SortOrder:   order,
+			NutrientID:  r.NutrientID,
+			Units:       r.Units,
+			Description: r.Description,
+			SortOrder:   r.SortOrder,
+			Tagname:     r.Tagname,
+			Decimals:    r.Decimals,
 		})
-
 		return nil
 	})
 }
 
-func (db *ASCIIDB) readFoods() error {
-	return ReadFile(path.Join(db.basePath, "FOOD_DES.txt"), func(line string) error {
-		parts := strings.Split(line, "^")
-		if len(parts) != 14 {
-			return fmt.Errorf("Expected 14 parts, got %d from a FOOD_DES", len(parts))
-		}
-
-		foodGroup, err := intyString(parts[1])
-		if err != nil {
-			return fmt.Errorf("readFoods: FoodGroup: %v", err)
-		}
+type foodRecord struct {
+	_                  struct{}   `sr:"table=FOOD_DES,fields=14"`
+	NDBID              string     `sr:"col=1"`
+	FoodGroup          int        `sr:"col=2,type=text,width=4"`
+	LongDescription    string     `sr:"col=3"`
+	ShortDescription   string     `sr:"col=4"`
+	CommonNames        string     `sr:"col=5"`
+	Manufacturer       string     `sr:"col=6"`
+	Survey             string     `sr:"col=7"`
+	RefuseDescription  string     `sr:"col=8"`
+	Refuse             int        `sr:"col=9,nullable"`
+	ScientificName     string     `sr:"col=10"`
+	NitrogenFactor     *float32   `sr:"col=11"`
+	ProteinFactor      *float32   `sr:"col=12"`
+	FatFactor          *float32   `sr:"col=13"`
+	CarbohydrateFactor *float32   `sr:"col=14"`
+	Numbers            NumberText `sr:"numbers"`
+}
 
-		var refuse int
-		if s := trimString(parts[8]); s != "" {
-			refuse, err = intyString(s)
-			if err != nil {
-				return fmt.Errorf("readFoods: Refuse: %v", err)
-			}
-		}
+// food returns the Food of the record, without its nutrients and weights.
+func (r *foodRecord) food() *Food {
+	return &Food{
+		NDBID:              r.NDBID,
+		FoodGroup:          r.FoodGroup,
+		LongDescription:    r.LongDescription,
+		ShortDescription:   r.ShortDescription,
+		CommonNames:        r.CommonNames,
+		Manufacturer:       r.Manufacturer,
+		RefuseDescription:  r.RefuseDescription,
+		Refuse:             r.Refuse,
+		ScientificName:     r.ScientificName,
+		Survey:             r.Survey == "Y",
+		NitrogenFactor:     r.NitrogenFactor,
+		ProteinFactor:      r.ProteinFactor,
+		FatFactor:          r.FatFactor,
+		CarbohydrateFactor: r.CarbohydrateFactor,
+	}
+}
 
-		id := trimString(parts[0])
-
-		food := &Food{
-			NDBID:             id,
-			FoodGroup:         foodGroup,
-			LongDescription:   trimString(parts[2]),
-			ShortDescription:  trimString(parts[3]),
-			CommonNames:       trimString(parts[4]),
-			Manufacturer:      trimString(parts[5]),
-			RefuseDescription: trimString(parts[7]),
-			Refuse:            refuse,
+func (db *ASCIIDB) readFoods(ctx context.Context) error {
+	return db.readFile(ctx, "FOOD_DES.txt", func(line string) error {
+		var r foodRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
 		}
-		db.Foods[id] = food
+		food := r.food()
+		db.Foods[food.NDBID] = food
 		db.addTermsForFood(food)
-
+		db.keepNumbers(numberKey("FOOD_DES", r.NDBID, 0), r.Numbers)
 		return nil
 	})
 }
 
-func (db *ASCIIDB) readFoodNutrients() error {
-	return ReadFile(path.Join(db.basePath, "NUT_DATA.txt"), func(line string) error {
-		parts := strings.Split(line, "^")
-		if len(parts) != 18 {
-			return fmt.Errorf("Expected 18 parts, got %d from a NUT_DATA", len(parts))
-		}
+type foodNutrientRecord struct {
+	_                struct{}   `sr:"table=NUT_DATA,fields=18"`
+	NDBID            string     `sr:"col=1"`
+	NutrientID       int        `sr:"col=2,type=text,width=3"`
+	Value            float32    `sr:"col=3"`
+	DataPoints       int        `sr:"col=4"`
+	StdError         *float32   `sr:"col=5"`
+	SourceCode       string     `sr:"col=6"`
+	DerivationCode   string     `sr:"col=7"`
+	RefNDBID         string     `sr:"col=8"`
+	Added            string     `sr:"col=9"`
+	Studies          *int       `sr:"col=10"`
+	Min              *float32   `sr:"col=11"`
+	Max              *float32   `sr:"col=12"`
+	DegreesOfFreedom *int       `sr:"col=13"`
+	LowerErrorBound  *float32   `sr:"col=14"`
+	UpperErrorBound  *float32   `sr:"col=15"`
+	Comments         string     `sr:"col=16"`
+	Modified         string     `sr:"col=17"`
+	ConfidenceCode   string     `sr:"col=18,type=numeric"` // Not quoted in the USDA files.
+	Numbers          NumberText `sr:"numbers"`
+}
 
-		id := trimString(parts[0])
+// nutrient returns the FoodNutrient of the record, with Stats if any of its
+// statistics columns are set.
+func (r *foodNutrientRecord) nutrient() FoodNutrient {
+	stats := NutrientStats{
+		StdError:         r.StdError,
+		SourceCode:       r.SourceCode,
+		DerivationCode:   r.DerivationCode,
+		RefNDBID:         r.RefNDBID,
+		Added:            r.Added == "Y",
+		Studies:          r.Studies,
+		Min:              r.Min,
+		Max:              r.Max,
+		DegreesOfFreedom: r.DegreesOfFreedom,
+		LowerErrorBound:  r.LowerErrorBound,
+		UpperErrorBound:  r.UpperErrorBound,
+		Comments:         r.Comments,
+		Modified:         r.Modified,
+		ConfidenceCode:   r.ConfidenceCode,
+	}
+	n := FoodNutrient{
+		NutrientID: r.NutrientID,
+		Value:      r.Value,
+		DataPoints: r.DataPoints,
+	}
+	if stats != (NutrientStats{}) {
+		n.Stats = &stats
+	}
+	return n
+}
 
-		food, ok := db.Foods[id]
+func (db *ASCIIDB) readFoodNutrients(ctx context.Context) error {
+	return db.readFile(ctx, "NUT_DATA.txt", func(line string) error {
+		var r foodNutrientRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		food, ok := db.Foods[r.NDBID]
 		if !ok {
-			return fmt.Errorf("readFoodNutrients: Could not find food %s", id)
+			return fieldError(1, fmt.Errorf("readFoodNutrients: Could not find food %s", r.NDBID))
 		}
+		food.Nutrients = append(food.Nutrients, r.nutrient())
+		db.keepNumbers(numberKey("NUT_DATA", r.NDBID, r.NutrientID), r.Numbers)
+		return nil
+	})
+}
 
-		nutrientID, err := intyString(parts[1])
-		if err != nil {
-			return fmt.Errorf("readFoodNutrients: NutrientID: %v", err)
-		}
+type weightRecord struct {
+	_           struct{}   `sr:"table=WEIGHT,fields=7"`
+	NDBID       string     `sr:"col=1"`
+	Sequence    int        `sr:"col=2"`
+	Amount      float32    `sr:"col=3"`
+	Description string     `sr:"col=4"`
+	WeightG     float32    `sr:"col=5"`
+	DataPoints  *int       `sr:"col=6"`
+	StdDev      *float32   `sr:"col=7"`
+	Numbers     NumberText `sr:"numbers"`
+}
 
-		value, err := strconv.ParseFloat(trimString(parts[2]), 32)
-		if err != nil {
-			return fmt.Errorf("readFoodNutrients: Value: %v", err)
-		}
+// weight returns the Weight of the record.
+func (r *weightRecord) weight() Weight {
+	return Weight{
+		Sequence:    r.Sequence,
+		Amount:      r.Amount,
+		Description: r.Description,
+		WeightG:     r.WeightG,
+		DataPoints:  r.DataPoints,
+		StdDev:      r.StdDev,
+	}
+}
 
-		dataPoints, err := intyString(parts[3])
-		if err != nil {
-			return fmt.Errorf("readFoodNutrients: NutrientID: %v", err)
+func (db *ASCIIDB) readWeights(ctx context.Context) error {
+	return db.readFile(ctx, "WEIGHT.txt", func(line string) error {
+		var r weightRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
 		}
-
-		food.Nutrients = append(food.Nutrients, FoodNutrient{
-			NutrientID: nutrientID,
-			Value:      float32(value),
-			DataPoints: dataPoints,
-		})
+		food, ok := db.Foods[r.NDBID]
+		if !ok {
+			return fieldError(1, fmt.Errorf("readWeights: Could not find food %s", r.NDBID))
+		}
+		food.Weights = append(food.Weights, r.weight())
+		db.keepNumbers(numberKey("WEIGHT", r.NDBID, r.Sequence), r.Numbers)
 		return nil
 	})
 }
 
-func (db *ASCIIDB) readWeights() error {
-	return ReadFile(path.Join(db.basePath, "WEIGHT.txt"), func(line string) error {
-		parts := strings.Split(line, "^")
-		if len(parts) != 7 {
-			return fmt.Errorf("Expected 7 parts, got %d from a WEIGHT", len(parts))
-		}
+type ingredientsRecord struct {
+	_           struct{} `sr:"table=INGREDIENTS,fields=2"`
+	NDBID       string   `sr:"col=1"`
+	Ingredients string   `sr:"col=2"`
+}
+
+// readIngredients loads the ingredient statements from INGREDIENTS.txt. This
+// file is not part of the USDA release, so it is optional. Each line has the
+// same format as the other files: ~NDB_No~^~Ingredient statement~.
+func (db *ASCIIDB) readIngredients(ctx context.Context) error {
+	if _, err := os.Stat(path.Join(db.basePath, "INGREDIENTS.txt")); os.IsNotExist(err) {
+		return nil
+	}
 
-		id := trimString(parts[0])
-		food, ok := db.Foods[id]
+	return db.readFile(ctx, "INGREDIENTS.txt", func(line string) error {
+		var r ingredientsRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		food, ok := db.Foods[r.NDBID]
 		if !ok {
-			return fmt.Errorf("readWeights: Could not find food %s", id)
+			return fieldError(1, fmt.Errorf("readIngredients: Could not find food %s", r.NDBID))
 		}
+		food.Ingredients = r.Ingredients
+		return nil
+	})
+}
 
-		sequence, err := intyString(parts[1])
-		if err != nil {
-			return fmt.Errorf("readWeights: Sequence: %v", err)
-		}
+type footnoteRecord struct {
+	_          struct{} `sr:"table=FOOTNOTE,fields=5"`
+	NDBID      string   `sr:"col=1"`
+	Number     int      `sr:"col=2,type=text,width=2"`
+	Type       string   `sr:"col=3"`
+	NutrientID int      `sr:"col=4,type=text,nullable,width=3"`
+	Text       string   `sr:"col=5"`
+}
 
-		amount, err := strconv.ParseFloat(trimString(parts[2]), 32)
-		if err != nil {
-			return fmt.Errorf("readWeights: Amount: %v", err)
-		}
+// readFootnotes loads the comments on foods from FOOTNOTE.txt. Older and custom
+// releases may not have the file, so it is optional.
+func (db *ASCIIDB) readFootnotes(ctx context.Context) error {
+	if _, err := os.Stat(path.Join(db.basePath, "FOOTNOTE.txt")); os.IsNotExist(err) {
+		return nil
+	}
 
-		weight, err := strconv.ParseFloat(trimString(parts[4]), 32)
-		if err != nil {
-			return fmt.Errorf("readWeights: WeightG: %v", err)
+	return db.readFile(ctx, "FOOTNOTE.txt", func(line string) error {
+		var r footnoteRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
 		}
-
-		food.Weights = append(food.Weights, Weight{
-			Sequence:    sequence,
-			Amount:      float32(amount),
-			Description: trimString(parts[3]),
-			WeightG:     float32(weight),
+		food, ok := db.Foods[r.NDBID]
+		if !ok {
+			return fieldError(1, fmt.Errorf("readFootnotes: Could not find food %s", r.NDBID))
+		}
+		switch r.Type {
+		case FootnoteDescription, FootnoteMeasure, FootnoteNutrient:
+		default:
+			return fieldError(3, fmt.Errorf("readFootnotes: Invalid footnote type %q", r.Type))
+		}
+		food.Footnotes = append(food.Footnotes, Footnote{
+			Number:     r.Number,
+			Type:       r.Type,
+			NutrientID: r.NutrientID,
+			Text:       r.Text,
 		})
 		return nil
 	})
 }
 
-// intyString turns a stringified number in the ASCII database dump format into an actual int.
-func intyString(a string) (int, error) {
-	return strconv.Atoi(trimString(a))
+// numberKey returns the key in ASCIIDB.numbers of the line of |table| for the
+// food |id| and, in NUT_DATA and WEIGHT, the nutrient or weight |n|.
+func numberKey(table, id string, n int) string {
+	return fmt.Sprintf("%s^%s^%d", table, id, n)
+}
+
+// keepNumbers keeps the NumberText of a line, if it has any, so that
+// WriteDatabase writes its numbers as they were.
+func (db *ASCIIDB) keepNumbers(key string, numbers NumberText) {
+	if numbers == nil {
+		return
+	}
+	if db.numbers == nil {
+		db.numbers = make(map[string]NumberText)
+	}
+	db.numbers[key] = numbers
 }
 
 func trimString(s string) string {
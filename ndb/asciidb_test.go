@@ -0,0 +1,260 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testdata/sr is a small release in the USDA format: Windows-1252 with CRLF
+// line endings.
+const kTestDatabase = "testdata/sr"
+
+func TestReadDatabaseEncoding(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var units string
+	for _, n := range db.Nutrients {
+		if n.NutrientID == 320 {
+			units = n.Units
+		}
+	}
+	if units != "µg" {
+		t.Errorf("Expected Vitamin A units to be %q, got %q", "µg", units)
+	}
+
+	food, ok := db.Foods["19999"]
+	if !ok {
+		t.Fatalf("Could not find food 19999")
+	}
+	expectations := []struct {
+		field    string
+		actual   string
+		expected string
+	}{
+		{"LongDescription", food.LongDescription, "Dessert, crème brûlée, prepared from recipe"},
+		{"CommonNames", food.CommonNames, "Crème brûlée"},
+		{"Manufacturer", food.Manufacturer, "“Café” Desserts"},
+	}
+	for _, expected := range expectations {
+		if expected.actual != expected.expected {
+			t.Errorf("Expected %s to be %q, got %q", expected.field, expected.expected, expected.actual)
+		}
+	}
+
+	// Reading the same file as Latin-1 turns the curly quotes into C1 controls.
+	db, err = ReadDatabaseOptions(kTestDatabase, &ReadOptions{Encoding: Latin1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := db.Foods["19999"].Manufacturer, "\u0093Café\u0094 Desserts"; actual != expected {
+		t.Errorf("Expected Latin-1 Manufacturer to be %q, got %q", expected, actual)
+	}
+}
+
+func TestReadDatabaseStats(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := db.Nutrients[0]; n.Tagname != "PROCNT" || n.Decimals != 2 {
+		t.Errorf("Expected the PROCNT tagname and 2 decimals, got %+v", n)
+	}
+
+	butter := db.Foods["01001"]
+	if !butter.Survey || butter.NitrogenFactor == nil || *butter.NitrogenFactor != 6.38 || butter.CarbohydrateFactor == nil || *butter.CarbohydrateFactor != 3.87 {
+		t.Errorf("Expected the survey flag and factors, got %+v", butter)
+	}
+
+	f := func(v float32) *float32 { return &v }
+	i := func(v int) *int { return &v }
+	expected := &NutrientStats{
+		StdError:         f(0.074),
+		SourceCode:       "1",
+		DerivationCode:   "A",
+		Studies:          i(3),
+		Min:              f(80.2),
+		Max:              f(82),
+		DegreesOfFreedom: i(2),
+		LowerErrorBound:  f(80.5),
+		UpperErrorBound:  f(81.7),
+		Comments:         "2, 3",
+		Modified:         "11/1976",
+	}
+	if actual := butter.Nutrients[1].Stats; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected stats %+v, got %+v", expected, actual)
+	}
+	if stats := butter.Nutrients[0].Stats; stats == nil || stats.Studies != nil || stats.Min != nil {
+		t.Errorf("Expected empty numbers to be nil, got %+v", stats)
+	}
+
+	broccoli := db.Foods["11090"]
+	if w := broccoli.Weights[0]; w.DataPoints == nil || *w.DataPoints != 9 || w.StdDev == nil || *w.StdDev != 6.164 {
+		t.Errorf("Expected the cup weight to have 9 data points, got %+v", w)
+	}
+	if w := butter.Weights[0]; w.DataPoints != nil || w.StdDev != nil {
+		t.Errorf("Expected the cup weight to have no statistics, got %+v", w)
+	}
+}
+
+// Loading a database with many small chunks and workers must give the same
+// result as the file order every time. Run with -race to check the loaders.
+func TestReadDatabaseDeterministic(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		db, err := ReadDatabaseOptions(kTestDatabase, &ReadOptions{Workers: 4, chunkSize: 32})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		butter := db.Foods["01001"]
+		expectedNutrients := []FoodNutrient{
+			{NutrientID: 203, Value: 0.85, DataPoints: 16},
+			{NutrientID: 204, Value: 81.11, DataPoints: 580},
+			{NutrientID: 208, Value: 717, DataPoints: 0},
+			{NutrientID: 320, Value: 684, DataPoints: 0},
+		}
+		var nutrients []FoodNutrient
+		for _, n := range butter.Nutrients {
+			n.Stats = nil
+			nutrients = append(nutrients, n)
+		}
+		if !reflect.DeepEqual(expectedNutrients, nutrients) {
+			t.Fatalf("Expected nutrients %v, got %v", expectedNutrients, nutrients)
+		}
+
+		var sequences []int
+		for _, w := range butter.Weights {
+			sequences = append(sequences, w.Sequence)
+		}
+		if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(expected, sequences) {
+			t.Fatalf("Expected weight sequences %v, got %v", expected, sequences)
+		}
+
+		expectedFootnotes := []Footnote{
+			{Number: 1, Type: FootnoteDescription, Text: "Salted with 1.5% to 2% salt"},
+			{Number: 2, Type: FootnoteMeasure, Text: "Measured from stick butter"},
+		}
+		if !reflect.DeepEqual(expectedFootnotes, butter.Footnotes) {
+			t.Fatalf("Expected footnotes %v, got %v", expectedFootnotes, butter.Footnotes)
+		}
+		broccoli := []Footnote{{Number: 1, Type: FootnoteNutrient, NutrientID: 208, Text: "Calculated from the proximates"}}
+		if !reflect.DeepEqual(broccoli, db.Foods["11090"].Footnotes) {
+			t.Fatalf("Expected footnotes %v, got %v", broccoli, db.Foods["11090"].Footnotes)
+		}
+
+		var codes []int
+		for _, g := range db.FoodGroups {
+			codes = append(codes, g.GroupCode)
+		}
+		if expected := []int{100, 1100, 1900}; !reflect.DeepEqual(expected, codes) {
+			t.Fatalf("Expected food groups %v, got %v", expected, codes)
+		}
+	}
+}
+
+func TestReadDatabaseContext(t *testing.T) {
+	var files []string
+	opts := &ReadOptions{
+		Progress: func(p Progress) {
+			if p.Done {
+				files = append(files, filepath.Base(p.File))
+			}
+		},
+	}
+	if _, err := ReadDatabaseContext(context.Background(), kTestDatabase, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"FD_GROUP.txt", "NUTR_DEF.txt", "FOOD_DES.txt", "NUT_DATA.txt", "WEIGHT.txt", "FOOTNOTE.txt"}
+	if !reflect.DeepEqual(expected, files) {
+		t.Errorf("Expected progress for %v, got %v", expected, files)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadDatabaseContext(ctx, kTestDatabase, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// copyTestDatabase copies the test database to a temporary directory, replacing
+// the contents of the file |name|.
+func copyTestDatabase(t *testing.T, name, contents string) string {
+	dir := t.TempDir()
+	files, err := filepath.Glob(filepath.Join(kTestDatabase, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(file) == name {
+			data = []byte(contents)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadDatabaseParseErrors(t *testing.T) {
+	base := copyTestDatabase(t, "NUT_DATA.txt",
+		"~01001~^~203~^0.85^16^0.074^~1~^~~^~~^~~^^^^^^^~~^~11/1976~^\r\n"+
+			"~01001~^~204~^bad^580^0.074^~1~^~~^~~^~~^^^^^^^~~^~11/1976~^\r\n"+
+			"~01001~^~208~^717\r\n"+
+			"~99999~^~203~^1^0^0.074^~1~^~~^~~^~~^^^^^^^~~^~11/1976~^\r\n")
+
+	_, err := ReadDatabase(base)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if filepath.Base(pe.File) != "NUT_DATA.txt" || pe.Line != 2 || pe.Column != 3 {
+		t.Errorf("Expected an error at NUT_DATA.txt:2 field 3, got %v", pe)
+	}
+
+	var warnings []string
+	opts := &ReadOptions{
+		Lenient: true,
+		Warn: func(pe *ParseError) {
+			warnings = append(warnings, fmt.Sprintf("%d:%d", pe.Line, pe.Column))
+		},
+	}
+	db, err := ReadDatabaseOptions(base, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"2:3", "3:0", "4:1"}; !reflect.DeepEqual(expected, warnings) {
+		t.Errorf("Expected warnings %v, got %v", expected, warnings)
+	}
+	if n := len(db.Foods["01001"].Nutrients); n != 1 {
+		t.Errorf("Expected the valid nutrient to be loaded, got %d", n)
+	}
+}
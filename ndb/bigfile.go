@@ -18,10 +18,17 @@
 package ndb
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"strings"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // The number of bytes of a file to read, aligned to the nearest newline.
@@ -29,114 +36,421 @@ const kChunkSize = 524288
 
 type LineProcessor func(line string) error
 
+// ReadOptions controls how ReadFile reads a file. The zero value, or a nil
+// *ReadOptions, reads a USDA release file.
+type ReadOptions struct {
+	// The character set of the file. If the file starts with a UTF-8 byte
+	// order mark, it is read as UTF-8 instead.
+	Encoding Encoding
+	// The maximum number of chunks that are read but not yet processed. This
+	// bounds both the number of worker goroutines and the memory used. The
+	// default is runtime.NumCPU().
+	Workers int
+	// If true, the processor is called with the lines in file order, one at a
+	// time. The workers still split and decode the chunks concurrently.
+	// Otherwise the workers call the processor concurrently, in no particular
+	// order.
+	Ordered bool
+	// If set, called with a Progress event after each chunk of the file is
+	// processed, and once more when reading stops. The calls are serialized.
+	Progress func(Progress)
+	// If true, a line that the processor returns an error for is skipped, and
+	// the *ParseError is passed to Warn instead of stopping the read.
+	Lenient bool
+	// Receives the warnings in Lenient mode. The calls are serialized. The
+	// default logs them.
+	Warn func(*ParseError)
+
+	// The number of bytes per chunk, if not kChunkSize. Used by tests to
+	// split small files into several chunks.
+	chunkSize int
+}
+
+// Progress reports how far ReadFile has got through a file.
+type Progress struct {
+	// The path of the file.
+	File string
+	// The size of the file, and the number of bytes of it that have been
+	// processed.
+	Size, BytesRead int64
+	// The number of lines that have been processed.
+	Lines int64
+	// The time since ReadFile opened the file.
+	Elapsed time.Duration
+	// True for the last event, after reading stopped, whether or not it
+	// succeeded.
+	Done bool
+}
+
+// A chunk is a run of whole lines from a file.
+type chunk struct {
+	seq  int // The index of the chunk within the file.
+	line int // The 1-based line number of the first line of data.
+	data []byte
+	size int // The number of bytes of the file, including a byte order mark, in the chunk.
+}
+
+// A parsedChunk holds the decoded lines of a chunk, for Ordered delivery.
+type parsedChunk struct {
+	seq   int
+	lines []numberedLine
+	size  int
+}
+
+type numberedLine struct {
+	num  int
+	text string
+}
+
 type bigFile struct {
-	processor LineProcessor // The function that accepts lines.
-	started   chan bool     // Used to send messages from processChunk whenever a worker starts.
-	done      chan error    // Used to send messages from processChunk or readFile when an error occurs, or nil at EOF.
+	processor LineProcessor    // The function that accepts lines.
+	opts      ReadOptions      // The options, with defaults filled in.
+	encoding  Encoding         // The character set of the file, set before the first chunk is sent.
+	chunks    chan chunk       // Used to send chunks from readChunks to the workers.
+	parsed    chan parsedChunk // Used to send decoded chunks from the workers to deliver, if Ordered.
+	tokens    chan struct{}    // Holds a token for each chunk in flight, to bound memory.
+	quit      chan struct{}    // Closed when an error occurs, to stop reading and processing.
+	quitOnce  sync.Once
+	mu        sync.Mutex // Protects errs and serializes calls to opts.Warn.
+	errs      []error
+
+	// Progress reporting.
+	progress   Progress   // The File and Size; the counts are below.
+	start      time.Time  // When the file was opened.
+	bytesRead  int64      // Accessed atomically.
+	lines      int64      // Accessed atomically.
+	progressMu sync.Mutex // Serializes calls to opts.Progress.
 }
 
 // ReadFile reads the file at path |file| and processes lines using the |processor|
-// function. The processor can execute concurrently. Returns the first error that
-// occurs. The processor should communicate over a chanel to its own storage facility.
-func ReadFile(file string, processor LineProcessor) error {
-	bf := &bigFile{
-		processor: processor,
-		started:   make(chan bool),
-		done:      make(chan error),
+// function. Unless |opts| asks for Ordered processing, the processor can execute
+// concurrently and should communicate over a channel to its own storage facility.
+// Reading stops at the first error, but all the errors that occurred in the
+// meantime are returned. The |opts| may be nil to use the defaults.
+func ReadFile(file string, opts *ReadOptions, processor LineProcessor) error {
+	return ReadFileContext(context.Background(), file, opts, processor)
+}
+
+// ReadFileContext is like ReadFile, but stops reading when |ctx| is done. Lines
+// that are already being processed finish, but no more are started, and the
+// returned error wraps the ctx.Err().
+func ReadFileContext(ctx context.Context, file string, opts *ReadOptions, processor LineProcessor) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("ReadFile(%s): %w", file, err)
 	}
 
-	go bf.readFile(file)
+	bf := &bigFile{processor: processor}
+	if opts != nil {
+		bf.opts = *opts
+	}
+	if bf.opts.Workers <= 0 {
+		bf.opts.Workers = runtime.NumCPU()
+	}
+	if bf.opts.chunkSize <= 0 {
+		bf.opts.chunkSize = kChunkSize
+	}
+	bf.encoding = bf.opts.Encoding
+	bf.chunks = make(chan chunk, bf.opts.Workers)
+	bf.parsed = make(chan parsedChunk, bf.opts.Workers)
+	bf.tokens = make(chan struct{}, bf.opts.Workers)
+	bf.quit = make(chan struct{})
 
-	// Wait for the workers to complete.
-	// Count readFile as the first worker.
-	var errs []string
-	for i := 1; i > 0; {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("ReadFile(%s): %v", file, err)
+	}
+	defer f.Close()
+
+	bf.start = time.Now()
+	bf.progress.File = file
+	if fi, err := f.Stat(); err == nil {
+		bf.progress.Size = fi.Size()
+	}
+
+	// Cancelling the context stops the goroutines the same way an error does.
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
 		select {
-		case <-bf.started:
-			i++
-		case err := <-bf.done:
-			i--
-			if err != nil {
-				errs = append(errs, err.Error())
-			}
+		case <-ctx.Done():
+			bf.stop()
+		case <-finished:
 		}
+	}()
+
+	go bf.readChunks(f)
+
+	var workers sync.WaitGroup
+	for i := 0; i < bf.opts.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			bf.work()
+		}()
 	}
 
-	if len(errs) > 0 {
-		errStr := strings.Join(errs, "\n\t")
-		return fmt.Errorf("ReadFile(%s) encountered the following errors:\n\t%s", file, errStr)
+	if bf.opts.Ordered {
+		go func() {
+			workers.Wait()
+			close(bf.parsed)
+		}()
+		bf.deliver()
+	} else {
+		workers.Wait()
+	}
+	bf.report(0, 0, true)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("ReadFile(%s): %w", file, err)
+	}
+	if len(bf.errs) > 0 {
+		return &MultiError{File: file, Errors: bf.errs}
 	}
 
 	return nil
 }
 
-// readFile synchronously opens and reads the file into chunks. Each chunk is then passed
-// to a goroutine, which processes each line of the chunk individually.
-func (bf *bigFile) readFile(file string) {
-	f, err := os.Open(file)
-	if err != nil {
-		bf.done <- err
-		return
+// fail records |err| and tells all the goroutines to stop.
+func (bf *bigFile) fail(err error) {
+	bf.mu.Lock()
+	bf.errs = append(bf.errs, err)
+	bf.mu.Unlock()
+	bf.stop()
+}
+
+// stop tells all the goroutines to stop.
+func (bf *bigFile) stop() {
+	bf.quitOnce.Do(func() { close(bf.quit) })
+}
+
+// processLine calls the processor for line number |num|. An error is turned into a
+// *ParseError, which either stops the read or, if Lenient, becomes a warning.
+// Returns false if the read should stop.
+func (bf *bigFile) processLine(num int, line string) bool {
+	err := bf.processor(line)
+	if err == nil {
+		return true
 	}
-	defer f.Close()
 
-	for {
-		buf := make([]byte, kChunkSize)
-		n, err := f.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				err = nil
+	// Copy a field error, even if the processor wrapped it, rather than
+	// changing it.
+	pe := &ParseError{Err: err}
+	var fe *ParseError
+	if errors.As(err, &fe) {
+		c := *fe
+		pe = &c
+	}
+	pe.File = bf.progress.File
+	pe.Line = num
+	pe.Raw = line
+
+	if !bf.opts.Lenient {
+		bf.fail(pe)
+		return false
+	}
+
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	if bf.opts.Warn != nil {
+		bf.opts.Warn(pe)
+	} else {
+		log.Printf("Skipping invalid line: %v", pe)
+	}
+	return true
+}
+
+func (bf *bigFile) stopped() bool {
+	select {
+	case <-bf.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// readChunks synchronously reads the file into chunks that end on a newline, and
+// sends them to the workers. It waits for a token before reading each chunk, so
+// that at most opts.Workers chunks are in memory. Closes bf.chunks when done.
+func (bf *bigFile) readChunks(r io.Reader) {
+	defer close(bf.chunks)
+
+	var leftover []byte
+	line := 1
+	for seq := 0; ; seq++ {
+		select {
+		case bf.tokens <- struct{}{}:
+		case <-bf.quit:
+			return
+		}
+
+		// Fill a buffer after the partial line from the last chunk. A line
+		// longer than the buffer grows it until the end of the line is read.
+		buf := append(make([]byte, 0, len(leftover)+bf.opts.chunkSize), leftover...)
+		var err error
+		for {
+			var n int
+			n, err = io.ReadFull(r, buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+			if err != nil || bytes.IndexByte(buf, '\n') >= 0 {
+				break
 			}
-			bf.done <- err
+			buf = append(buf, make([]byte, bf.opts.chunkSize)...)[:len(buf)]
+		}
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			bf.fail(err)
 			return
 		}
 
-		// Walk the buffer back to the previous newline.
-		nOrig := n
-		for ; buf[n-1] != '\n'; n-- {
+		// A byte order mark overrides the requested encoding.
+		bom := 0
+		if seq == 0 && bytes.HasPrefix(buf, utf8BOM) {
+			bf.encoding = UTF8
+			bom = len(utf8BOM)
+			buf = buf[bom:]
+		}
+
+		// Keep the partial line at the end for the next chunk. At EOF there is
+		// no next chunk, so the last line does not need a newline.
+		data := buf
+		leftover = nil
+		if !eof {
+			i := bytes.LastIndexByte(buf, '\n')
+			data, leftover = buf[:i+1], buf[i+1:]
 		}
 
-		// If we moved at all, seek the file backwards.
-		if delta := n - nOrig; delta < 0 {
-			_, err := f.Seek(int64(delta), 1)
-			if err != nil {
-				bf.done <- err
+		if len(data) == 0 {
+			<-bf.tokens
+		} else {
+			select {
+			case bf.chunks <- chunk{seq: seq, line: line, data: data, size: bom + len(data)}:
+			case <-bf.quit:
 				return
 			}
+			line += bytes.Count(data, []byte{'\n'})
 		}
 
-		// Proceess the chunk.
-		bf.started <- true
-		go bf.processChunk(buf[:n])
+		if eof {
+			return
+		}
 	}
+}
 
-	bf.done <- nil
+// work processes chunks until bf.chunks is closed. Each chunk's token is released
+// once its lines have been processed, which for Ordered files happens in deliver.
+func (bf *bigFile) work() {
+	for c := range bf.chunks {
+		if bf.opts.Ordered {
+			// Even after an error, deliver needs every chunk to release its token.
+			var lines []numberedLine
+			if !bf.stopped() {
+				lines = bf.splitLines(c)
+			}
+			bf.parsed <- parsedChunk{seq: c.seq, lines: lines, size: c.size}
+		} else {
+			if !bf.stopped() {
+				bf.report(c.size, bf.processChunk(c), false)
+			}
+			<-bf.tokens
+		}
+	}
+}
 
-	return
+// processChunk sends each line of the chunk to the processor, stopping at the
+// first error. Returns the number of lines processed.
+func (bf *bigFile) processChunk(c chunk) int {
+	n := 0
+	eachLine(c.data, c.line, func(num int, line []byte) bool {
+		if !bf.processLine(num, bf.encoding.decode(line)) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
 }
 
-// processChunk splits a chunk on \r\n and sends each whitespace-trimmed line to
-// the processor. Any errors from the processor are sent over the error channel,
-// or nil is sent when processing is done.
-func (bf *bigFile) processChunk(buf []byte) {
-	// Convert the bytes to runes, which is necessary to ensure that any ASCII
-	// characters are decoded correctly to UTF8.
-	chunk := make([]rune, len(buf))
+// splitLines decodes the lines of the chunk.
+func (bf *bigFile) splitLines(c chunk) []numberedLine {
+	var lines []numberedLine
+	eachLine(c.data, c.line, func(num int, line []byte) bool {
+		lines = append(lines, numberedLine{num, bf.encoding.decode(line)})
+		return true
+	})
+	return lines
+}
 
-	var start int
-	for i := 0; i < len(buf); i++ {
-		chunk[i] = rune(buf[i])
+// deliver sends the lines of the parsed chunks to the processor in file order,
+// until bf.parsed is closed. Chunks that arrive early wait in |pending|; there
+// are at most opts.Workers of them because each holds a token.
+func (bf *bigFile) deliver() {
+	pending := make(map[int]parsedChunk)
+	next := 0
+	for pc := range bf.parsed {
+		pending[pc.seq] = pc
+		for {
+			pc, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
 
-		if chunk[i] == '\r' {
-			if err := bf.processor(string(chunk[start:i])); err != nil {
-				bf.done <- err
-				return
+			n := 0
+			for _, line := range pc.lines {
+				if bf.stopped() {
+					break
+				}
+				if !bf.processLine(line.num, line.text) {
+					break
+				}
+				n++
+			}
+			if n == len(pc.lines) && !bf.stopped() {
+				bf.report(pc.size, n, false)
 			}
-			start = i + 1
-		} else if chunk[i] == '\n' {
-			start = i + 1
+			<-bf.tokens
 		}
 	}
+}
+
+// report adds a processed chunk of |size| bytes and |lines| lines to the counts,
+// and sends a Progress event.
+func (bf *bigFile) report(size, lines int, done bool) {
+	bytesRead := atomic.AddInt64(&bf.bytesRead, int64(size))
+	lineCount := atomic.AddInt64(&bf.lines, int64(lines))
+	if bf.opts.Progress == nil {
+		return
+	}
+
+	p := bf.progress
+	p.BytesRead = bytesRead
+	p.Lines = lineCount
+	p.Elapsed = time.Since(bf.start)
+	p.Done = done
+
+	bf.progressMu.Lock()
+	defer bf.progressMu.Unlock()
+	bf.opts.Progress(p)
+}
 
-	bf.done <- nil
+// eachLine calls |f| with each line of |buf|, and its number counting from |num|,
+// until it returns false. Lines end with \n or \r\n; the last line may have
+// neither. Empty lines, and the DOS end-of-file marker, are skipped.
+func eachLine(buf []byte, num int, f func(num int, line []byte) bool) {
+	for ; len(buf) > 0; num++ {
+		line := buf
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			line, buf = buf[:i], buf[i+1:]
+		} else {
+			buf = nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if len(line) == 0 || (len(line) == 1 && line[0] == 0x1A) {
+			continue
+		}
+		if !f(num, line) {
+			return
+		}
+	}
 }
@@ -0,0 +1,329 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, contents []byte) string {
+	file := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(file, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// readLines reads |contents| through ReadFile and returns the sorted lines.
+func readLines(t *testing.T, contents []byte, opts *ReadOptions) []string {
+	file := writeTestFile(t, contents)
+
+	var mu sync.Mutex
+	var lines []string
+	err := ReadFile(file, opts, func(line string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestReadFileEncoding(t *testing.T) {
+	expectations := []struct {
+		name     string
+		contents []byte
+		opts     *ReadOptions
+		expected []string
+	}{
+		{
+			"default",
+			[]byte("~\xb5g~\r\n~Caf\xe9 \x93au lait\x94~\r\n"),
+			nil,
+			[]string{"~Café “au lait”~", "~µg~"},
+		},
+		{
+			"latin1",
+			[]byte("~\xb5g~\r\n~Caf\xe9~\r\n"),
+			&ReadOptions{Encoding: Latin1},
+			[]string{"~Café~", "~µg~"},
+		},
+		{
+			"utf8",
+			[]byte("~\xc2\xb5g~\r\n~Caf\xc3\xa9~\r\n"),
+			&ReadOptions{Encoding: UTF8},
+			[]string{"~Café~", "~µg~"},
+		},
+		{
+			"utf8 with BOM",
+			[]byte("\xef\xbb\xbf~\xc2\xb5g~\r\n~Caf\xc3\xa9~\r\n"),
+			&ReadOptions{Encoding: Windows1252},
+			[]string{"~Café~", "~µg~"},
+		},
+		{
+			"invalid utf8",
+			[]byte("~Caf\xe9~\r\n"),
+			&ReadOptions{Encoding: UTF8},
+			[]string{"~Caf�~"},
+		},
+	}
+	for _, expected := range expectations {
+		actual := readLines(t, expected.contents, expected.opts)
+		if !reflect.DeepEqual(expected.expected, actual) {
+			t.Errorf("%s: expected %q, got %q", expected.name, expected.expected, actual)
+		}
+	}
+}
+
+// numberedLines returns a file of |n| lines, "line 0" to "line n-1", and the
+// expected lines.
+func numberedLines(n int) ([]byte, []string) {
+	var b strings.Builder
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+		b.WriteString(lines[i] + "\r\n")
+	}
+	return []byte(b.String()), lines
+}
+
+func TestReadFileOrdered(t *testing.T) {
+	contents, expected := numberedLines(1000)
+	file := writeTestFile(t, contents)
+
+	var actual []string
+	err := ReadFile(file, &ReadOptions{Workers: 3, Ordered: true, chunkSize: 64}, func(line string) error {
+		actual = append(actual, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %d lines in order, got %d: %q...", len(expected), len(actual), actual[:10])
+	}
+}
+
+func TestReadFileWorkers(t *testing.T) {
+	contents, expected := numberedLines(1000)
+	file := writeTestFile(t, contents)
+
+	const kWorkers = 2
+	var active, maxActive int32
+	var mu sync.Mutex
+	var actual []string
+	err := ReadFile(file, &ReadOptions{Workers: kWorkers, chunkSize: 64}, func(line string) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		actual = append(actual, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxActive > kWorkers {
+		t.Errorf("Expected at most %d concurrent processors, got %d", kWorkers, maxActive)
+	}
+
+	sort.Strings(expected)
+	sort.Strings(actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %d lines, got %d", len(expected), len(actual))
+	}
+}
+
+func TestReadFileLineEndings(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	contents := []byte("a\r\nb\n\r\n" + long + "\r\nlast")
+	expected := []string{"a", "b", long, "last"}
+
+	for _, ordered := range []bool{false, true} {
+		actual := readLines(t, contents, &ReadOptions{Ordered: ordered, chunkSize: 16})
+		sort.Strings(expected)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Ordered=%t: expected %q, got %q", ordered, expected, actual)
+		}
+	}
+}
+
+func TestReadFileError(t *testing.T) {
+	contents, _ := numberedLines(1000)
+	file := writeTestFile(t, contents)
+
+	for _, ordered := range []bool{false, true} {
+		var count int32
+		err := ReadFile(file, &ReadOptions{Workers: 4, Ordered: ordered, chunkSize: 64}, func(line string) error {
+			atomic.AddInt32(&count, 1)
+			if line == "line 100" {
+				return errors.New("bad line")
+			}
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "bad line") {
+			t.Errorf("Ordered=%t: expected the processor error, got %v", ordered, err)
+		}
+		if ordered && count != 101 {
+			t.Errorf("Expected Ordered processing to stop after the error, processed %d lines", count)
+		}
+	}
+}
+
+func TestReadFileContextCancel(t *testing.T) {
+	contents, _ := numberedLines(1000)
+	file := writeTestFile(t, contents)
+
+	for _, ordered := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var count int32
+		err := ReadFileContext(ctx, file, &ReadOptions{Workers: 2, Ordered: ordered, chunkSize: 64}, func(line string) error {
+			if atomic.AddInt32(&count, 1) == 100 {
+				cancel()
+			}
+			return nil
+		})
+		cancel()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Ordered=%t: expected context.Canceled, got %v", ordered, err)
+		}
+		if count >= 1000 {
+			t.Errorf("Ordered=%t: expected reading to stop, processed %d lines", ordered, count)
+		}
+	}
+}
+
+func TestReadFileProgress(t *testing.T) {
+	contents, _ := numberedLines(1000)
+	file := writeTestFile(t, contents)
+
+	var events []Progress
+	opts := &ReadOptions{
+		Workers:   3,
+		chunkSize: 64,
+		Progress: func(p Progress) {
+			events = append(events, p)
+		},
+	}
+	if err := ReadFile(file, opts, func(string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) < 2 {
+		t.Fatalf("Expected several progress events, got %d", len(events))
+	}
+	for i, p := range events {
+		if p.File != file || p.Size != int64(len(contents)) {
+			t.Errorf("Event %d: expected File %q and Size %d, got %+v", i, file, len(contents), p)
+		}
+		if i > 0 && (p.BytesRead < events[i-1].BytesRead || p.Lines < events[i-1].Lines) {
+			t.Errorf("Event %d went backwards: %+v after %+v", i, p, events[i-1])
+		}
+		if p.Done != (i == len(events)-1) {
+			t.Errorf("Event %d: unexpected Done %t", i, p.Done)
+		}
+	}
+	if last := events[len(events)-1]; last.BytesRead != last.Size || last.Lines != 1000 {
+		t.Errorf("Expected the last event to cover the file, got %+v", last)
+	}
+}
+
+func TestReadFileParseError(t *testing.T) {
+	// Line 2 is empty, and so is skipped, but still counted.
+	file := writeTestFile(t, []byte("a^1\r\n\r\nb^x\r\nc^3\r\nd^y\r\n"))
+	processor := func(line string) error {
+		parts := strings.Split(line, "^")
+		if parts[1] == "x" || parts[1] == "y" {
+			return fieldError(2, errors.New("not a number"))
+		}
+		return nil
+	}
+
+	err := ReadFile(file, &ReadOptions{Ordered: true, chunkSize: 4}, processor)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	expected := &ParseError{File: file, Line: 3, Column: 2, Raw: "b^x", Err: pe.Err}
+	if !reflect.DeepEqual(expected, pe) {
+		t.Errorf("Expected %+v, got %+v", expected, pe)
+	}
+	if msg := file + ":3: field 2: not a number"; pe.Error() != msg {
+		t.Errorf("Expected message %q, got %q", msg, pe.Error())
+	}
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errors) != 1 {
+		t.Errorf("Expected a MultiError with one error, got %v", err)
+	}
+
+	var warnings []int
+	opts := &ReadOptions{
+		Ordered:   true,
+		chunkSize: 4,
+		Lenient:   true,
+		Warn: func(pe *ParseError) {
+			warnings = append(warnings, pe.Line)
+		},
+	}
+	if err := ReadFile(file, opts, processor); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{3, 5}; !reflect.DeepEqual(expected, warnings) {
+		t.Errorf("Expected warnings for lines %v, got %v", expected, warnings)
+	}
+}
+
+func TestReadFileWrappedParseError(t *testing.T) {
+	file := writeTestFile(t, []byte("a^1\r\nb^x\r\n"))
+	processor := func(line string) error {
+		if strings.HasSuffix(line, "^x") {
+			return fmt.Errorf("processor: %w", fieldError(2, errors.New("not a number")))
+		}
+		return nil
+	}
+
+	err := ReadFile(file, &ReadOptions{Ordered: true}, processor)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if pe.Line != 2 || pe.Column != 2 || pe.Raw != "b^x" {
+		t.Errorf("Expected line 2 field 2, got %+v", pe)
+	}
+}
@@ -0,0 +1,220 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// A DatabaseDiff describes the differences between two releases of the
+// database. All slices are sorted by their key, so the output is stable.
+type DatabaseDiff struct {
+	// Foods that are only present in the new database.
+	Added []*Food
+	// Foods that are only present in the old database.
+	Removed []*Food
+	// Foods present in both databases that have at least one difference.
+	Changed []FoodDiff
+}
+
+// A FoodDiff lists the changes to a single Food between two releases.
+type FoodDiff struct {
+	NDBID string
+	// The LongDescription in the new database.
+	Description string
+	// Changes to the scalar fields of the Food, e.g. LongDescription.
+	Fields []FieldChange `json:",omitempty"`
+	// Changes to the FoodNutrients, keyed by NutrientID.
+	Nutrients []NutrientChange `json:",omitempty"`
+	// Changes to the Weights, keyed by Sequence.
+	Weights []WeightChange `json:",omitempty"`
+}
+
+// Renamed returns true if the LongDescription of the food changed.
+func (d *FoodDiff) Renamed() bool {
+	for _, f := range d.Fields {
+		if f.Field == "LongDescription" {
+			return true
+		}
+	}
+	return false
+}
+
+// A FieldChange is a change to a scalar field, with values in string form.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// A NutrientChange records an added, removed or modified FoodNutrient. Old is
+// nil for added nutrients and New is nil for removed ones.
+type NutrientChange struct {
+	NutrientID int
+	Old        *FoodNutrient
+	New        *FoodNutrient
+}
+
+// A WeightChange records an added, removed or modified Weight. Old is nil for
+// added weights and New is nil for removed ones.
+type WeightChange struct {
+	Sequence int
+	Old      *Weight
+	New      *Weight
+}
+
+// Empty returns true if the two databases had no differences.
+func (d *DatabaseDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the |old| and |new| databases. Two numeric values are
+// considered equal if they differ by no more than |tolerance|.
+func Diff(old, new *ASCIIDB, tolerance float64) *DatabaseDiff {
+	d := &DatabaseDiff{}
+
+	for _, id := range sortedFoodIDs(new.Foods) {
+		food := new.Foods[id]
+		oldFood, ok := old.Foods[id]
+		if !ok {
+			d.Added = append(d.Added, food)
+			continue
+		}
+		if fd := diffFood(oldFood, food, tolerance); fd != nil {
+			d.Changed = append(d.Changed, *fd)
+		}
+	}
+
+	for _, id := range sortedFoodIDs(old.Foods) {
+		if _, ok := new.Foods[id]; !ok {
+			d.Removed = append(d.Removed, old.Foods[id])
+		}
+	}
+
+	return d
+}
+
+func sortedFoodIDs(foods map[string]*Food) []string {
+	ids := make([]string, 0, len(foods))
+	for id := range foods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// diffFood compares two versions of the same Food and returns nil if they are
+// equivalent.
+func diffFood(old, new *Food, tolerance float64) *FoodDiff {
+	d := &FoodDiff{
+		NDBID:       new.NDBID,
+		Description: new.LongDescription,
+	}
+
+	addField := func(field, o, n string) {
+		if o != n {
+			d.Fields = append(d.Fields, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+	addField("FoodGroup", strconv.Itoa(old.FoodGroup), strconv.Itoa(new.FoodGroup))
+	addField("LongDescription", old.LongDescription, new.LongDescription)
+	addField("ShortDescription", old.ShortDescription, new.ShortDescription)
+	addField("CommonNames", old.CommonNames, new.CommonNames)
+	addField("ScientificName", old.ScientificName, new.ScientificName)
+	addField("Manufacturer", old.Manufacturer, new.Manufacturer)
+	addField("RefuseDescription", old.RefuseDescription, new.RefuseDescription)
+	addField("Refuse", strconv.Itoa(old.Refuse), strconv.Itoa(new.Refuse))
+
+	d.Nutrients = diffNutrients(old.Nutrients, new.Nutrients, tolerance)
+	d.Weights = diffWeights(old.Weights, new.Weights, tolerance)
+
+	if len(d.Fields) == 0 && len(d.Nutrients) == 0 && len(d.Weights) == 0 {
+		return nil
+	}
+	return d
+}
+
+func diffNutrients(old, new []FoodNutrient, tolerance float64) []NutrientChange {
+	oldByID := make(map[int]*FoodNutrient, len(old))
+	for i := range old {
+		oldByID[old[i].NutrientID] = &old[i]
+	}
+	newByID := make(map[int]*FoodNutrient, len(new))
+	for i := range new {
+		newByID[new[i].NutrientID] = &new[i]
+	}
+
+	var changes []NutrientChange
+	for id, n := range newByID {
+		o, ok := oldByID[id]
+		if !ok {
+			changes = append(changes, NutrientChange{NutrientID: id, New: n})
+		} else if !floatEqual(o.Value, n.Value, tolerance) || o.DataPoints != n.DataPoints {
+			changes = append(changes, NutrientChange{NutrientID: id, Old: o, New: n})
+		}
+	}
+	for id, o := range oldByID {
+		if _, ok := newByID[id]; !ok {
+			changes = append(changes, NutrientChange{NutrientID: id, Old: o})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].NutrientID < changes[j].NutrientID
+	})
+	return changes
+}
+
+func diffWeights(old, new []Weight, tolerance float64) []WeightChange {
+	oldBySeq := make(map[int]*Weight, len(old))
+	for i := range old {
+		oldBySeq[old[i].Sequence] = &old[i]
+	}
+	newBySeq := make(map[int]*Weight, len(new))
+	for i := range new {
+		newBySeq[new[i].Sequence] = &new[i]
+	}
+
+	var changes []WeightChange
+	for seq, n := range newBySeq {
+		o, ok := oldBySeq[seq]
+		if !ok {
+			changes = append(changes, WeightChange{Sequence: seq, New: n})
+		} else if o.Description != n.Description ||
+			!floatEqual(o.Amount, n.Amount, tolerance) ||
+			!floatEqual(o.WeightG, n.WeightG, tolerance) {
+			changes = append(changes, WeightChange{Sequence: seq, Old: o, New: n})
+		}
+	}
+	for seq, o := range oldBySeq {
+		if _, ok := newBySeq[seq]; !ok {
+			changes = append(changes, WeightChange{Sequence: seq, Old: o})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Sequence < changes[j].Sequence
+	})
+	return changes
+}
+
+func floatEqual(a, b float32, tolerance float64) bool {
+	return math.Abs(float64(a)-float64(b)) <= tolerance
+}
@@ -0,0 +1,93 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &ASCIIDB{Foods: map[string]*Food{
+		"01001": {
+			NDBID:           "01001",
+			LongDescription: "Butter, salted",
+			Nutrients: []FoodNutrient{
+				{NutrientID: 203, Value: 0.85, DataPoints: 16},
+				{NutrientID: 204, Value: 81.11, DataPoints: 580},
+			},
+			Weights: []Weight{{Sequence: 1, Amount: 1, Description: "cup", WeightG: 227}},
+		},
+		"01002": {NDBID: "01002", LongDescription: "Butter, whipped"},
+	}}
+	new := &ASCIIDB{Foods: map[string]*Food{
+		"01001": {
+			NDBID:           "01001",
+			LongDescription: "Butter, with salt",
+			Nutrients: []FoodNutrient{
+				{NutrientID: 203, Value: 0.86, DataPoints: 16},
+				{NutrientID: 205, Value: 0.06, DataPoints: 0},
+			},
+			Weights: []Weight{{Sequence: 1, Amount: 1, Description: "cup", WeightG: 227}},
+		},
+		"01003": {NDBID: "01003", LongDescription: "Butter oil"},
+	}}
+
+	d := Diff(old, new, 0)
+	if len(d.Added) != 1 || d.Added[0].NDBID != "01003" {
+		t.Errorf("Expected 01003 to be added, got %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].NDBID != "01002" {
+		t.Errorf("Expected 01002 to be removed, got %v", d.Removed)
+	}
+	if len(d.Changed) != 1 {
+		t.Fatalf("Expected 1 changed food, got %d", len(d.Changed))
+	}
+
+	fd := d.Changed[0]
+	if !fd.Renamed() {
+		t.Errorf("Expected 01001 to be renamed, got fields %v", fd.Fields)
+	}
+	if len(fd.Weights) != 0 {
+		t.Errorf("Expected no weight changes, got %v", fd.Weights)
+	}
+
+	expectations := []struct {
+		id     int
+		hasOld bool
+		hasNew bool
+	}{
+		{203, true, true},
+		{204, true, false},
+		{205, false, true},
+	}
+	if len(fd.Nutrients) != len(expectations) {
+		t.Fatalf("Expected %d nutrient changes, got %d", len(expectations), len(fd.Nutrients))
+	}
+	for i, expected := range expectations {
+		actual := fd.Nutrients[i]
+		if actual.NutrientID != expected.id || (actual.Old != nil) != expected.hasOld || (actual.New != nil) != expected.hasNew {
+			t.Errorf("Nutrient change %d: expected %v, got %+v", i, expected, actual)
+		}
+	}
+
+	// With a tolerance, the small change to nutrient 203 is ignored.
+	d = Diff(old, new, 0.05)
+	if n := len(d.Changed[0].Nutrients); n != 2 {
+		t.Errorf("Expected 2 nutrient changes with tolerance, got %d", n)
+	}
+}
@@ -0,0 +1,144 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// An Encoding is the character set of a database file.
+type Encoding int
+
+const (
+	// Windows code page 1252, which the USDA SR releases are written in. It
+	// is a superset of Latin-1 that has printable characters, like curly
+	// quotes, in place of the C1 control codes at 0x80-0x9F.
+	Windows1252 Encoding = iota
+	// ISO 8859-1.
+	Latin1
+	// UTF-8.
+	UTF8
+)
+
+// The UTF-8 byte order mark. If a file starts with this, it is decoded as UTF-8
+// regardless of the requested Encoding.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+func (e Encoding) String() string {
+	switch e {
+	case Windows1252:
+		return "windows-1252"
+	case Latin1:
+		return "latin1"
+	case UTF8:
+		return "utf-8"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// ParseEncoding returns the Encoding for a character set |name|, e.g. from a
+// command line flag.
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(name) {
+	case "windows-1252", "cp1252":
+		return Windows1252, nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return Latin1, nil
+	case "utf-8", "utf8":
+		return UTF8, nil
+	}
+	return 0, fmt.Errorf("Unknown encoding %q", name)
+}
+
+// The code points for bytes 0x80-0x9F in Windows-1252. The five bytes that are
+// unassigned map to the C1 control code of the same value, as Windows does.
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// decode converts |b| from the Encoding to a UTF-8 string.
+func (e Encoding) decode(b []byte) string {
+	if isASCII(b) {
+		return string(b)
+	}
+
+	if e == UTF8 {
+		return string(bytes.ToValidUTF8(b, []byte(string(utf8.RuneError))))
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(b) + len(b)/2)
+	for _, c := range b {
+		r := rune(c)
+		if e == Windows1252 && c >= 0x80 && c <= 0x9F {
+			r = windows1252High[c-0x80]
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// encode converts the UTF-8 string |s| to the Encoding. Returns an error if |s|
+// has a character that the Encoding cannot represent.
+func (e Encoding) encode(s string) ([]byte, error) {
+	if e == UTF8 || isASCII([]byte(s)) {
+		return []byte(s), nil
+	}
+
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := e.encodeRune(r)
+		if !ok {
+			return nil, fmt.Errorf("Cannot encode %q in %s", r, e)
+		}
+		b = append(b, c)
+	}
+	return b, nil
+}
+
+func (e Encoding) encodeRune(r rune) (byte, bool) {
+	if e == Windows1252 {
+		for i, hr := range windows1252High {
+			if hr == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r >= 0x80 && r <= 0x9F {
+			return 0, false
+		}
+	}
+	if r <= 0xFF {
+		return byte(r), true
+	}
+	return 0, false
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
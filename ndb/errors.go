@@ -0,0 +1,75 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ParseError is an error processing a line of a database file.
+type ParseError struct {
+	// The path of the file.
+	File string
+	// The 1-based line number, counting empty lines.
+	Line int
+	// The 1-based field of the line that is invalid, or 0 if the error is not
+	// about a single field.
+	Column int
+	// The decoded line.
+	Raw string
+	// The cause of the error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d: field %d: %v", e.File, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns an error about the 1-based |column| of a line. ReadFile
+// fills in the rest of the ParseError.
+func fieldError(column int, err error) error {
+	return &ParseError{Column: column, Err: err}
+}
+
+// A MultiError holds all the errors that ReadFile encountered in a file, in the
+// order they occurred. The errors are usually *ParseErrors.
+type MultiError struct {
+	File   string
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	return fmt.Sprintf("ReadFile(%s) encountered the following errors:\n\t%s", e.File, strings.Join(errs, "\n\t"))
+}
+
+// Unwrap allows errors.Is and errors.As to match any of the Errors.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
@@ -0,0 +1,672 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+// This file reads the FoodData Central (FDC) downloads, which replace the
+// caret-delimited SR files. FDC is published as a directory of CSV files or as
+// a single JSON document per data type (Foundation, SR Legacy, Branded, FNDDS).
+// Both are mapped onto the same models as the ASCII database:
+//
+//   - Only foods of the fdcFoodDataTypes are loaded. The others, like the
+//     samples that Foundation foods are averaged from, are skipped.
+//   - Foods keep their SR NDB number as the NDBID when one is available (via
+//     sr_legacy_food.csv or the JSON ndbNumber). Otherwise the fdc_id is used.
+//   - Nutrients are keyed by their legacy nutrient_nbr (e.g. 203 for protein),
+//     falling back to the FDC nutrient id for nutrients that have none.
+//   - Food categories with a 4-digit code become FoodGroups.
+//   - Food portions become Weights.
+//   - Branded food label information becomes a BrandedFood.
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rsesek/usda-ndb/bst"
+)
+
+// ReadFDCDatabase reads a FoodData Central CSV download from the directory
+// |base|. Only food.csv and nutrient.csv are required; the other tables are
+// loaded if they are present.
+func ReadFDCDatabase(base string) (*ASCIIDB, error) {
+	db := &ASCIIDB{
+		basePath:   base,
+		Foods:      make(map[string]*Food, 8000),
+		searchTree: bst.NewTree(),
+	}
+	r := &fdcCSVReader{
+		db:           db,
+		categories:   make(map[string]int),
+		nutrients:    make(map[string]int),
+		measureUnits: make(map[string]string),
+		ndbNumbers:   make(map[string]string),
+		foods:        make(map[string]*Food),
+		skipped:      make(map[string]bool),
+	}
+
+	log.Print("Loading FDC food categories")
+	if err := r.readFoodCategories(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC nutrient definitions")
+	if err := r.readNutrients(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC measure units")
+	if err := r.readMeasureUnits(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC SR legacy numbers")
+	if err := r.readSRLegacyFoods(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC food database")
+	if err := r.readFoods(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC branded food information")
+	if err := r.readBrandedFoods(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC food nutrients information")
+	if err := r.readFoodNutrients(); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading FDC portion information")
+	if err := r.readFoodPortions(); err != nil {
+		return nil, err
+	}
+
+	for _, food := range db.Foods {
+		db.addTermsForFood(food)
+		db.addGTINForFood(food)
+	}
+
+	log.Print("Database loaded")
+	log.Printf("... %d foods", len(db.Foods))
+
+	return db, nil
+}
+
+// fdcCSVReader holds the lookup tables needed to join the FDC CSV files.
+type fdcCSVReader struct {
+	db *ASCIIDB
+	// food_category.id to FoodGroup.GroupCode.
+	categories map[string]int
+	// nutrient.id to Nutrient.NutrientID.
+	nutrients map[string]int
+	// measure_unit.id to its name.
+	measureUnits map[string]string
+	// fdc_id to SR NDB number.
+	ndbNumbers map[string]string
+	// fdc_id to Food.
+	foods map[string]*Food
+	// The fdc_ids of the foods that are not of the fdcFoodDataTypes.
+	skipped map[string]bool
+}
+
+// fdcFoodDataTypes are the food.csv data_types that are loaded. The others are
+// the samples and acquisitions behind Foundation foods, which are not foods to
+// look up themselves. A file without the column is loaded in full.
+var fdcFoodDataTypes = map[string]bool{
+	"":                  true,
+	"foundation_food":   true,
+	"sr_legacy_food":    true,
+	"branded_food":      true,
+	"survey_fndds_food": true,
+}
+
+func (r *fdcCSVReader) readFoodCategories() error {
+	return readCSV(path.Join(r.db.basePath, "food_category.csv"), true, func(rec csvRecord) error {
+		code, err := strconv.Atoi(rec.get("code"))
+		if err != nil {
+			return fmt.Errorf("readFoodCategories: code: %v", err)
+		}
+		r.categories[rec.get("id")] = code
+		r.db.FoodGroups = append(r.db.FoodGroups, FoodGroup{
+			GroupCode:   code,
+			Description: rec.get("description"),
+		})
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readNutrients() error {
+	return readCSV(path.Join(r.db.basePath, "nutrient.csv"), false, func(rec csvRecord) error {
+		fdcID, err := strconv.Atoi(rec.get("id"))
+		if err != nil {
+			return fmt.Errorf("readNutrients: id: %v", err)
+		}
+
+		var rank int
+		if s := rec.get("rank"); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("readNutrients: rank: %v", err)
+			}
+			rank = int(f)
+		}
+
+		id := fdcNutrientID(rec.get("nutrient_nbr"), fdcID)
+		r.nutrients[rec.get("id")] = id
+		r.db.Nutrients = append(r.db.Nutrients, Nutrient{
+			NutrientID:  id,
+			Units:       fdcUnits(rec.get("unit_name")),
+			Description: rec.get("name"),
+			SortOrder:   rank,
+		})
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readMeasureUnits() error {
+	return readCSV(path.Join(r.db.basePath, "measure_unit.csv"), true, func(rec csvRecord) error {
+		r.measureUnits[rec.get("id")] = rec.get("name")
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readSRLegacyFoods() error {
+	return readCSV(path.Join(r.db.basePath, "sr_legacy_food.csv"), true, func(rec csvRecord) error {
+		r.ndbNumbers[rec.get("fdc_id")] = fdcNDBNumber(rec.get("NDB_number"))
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readFoods() error {
+	return readCSV(path.Join(r.db.basePath, "food.csv"), false, func(rec csvRecord) error {
+		fdcID := rec.get("fdc_id")
+		numericID, err := strconv.Atoi(fdcID)
+		if err != nil {
+			return fmt.Errorf("readFoods: fdc_id: %v", err)
+		}
+		if !fdcFoodDataTypes[rec.get("data_type")] {
+			r.skipped[fdcID] = true
+			return nil
+		}
+
+		id, ok := r.ndbNumbers[fdcID]
+		if !ok {
+			id = fdcID
+		}
+
+		food := &Food{
+			NDBID:           id,
+			FDCID:           numericID,
+			FoodGroup:       r.categories[rec.get("food_category_id")],
+			LongDescription: rec.get("description"),
+		}
+		r.foods[fdcID] = food
+		r.db.Foods[id] = food
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readBrandedFoods() error {
+	return readCSV(path.Join(r.db.basePath, "branded_food.csv"), true, func(rec csvRecord) error {
+		food, ok := r.foods[rec.get("fdc_id")]
+		if r.skipped[rec.get("fdc_id")] {
+			return nil
+		} else if !ok {
+			return fmt.Errorf("readBrandedFoods: Could not find food %s", rec.get("fdc_id"))
+		}
+
+		var servingSize float64
+		if s := rec.get("serving_size"); s != "" {
+			var err error
+			servingSize, err = strconv.ParseFloat(s, 32)
+			if err != nil {
+				return fmt.Errorf("readBrandedFoods: ServingSize: %v", err)
+			}
+		}
+
+		food.Branded = &BrandedFood{
+			GTIN:             rec.get("gtin_upc"),
+			BrandOwner:       rec.get("brand_owner"),
+			BrandName:        rec.get("brand_name"),
+			ServingSize:      float32(servingSize),
+			ServingSizeUnit:  rec.get("serving_size_unit"),
+			HouseholdServing: rec.get("household_serving_fulltext"),
+			Category:         rec.get("branded_food_category"),
+		}
+		food.Manufacturer = food.Branded.BrandOwner
+		food.Ingredients = rec.get("ingredients")
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readFoodNutrients() error {
+	return readCSV(path.Join(r.db.basePath, "food_nutrient.csv"), true, func(rec csvRecord) error {
+		food, ok := r.foods[rec.get("fdc_id")]
+		if r.skipped[rec.get("fdc_id")] {
+			return nil
+		} else if !ok {
+			return fmt.Errorf("readFoodNutrients: Could not find food %s", rec.get("fdc_id"))
+		}
+
+		nutrientID, ok := r.nutrients[rec.get("nutrient_id")]
+		if !ok {
+			return fmt.Errorf("readFoodNutrients: Could not find nutrient %s", rec.get("nutrient_id"))
+		}
+
+		value, err := strconv.ParseFloat(rec.get("amount"), 32)
+		if err != nil {
+			return fmt.Errorf("readFoodNutrients: Value: %v", err)
+		}
+
+		var dataPoints int
+		if s := rec.get("data_points"); s != "" {
+			dataPoints, err = strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("readFoodNutrients: DataPoints: %v", err)
+			}
+		}
+
+		food.Nutrients = append(food.Nutrients, FoodNutrient{
+			NutrientID: nutrientID,
+			Value:      float32(value),
+			DataPoints: dataPoints,
+		})
+		return nil
+	})
+}
+
+func (r *fdcCSVReader) readFoodPortions() error {
+	return readCSV(path.Join(r.db.basePath, "food_portion.csv"), true, func(rec csvRecord) error {
+		food, ok := r.foods[rec.get("fdc_id")]
+		if r.skipped[rec.get("fdc_id")] {
+			return nil
+		} else if !ok {
+			return fmt.Errorf("readFoodPortions: Could not find food %s", rec.get("fdc_id"))
+		}
+
+		sequence := len(food.Weights) + 1
+		if s := rec.get("seq_num"); s != "" {
+			var err error
+			sequence, err = strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("readFoodPortions: Sequence: %v", err)
+			}
+		}
+
+		amount := 1.0
+		if s := rec.get("amount"); s != "" {
+			var err error
+			amount, err = strconv.ParseFloat(s, 32)
+			if err != nil {
+				return fmt.Errorf("readFoodPortions: Amount: %v", err)
+			}
+		}
+
+		weight, err := strconv.ParseFloat(rec.get("gram_weight"), 32)
+		if err != nil {
+			return fmt.Errorf("readFoodPortions: WeightG: %v", err)
+		}
+
+		food.Weights = append(food.Weights, Weight{
+			Sequence: sequence,
+			Amount:   float32(amount),
+			Description: fdcPortionDescription(rec.get("portion_description"),
+				r.measureUnits[rec.get("measure_unit_id")], rec.get("modifier")),
+			WeightG: float32(weight),
+		})
+		return nil
+	})
+}
+
+// csvRecord is a single row of a CSV file whose fields can be looked up by the
+// column names in the header row.
+type csvRecord struct {
+	header map[string]int
+	fields []string
+}
+
+// get returns the trimmed value of the |column|, or the empty string if the
+// file does not have that column.
+func (r csvRecord) get(column string) string {
+	if i, ok := r.header[column]; ok && i < len(r.fields) {
+		return strings.TrimSpace(r.fields[i])
+	}
+	return ""
+}
+
+// readCSV reads the CSV file at |file| and sends each row after the header to
+// |processor|. If |optional| is true, a missing file is not an error.
+func readCSV(file string, optional bool, processor func(rec csvRecord) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	names, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("readCSV(%s): header: %v", file, err)
+	}
+	rec := csvRecord{header: make(map[string]int, len(names))}
+	for i, name := range names {
+		rec.header[strings.TrimPrefix(name, "\ufeff")] = i
+	}
+
+	for {
+		rec.fields, err = r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("readCSV(%s): %v", file, err)
+		}
+		if err := processor(rec); err != nil {
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("readCSV(%s): line %d: %v", file, line, err)
+		}
+	}
+}
+
+// ReadFDCJSON reads a FoodData Central JSON download. The file is streamed, so
+// that the multi-gigabyte Branded download does not need to fit in memory as
+// a single document.
+func ReadFDCJSON(file string) (*ASCIIDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := &ASCIIDB{
+		basePath:   path.Dir(file),
+		Foods:      make(map[string]*Food, 8000),
+		searchTree: bst.NewTree(),
+	}
+	groups := make(map[int]bool)
+	nutrients := make(map[int]bool)
+
+	log.Print("Loading FDC JSON food database")
+
+	// The document is an object with a single key, e.g. "SRLegacyFoods", that
+	// holds the array of foods.
+	dec := json.NewDecoder(f)
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("ReadFDCJSON(%s): %v", file, err)
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("ReadFDCJSON(%s): %v", file, err)
+		}
+		if err := expectJSONDelim(dec, '['); err != nil {
+			return nil, fmt.Errorf("ReadFDCJSON(%s): %v", file, err)
+		}
+		for dec.More() {
+			var jf fdcJSONFood
+			if err := dec.Decode(&jf); err != nil {
+				return nil, fmt.Errorf("ReadFDCJSON(%s): %v", file, err)
+			}
+			food := jf.toFood()
+			db.Foods[food.NDBID] = food
+			db.addTermsForFood(food)
+			db.addGTINForFood(food)
+
+			if group, ok := jf.foodGroup(); ok && !groups[group.GroupCode] {
+				groups[group.GroupCode] = true
+				db.FoodGroups = append(db.FoodGroups, group)
+			}
+			for _, fn := range jf.FoodNutrients {
+				n := fn.Nutrient.toNutrient()
+				if !nutrients[n.NutrientID] {
+					nutrients[n.NutrientID] = true
+					db.Nutrients = append(db.Nutrients, n)
+				}
+			}
+		}
+		if err := expectJSONDelim(dec, ']'); err != nil {
+			return nil, fmt.Errorf("ReadFDCJSON(%s): %v", file, err)
+		}
+	}
+
+	sort.Slice(db.FoodGroups, func(i, j int) bool {
+		return db.FoodGroups[i].GroupCode < db.FoodGroups[j].GroupCode
+	})
+	sort.Slice(db.Nutrients, func(i, j int) bool {
+		return db.Nutrients[i].SortOrder < db.Nutrients[j].SortOrder
+	})
+
+	log.Print("Database loaded")
+	log.Printf("... %d foods", len(db.Foods))
+
+	return db, nil
+}
+
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("Expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+// fdcJSONFood is the subset of an FDC JSON food record that maps onto Food.
+// Survey (FNDDS) foods use wweiaFoodCategory rather than foodCategory, and
+// only Branded foods have the label fields.
+type fdcJSONFood struct {
+	FdcID        int           `json:"fdcId"`
+	NDBNumber    fdcJSONNumber `json:"ndbNumber"`
+	Description  string        `json:"description"`
+	FoodCategory *struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	} `json:"foodCategory"`
+	WWEIAFoodCategory *struct {
+		Code        int    `json:"wweiaFoodCategoryCode"`
+		Description string `json:"wweiaFoodCategoryDescription"`
+	} `json:"wweiaFoodCategory"`
+	FoodNutrients []struct {
+		Nutrient   fdcJSONNutrient `json:"nutrient"`
+		Amount     float32         `json:"amount"`
+		DataPoints int             `json:"dataPoints"`
+	} `json:"foodNutrients"`
+	FoodPortions []struct {
+		SequenceNumber     int     `json:"sequenceNumber"`
+		Amount             float32 `json:"amount"`
+		GramWeight         float32 `json:"gramWeight"`
+		Modifier           string  `json:"modifier"`
+		PortionDescription string  `json:"portionDescription"`
+		MeasureUnit        struct {
+			Name string `json:"name"`
+		} `json:"measureUnit"`
+	} `json:"foodPortions"`
+
+	GTINUPC             string  `json:"gtinUpc"`
+	BrandOwner          string  `json:"brandOwner"`
+	BrandName           string  `json:"brandName"`
+	Ingredients         string  `json:"ingredients"`
+	ServingSize         float32 `json:"servingSize"`
+	ServingSizeUnit     string  `json:"servingSizeUnit"`
+	HouseholdServing    string  `json:"householdServingFullText"`
+	BrandedFoodCategory string  `json:"brandedFoodCategory"`
+}
+
+func (jf *fdcJSONFood) toFood() *Food {
+	food := &Food{
+		NDBID:           string(jf.NDBNumber),
+		FDCID:           jf.FdcID,
+		LongDescription: jf.Description,
+	}
+	if food.NDBID == "" {
+		food.NDBID = strconv.Itoa(jf.FdcID)
+	}
+	if group, ok := jf.foodGroup(); ok {
+		food.FoodGroup = group.GroupCode
+	}
+	if jf.GTINUPC != "" {
+		food.Branded = &BrandedFood{
+			GTIN:             jf.GTINUPC,
+			BrandOwner:       jf.BrandOwner,
+			BrandName:        jf.BrandName,
+			ServingSize:      jf.ServingSize,
+			ServingSizeUnit:  jf.ServingSizeUnit,
+			HouseholdServing: jf.HouseholdServing,
+			Category:         jf.BrandedFoodCategory,
+		}
+		food.Manufacturer = jf.BrandOwner
+		food.Ingredients = jf.Ingredients
+	}
+
+	for _, fn := range jf.FoodNutrients {
+		food.Nutrients = append(food.Nutrients, FoodNutrient{
+			NutrientID: fn.Nutrient.toNutrient().NutrientID,
+			Value:      fn.Amount,
+			DataPoints: fn.DataPoints,
+		})
+	}
+
+	for i, fp := range jf.FoodPortions {
+		sequence := fp.SequenceNumber
+		if sequence == 0 {
+			sequence = i + 1
+		}
+		amount := fp.Amount
+		if amount == 0 {
+			amount = 1
+		}
+		food.Weights = append(food.Weights, Weight{
+			Sequence:    sequence,
+			Amount:      amount,
+			Description: fdcPortionDescription(fp.PortionDescription, fp.MeasureUnit.Name, fp.Modifier),
+			WeightG:     fp.GramWeight,
+		})
+	}
+
+	return food
+}
+
+func (jf *fdcJSONFood) foodGroup() (FoodGroup, bool) {
+	if c := jf.FoodCategory; c != nil {
+		if code, err := strconv.Atoi(c.Code); err == nil {
+			return FoodGroup{GroupCode: code, Description: c.Description}, true
+		}
+	}
+	if c := jf.WWEIAFoodCategory; c != nil && c.Code != 0 {
+		return FoodGroup{GroupCode: c.Code, Description: c.Description}, true
+	}
+	return FoodGroup{}, false
+}
+
+type fdcJSONNutrient struct {
+	ID       int     `json:"id"`
+	Number   string  `json:"number"`
+	Name     string  `json:"name"`
+	Rank     float64 `json:"rank"`
+	UnitName string  `json:"unitName"`
+}
+
+func (n *fdcJSONNutrient) toNutrient() Nutrient {
+	return Nutrient{
+		NutrientID:  fdcNutrientID(n.Number, n.ID),
+		Units:       fdcUnits(n.UnitName),
+		Description: n.Name,
+		SortOrder:   int(n.Rank),
+	}
+}
+
+// fdcJSONNumber is an NDB number, which is a JSON number in the SR Legacy
+// download but a string in some of the others.
+type fdcJSONNumber string
+
+func (n *fdcJSONNumber) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*n = fdcJSONNumber(fdcNDBNumber(strconv.Itoa(int(v))))
+	case string:
+		*n = fdcJSONNumber(fdcNDBNumber(v))
+	}
+	return nil
+}
+
+// fdcNDBNumber restores the leading zeros that FDC drops from NDB numbers.
+func fdcNDBNumber(s string) string {
+	if s == "" || len(s) >= 5 {
+		return s
+	}
+	return strings.Repeat("0", 5-len(s)) + s
+}
+
+// fdcNutrientID returns the legacy nutrient number |nbr| if it is an integer,
+// or the FDC nutrient |id| otherwise.
+func fdcNutrientID(nbr string, id int) int {
+	if n, err := strconv.Atoi(nbr); err == nil {
+		return n
+	}
+	return id
+}
+
+// fdcUnits converts the upper-case FDC unit names to the SR spelling.
+func fdcUnits(unit string) string {
+	switch u := strings.ToLower(unit); u {
+	case "ug":
+		return "µg"
+	case "kj":
+		return "kJ"
+	case "iu":
+		return "IU"
+	default:
+		return u
+	}
+}
+
+// fdcPortionDescription builds a Weight.Description from the parts of an FDC
+// portion. SR Legacy portions put the whole description in the modifier, and
+// use the "undetermined" measure unit.
+func fdcPortionDescription(description, unit, modifier string) string {
+	if description != "" && description != "Quantity not specified" {
+		return description
+	}
+	if unit == "" || unit == "undetermined" {
+		return modifier
+	}
+	if modifier != "" {
+		return unit + ", " + modifier
+	}
+	return unit
+}
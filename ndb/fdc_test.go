@@ -0,0 +1,205 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadFDCDatabase(t *testing.T) {
+	db, err := ReadFDCDatabase("testdata/fdc/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sample_food, with its nutrient and portion, is skipped.
+	if len(db.Foods) != 3 {
+		t.Errorf("Expected 3 foods, got %d", len(db.Foods))
+	}
+	if food, ok := db.Foods["2345678"]; ok {
+		t.Errorf("Expected the sample food not to be loaded, got %+v", food)
+	}
+	if len(db.FoodGroups) != 2 || db.FoodGroups[1].GroupCode != 1100 {
+		t.Errorf("Unexpected food groups %v", db.FoodGroups)
+	}
+
+	// Nutrients without a legacy number keep their FDC id.
+	expectedNutrients := []Nutrient{
+		{NutrientID: 203, Units: "g", Description: "Protein", SortOrder: 600},
+		{NutrientID: 204, Units: "g", Description: "Total lipid (fat)", SortOrder: 800},
+		{NutrientID: 320, Units: "µg", Description: "Vitamin A, RAE", SortOrder: 7420},
+		{NutrientID: 957, Units: "kcal", Description: "Energy (Atwater General Factors)", SortOrder: 280},
+		{NutrientID: 1405, Units: "g", Description: "Galactose (experimental)", SortOrder: 1700},
+	}
+	if !reflect.DeepEqual(expectedNutrients, db.Nutrients) {
+		t.Errorf("Expected nutrients %v, got %v", expectedNutrients, db.Nutrients)
+	}
+
+	// SR Legacy foods are keyed by their zero-padded NDB number.
+	butter, ok := db.Foods["01001"]
+	if !ok {
+		t.Fatalf("Could not find butter by NDB number")
+	}
+	expected := &Food{
+		NDBID:           "01001",
+		FDCID:           173410,
+		FoodGroup:       100,
+		LongDescription: "Butter, salted",
+		Nutrients: []FoodNutrient{
+			{NutrientID: 203, Value: 0.85, DataPoints: 16},
+			{NutrientID: 204, Value: 81.11, DataPoints: 580},
+			{NutrientID: 320, Value: 684, DataPoints: 0},
+		},
+		Weights: []Weight{
+			{Sequence: 1, Amount: 1, Description: `pat (1" sq, 1/3" high)`, WeightG: 5},
+			{Sequence: 2, Amount: 1, Description: "tbsp", WeightG: 14.2},
+		},
+	}
+	if !reflect.DeepEqual(expected, butter) {
+		t.Errorf("Expected %+v, got %+v", expected, butter)
+	}
+
+	// Other foods are keyed by their fdc_id.
+	broccoli, ok := db.Foods["747447"]
+	if !ok {
+		t.Fatalf("Could not find broccoli by fdc_id")
+	}
+	if broccoli.FoodGroup != 1100 || len(broccoli.Nutrients) != 3 {
+		t.Errorf("Unexpected broccoli %+v", broccoli)
+	}
+	expectedWeights := []Weight{{Sequence: 1, Amount: 1, Description: "cup, chopped", WeightG: 91}}
+	if !reflect.DeepEqual(expectedWeights, broccoli.Weights) {
+		t.Errorf("Expected weights %v, got %v", expectedWeights, broccoli.Weights)
+	}
+
+	if ids := db.FindFood("broccoli"); !reflect.DeepEqual(ids, []string{"747447"}) {
+		t.Errorf("Expected search to find broccoli, got %v", ids)
+	}
+
+	// Branded foods have label information and are indexed by barcode.
+	oil, ok := db.FindFoodByGTIN("27000612323")
+	if !ok {
+		t.Fatalf("Could not find oil by GTIN")
+	}
+	expectedBranded := &BrandedFood{
+		GTIN:             "027000612323",
+		BrandOwner:       "Richardson Oilseed Products (US) Limited",
+		BrandName:        "WESSON",
+		ServingSize:      15,
+		ServingSizeUnit:  "ml",
+		HouseholdServing: "1 Tbsp",
+		Category:         "Oils Edible",
+	}
+	if !reflect.DeepEqual(expectedBranded, oil.Branded) {
+		t.Errorf("Expected %+v, got %+v", expectedBranded, oil.Branded)
+	}
+	if oil.Manufacturer != expectedBranded.BrandOwner {
+		t.Errorf("Expected Manufacturer to be the brand owner, got %q", oil.Manufacturer)
+	}
+	if oil.Ingredients != "Vegetable Oil" {
+		t.Errorf("Expected ingredients from the label, got %q", oil.Ingredients)
+	}
+	if butter.Branded != nil {
+		t.Errorf("Expected butter not to be branded, got %+v", butter.Branded)
+	}
+}
+
+func TestReadFDCJSON(t *testing.T) {
+	db, err := ReadFDCJSON("testdata/fdc/sr_legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.Foods) != 2 {
+		t.Errorf("Expected 2 foods, got %d", len(db.Foods))
+	}
+	expectedGroups := []FoodGroup{{GroupCode: 100, Description: "Dairy and Egg Products"}}
+	if !reflect.DeepEqual(expectedGroups, db.FoodGroups) {
+		t.Errorf("Expected food groups %v, got %v", expectedGroups, db.FoodGroups)
+	}
+	if len(db.Nutrients) != 2 || db.Nutrients[1].Units != "µg" {
+		t.Errorf("Unexpected nutrients %v", db.Nutrients)
+	}
+
+	expected := &Food{
+		NDBID:           "01001",
+		FDCID:           173410,
+		FoodGroup:       100,
+		LongDescription: "Butter, salted",
+		Nutrients: []FoodNutrient{
+			{NutrientID: 203, Value: 0.85, DataPoints: 16},
+			{NutrientID: 320, Value: 684, DataPoints: 0},
+		},
+		Weights: []Weight{
+			{Sequence: 1, Amount: 1, Description: `pat (1" sq, 1/3" high)`, WeightG: 5},
+			{Sequence: 2, Amount: 1, Description: "tbsp", WeightG: 14.2},
+		},
+	}
+	if actual := db.Foods["01001"]; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestReadFDCJSONBranded(t *testing.T) {
+	db, err := ReadFDCJSON("testdata/fdc/branded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	food, ok := db.FindFoodByGTIN("021000615261")
+	if !ok {
+		t.Fatalf("Could not find cheese by UPC-A")
+	}
+	if food.NDBID != "2041155" || food.Manufacturer != "Kraft Heinz Foods Company" {
+		t.Errorf("Unexpected food %+v", food)
+	}
+	if food.Branded == nil || food.Branded.ServingSize != 28 || food.Branded.HouseholdServing != "1 oz" {
+		t.Errorf("Unexpected label information %+v", food.Branded)
+	}
+
+	// The index survives a rebuild, as happens after gob decoding.
+	db.gtinIndex = nil
+	db.RebuildSearchIndex()
+	if _, ok := db.FindFoodByGTIN("00021000615261"); !ok {
+		t.Errorf("Could not find cheese by GTIN-14 after RebuildSearchIndex")
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	expectations := []struct {
+		code     string
+		expected string
+		ok       bool
+	}{
+		{"021000615261", "00021000615261", true},
+		{"0021000615261", "00021000615261", true},
+		{"00021000615261", "00021000615261", true},
+		{" 4006381333931 ", "04006381333931", true},
+		{"", "", false},
+		{"12345-67890", "", false},
+		{"123456789012345", "", false},
+	}
+	for _, expected := range expectations {
+		actual, ok := NormalizeGTIN(expected.code)
+		if actual != expected.expected || ok != expected.ok {
+			t.Errorf("NormalizeGTIN(%q): expected (%q, %t), got (%q, %t)",
+				expected.code, expected.expected, expected.ok, actual, ok)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidFood is wrapped by the errors for a custom food that cannot be
+	// added to the database.
+	ErrInvalidFood = errors.New("Invalid custom food")
+	// ErrFoodExists is returned when creating a custom food whose NDBID is
+	// already used.
+	ErrFoodExists = errors.New("Food already exists")
+	// ErrFoodNotFound is returned when changing a food that does not exist.
+	ErrFoodNotFound = errors.New("Food not found")
+)
+
+// The operations of a JournalEntry.
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+)
+
+// A JournalEntry is a line of the journal: a change to a custom food.
+type JournalEntry struct {
+	Time time.Time
+	// Either "put" to add or replace the Food, or "delete" to remove the food
+	// with the NDBID.
+	Op    string
+	Food  *Food  `json:",omitempty"`
+	NDBID string `json:",omitempty"`
+}
+
+// A Journal records the changes made to the custom foods of a database in an
+// append-only file of JSON lines, so that they can be replayed when the
+// database is next loaded. It is safe for concurrent use, and the database can
+// be read while it makes changes.
+type Journal struct {
+	db   *ASCIIDB
+	file string
+
+	mu   sync.Mutex // Protects the fields below, and serializes the changes to db.
+	f    *os.File
+	size int64 // The length of the complete entries in f.
+	err  error // Set if f could not be restored after a failed write.
+}
+
+// OpenJournal replays the journal |file|, if it exists, into |db| and opens it
+// to record further changes. The foods in the journal have the file as their
+// Source. If the last entry is incomplete, because the server stopped while
+// writing it, it is logged and removed from the file.
+func OpenJournal(file string, db *ASCIIDB) (*Journal, error) {
+	j := &Journal{db: db, file: file}
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("OpenJournal: %v", err)
+	}
+	if err := f.Truncate(j.size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("OpenJournal: %v", err)
+	}
+	j.f = f
+	return j, nil
+}
+
+// replay applies the entries of the journal file, and sets size to the end of
+// the last complete one. An error is a *ParseError for the line.
+func (j *Journal) replay() error {
+	f, err := os.Open(j.file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("OpenJournal: %v", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for num := 1; ; num++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Every entry is written with its newline, so a line without one
+			// is a write that did not finish.
+			if len(line) > 0 {
+				log.Printf("OpenJournal: %s: discarding the incomplete entry at offset %d", j.file, j.size)
+			}
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("OpenJournal: %v", err)
+		}
+		entry := bytes.TrimSpace(line)
+		if err := j.apply(entry); err != nil {
+			return &ParseError{File: j.file, Line: num, Raw: string(entry), Err: fmt.Errorf("offset %d: %v", j.size, err)}
+		}
+		j.size += int64(len(line))
+	}
+}
+
+// apply makes the change of the JSON entry |line|, if it is not empty.
+func (j *Journal) apply(line []byte) error {
+	if len(line) == 0 {
+		return nil
+	}
+	var entry JournalEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+	switch entry.Op {
+	case journalPut:
+		if err := j.db.checkCustomFood(entry.Food); err != nil {
+			return err
+		}
+		j.setSource(entry.Food)
+		j.db.putFood(entry.Food)
+	case journalDelete:
+		if !IsCustomNDBID(entry.NDBID) {
+			return fmt.Errorf("Cannot delete %s, which is not a custom food", entry.NDBID)
+		}
+		j.db.deleteFood(entry.NDBID)
+	default:
+		return fmt.Errorf("Unknown journal operation %q", entry.Op)
+	}
+	return nil
+}
+
+// Create adds the custom |food| to the database. Returns ErrFoodExists if its
+// NDBID is already used.
+func (j *Journal) Create(food *Food) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.check(food); err != nil {
+		return err
+	}
+	if _, ok := j.db.Food(food.NDBID); ok {
+		return fmt.Errorf("Create(%s): %w", food.NDBID, ErrFoodExists)
+	}
+	if err := j.write(JournalEntry{Op: journalPut, Food: food}); err != nil {
+		return err
+	}
+
+	j.setSource(food)
+	j.db.putFood(food)
+	return nil
+}
+
+// Replace replaces the custom food with the NDBID of |food|. Returns
+// ErrFoodNotFound if there is no such food.
+func (j *Journal) Replace(food *Food) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.check(food); err != nil {
+		return err
+	}
+	if _, ok := j.db.Food(food.NDBID); !ok {
+		return fmt.Errorf("Replace(%s): %w", food.NDBID, ErrFoodNotFound)
+	}
+	if err := j.write(JournalEntry{Op: journalPut, Food: food}); err != nil {
+		return err
+	}
+
+	j.setSource(food)
+	j.db.putFood(food)
+	return nil
+}
+
+// Delete removes the custom food |id|. Returns ErrFoodNotFound if there is no
+// such food.
+func (j *Journal) Delete(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !IsCustomNDBID(id) {
+		return fmt.Errorf("Delete(%s): %w: Only custom foods can be deleted", id, ErrInvalidFood)
+	}
+	if _, ok := j.db.Food(id); !ok {
+		return fmt.Errorf("Delete(%s): %w", id, ErrFoodNotFound)
+	}
+	if err := j.write(JournalEntry{Op: journalDelete, NDBID: id}); err != nil {
+		return err
+	}
+
+	j.db.deleteFood(id)
+	return nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
+
+func (j *Journal) check(food *Food) error {
+	if err := j.db.checkCustomFood(food); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFood, err)
+	}
+	if food.LongDescription == "" {
+		return fmt.Errorf("%w: Food %s has no LongDescription", ErrInvalidFood, food.NDBID)
+	}
+	return nil
+}
+
+// write appends |entry| to the journal file and syncs it, so that a change is
+// only made once it is durable. If that fails, the file is truncated back to
+// the previous entry, so that the next write does not follow a partial line.
+func (j *Journal) write(entry JournalEntry) error {
+	if j.err != nil {
+		return fmt.Errorf("Journal: %v", j.err)
+	}
+	entry.Time = time.Now().UTC()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Journal: %v", err)
+	}
+	b = append(b, '\n')
+	_, err = j.f.Write(b)
+	if err == nil {
+		err = j.f.Sync()
+	}
+	if err != nil {
+		if terr := j.f.Truncate(j.size); terr != nil {
+			j.err = fmt.Errorf("%s may end with a partial entry after offset %d: %v", j.file, j.size, terr)
+			return fmt.Errorf("Journal: %v, and %v", err, j.err)
+		}
+		return fmt.Errorf("Journal: %v", err)
+	}
+	j.size += int64(len(b))
+	return nil
+}
+
+// setSource marks |food| and its nutrients as coming from the journal.
+func (j *Journal) setSource(food *Food) {
+	food.Source = j.file
+	for i := range food.Nutrients {
+		food.Nutrients[i].Source = j.file
+	}
+}
@@ -0,0 +1,207 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := OpenJournal(file, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	muffin := &Food{
+		NDBID:           "U100",
+		FoodGroup:       1900,
+		LongDescription: "Muffin, blueberry, bakery",
+		Nutrients:       []FoodNutrient{{NutrientID: 208, Value: 377}},
+	}
+	if err := j.Create(muffin); err != nil {
+		t.Fatal(err)
+	}
+	if ids := db.FindFood("blueberry"); !containsID(ids, "U100") {
+		t.Errorf("Expected the created food to be indexed, got %v", ids)
+	}
+
+	scone := &Food{NDBID: "U101", FoodGroup: 1900, LongDescription: "Scone, plain, bakery"}
+	if err := j.Create(scone); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Replace(&Food{NDBID: "U100", FoodGroup: 1900, LongDescription: "Muffin, bran, bakery"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := db.FindFood("blueberry"); containsID(ids, "U100") {
+		t.Errorf("Expected the replaced description to be removed from the index, got %v", ids)
+	}
+	if err := j.Delete("U101"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectations := []struct {
+		err      error
+		expected error
+	}{
+		{j.Create(&Food{NDBID: "U100", LongDescription: "Duplicate"}), ErrFoodExists},
+		{j.Create(&Food{NDBID: "01001", LongDescription: "Butter"}), ErrInvalidFood},
+		{j.Create(&Food{NDBID: "U102"}), ErrInvalidFood},
+		{j.Create(&Food{NDBID: "U102", LongDescription: "X", Nutrients: []FoodNutrient{{NutrientID: 999}}}), ErrInvalidFood},
+		{j.Replace(&Food{NDBID: "U999", LongDescription: "Missing"}), ErrFoodNotFound},
+		{j.Delete("U101"), ErrFoodNotFound},
+		{j.Delete("01001"), ErrInvalidFood},
+	}
+	for i, e := range expectations {
+		if !errors.Is(e.err, e.expected) {
+			t.Errorf("Expectation %d: expected %v, got %v", i, e.expected, e.err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying the journal into a fresh database gives the same custom foods.
+	db2, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j2, err := OpenJournal(file, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j2.Close()
+
+	if !reflect.DeepEqual(db.Foods, db2.Foods) {
+		t.Errorf("Expected the replayed foods to match")
+	}
+	if _, ok := db2.Foods["U101"]; ok {
+		t.Errorf("Expected the deleted food to stay deleted")
+	}
+	if muffin := db2.Foods["U100"]; muffin.LongDescription != "Muffin, bran, bakery" || muffin.Source != file {
+		t.Errorf("Expected the replaced food with the journal source, got %+v", muffin)
+	}
+	if ids := db2.FindFood("bran"); !containsID(ids, "U100") {
+		t.Errorf("Expected the replayed food to be indexed, got %v", ids)
+	}
+}
+
+// openTestJournal opens the journal |file| over a fresh test database.
+func openTestJournal(t *testing.T, file string) (*Journal, *ASCIIDB) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := OpenJournal(file, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j, db
+}
+
+func TestJournalIncompleteEntry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, _ := openTestJournal(t, file)
+	if err := j.Create(&Food{NDBID: "U100", FoodGroup: 1900, LongDescription: "Muffin"}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server stopped while writing the second entry.
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Op":"put","Food":{"NDBID":"U1`)
+	f.Close()
+
+	j, db := openTestJournal(t, file)
+	if _, ok := db.Foods["U100"]; !ok {
+		t.Errorf("Expected the complete entry to be replayed")
+	}
+	if fi2, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	} else if fi2.Size() != fi.Size() {
+		t.Errorf("Expected the incomplete entry to be removed, leaving %d bytes, got %d", fi.Size(), fi2.Size())
+	}
+	if err := j.Create(&Food{NDBID: "U101", FoodGroup: 1900, LongDescription: "Scone"}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	j, db = openTestJournal(t, file)
+	defer j.Close()
+	if _, ok := db.Foods["U101"]; !ok {
+		t.Errorf("Expected the entry after the removed one to be replayed")
+	}
+}
+
+func TestJournalInvalidEntry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	entries := `{"Op":"delete","NDBID":"U100"}` + "\n" + `{"Op":"burn"}` + "\n"
+	if err := os.WriteFile(file, []byte(entries), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenJournal(file, db)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 2 {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+func TestJournalFailedWrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, db := openTestJournal(t, file)
+	defer j.Close()
+
+	// A file that can be neither written nor truncated.
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.f.Close()
+	j.f = f
+
+	for _, id := range []string{"U100", "U101"} {
+		if err := j.Create(&Food{NDBID: id, FoodGroup: 1900, LongDescription: "Muffin"}); err == nil {
+			t.Errorf("Expected creating %s to fail", id)
+		}
+		if _, ok := db.Foods[id]; ok {
+			t.Errorf("Expected %s not to be added after the write failed", id)
+		}
+	}
+	if j.err == nil {
+		t.Errorf("Expected the journal to refuse further changes")
+	}
+}
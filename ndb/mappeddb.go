@@ -0,0 +1,774 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// A mapped database file starts with this, and the version of the format.
+const kMappedDBMagic = "NDBMMAP4"
+
+// The header is the magic bytes and then the offsets of the food group, nutrient
+// and food index sections, and the numbers of foods and of barcodes, as
+// little-endian uint64s.
+const kMappedDBHeaderSize = len(kMappedDBMagic) + 5*8
+
+// Each entry of the food index is the offset of the food's record as a uint64,
+// and the lengths of its NDBID and of the record as uint32s.
+const kMappedDBIndexEntrySize = 16
+
+// Each entry of the barcode index is a GTIN-14 as a number and the position of
+// its food in the food index, as uint64s.
+const kMappedDBGTINEntrySize = 16
+
+// WriteMappedDB writes |db| to |w| in the format read by OpenMappedDB. The food
+// groups and nutrients are followed by the index of the foods, sorted by NDBID,
+// the index of the barcodes of the branded foods, sorted by GTIN, and then a
+// record for each food: its NDBID and its fields. Numbers in the
+// sections and records are varints, floats are little-endian float32s, and
+// strings are a length and then the bytes. Booleans, and whether an optional
+// number or struct follows, are a byte of 0 or 1. The search index is not
+// written; see WriteSearchIndex.
+func WriteMappedDB(db *ASCIIDB, w io.Writer) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var groups bytes.Buffer
+	putUvarint(&groups, len(db.FoodGroups))
+	for _, g := range db.FoodGroups {
+		putVarint(&groups, g.GroupCode)
+		putString(&groups, g.Description)
+	}
+
+	var nutrients bytes.Buffer
+	putUvarint(&nutrients, len(db.Nutrients))
+	for _, n := range db.Nutrients {
+		putVarint(&nutrients, n.NutrientID)
+		putString(&nutrients, n.Units)
+		putString(&nutrients, n.Description)
+		putVarint(&nutrients, n.SortOrder)
+		putString(&nutrients, n.Tagname)
+		putVarint(&nutrients, n.Decimals)
+	}
+
+	ids := make([]string, 0, len(db.Foods))
+	for id := range db.Foods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// A barcode shared by several foods finds the last of them.
+	gtins := make(map[uint64]int)
+	for i, id := range ids {
+		food := db.Foods[id]
+		if food.Branded == nil {
+			continue
+		}
+		if gtin, ok := NormalizeGTIN(food.Branded.GTIN); ok {
+			n, _ := strconv.ParseUint(gtin, 10, 64)
+			gtins[n] = i
+		}
+	}
+	gtinIndex := make([]byte, 0, len(gtins)*kMappedDBGTINEntrySize)
+	for gtin, i := range gtins {
+		var entry [kMappedDBGTINEntrySize]byte
+		binary.LittleEndian.PutUint64(entry[:], gtin)
+		binary.LittleEndian.PutUint64(entry[8:], uint64(i))
+		gtinIndex = append(gtinIndex, entry[:]...)
+	}
+	sort.Sort(gtinEntries(gtinIndex))
+
+	groupsOffset := kMappedDBHeaderSize
+	nutrientsOffset := groupsOffset + groups.Len()
+	// Align the index, so that its entries can be read in place.
+	padding := -(nutrientsOffset + nutrients.Len()) & 7
+	indexOffset := nutrientsOffset + nutrients.Len() + padding
+	recordsOffset := indexOffset + len(ids)*kMappedDBIndexEntrySize + len(gtinIndex)
+
+	index := make([]byte, len(ids)*kMappedDBIndexEntrySize)
+	var records bytes.Buffer
+	for i, id := range ids {
+		start := records.Len()
+		records.WriteString(id)
+		putFood(&records, db.Foods[id])
+		entry := index[i*kMappedDBIndexEntrySize:]
+		binary.LittleEndian.PutUint64(entry, uint64(recordsOffset+start))
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(id)))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(records.Len()-start))
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(kMappedDBMagic)
+	for _, v := range []int{groupsOffset, nutrientsOffset, indexOffset, len(ids), len(gtins)} {
+		binary.Write(bw, binary.LittleEndian, uint64(v))
+	}
+	groups.WriteTo(bw)
+	nutrients.WriteTo(bw)
+	bw.Write(make([]byte, padding))
+	bw.Write(index)
+	bw.Write(gtinIndex)
+	records.WriteTo(bw)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("WriteMappedDB: %v", err)
+	}
+	return nil
+}
+
+// gtinEntries sorts the entries of a barcode index by GTIN.
+type gtinEntries []byte
+
+func (e gtinEntries) Len() int {
+	return len(e) / kMappedDBGTINEntrySize
+}
+
+func (e gtinEntries) Less(i, j int) bool {
+	return binary.LittleEndian.Uint64(e[i*kMappedDBGTINEntrySize:]) < binary.LittleEndian.Uint64(e[j*kMappedDBGTINEntrySize:])
+}
+
+func (e gtinEntries) Swap(i, j int) {
+	var tmp [kMappedDBGTINEntrySize]byte
+	a, b := e[i*kMappedDBGTINEntrySize:(i+1)*kMappedDBGTINEntrySize], e[j*kMappedDBGTINEntrySize:(j+1)*kMappedDBGTINEntrySize]
+	copy(tmp[:], a)
+	copy(a, b)
+	copy(b, tmp[:])
+}
+
+// putFood writes the fields of |food| other than the NDBID, which starts the
+// record.
+func putFood(w *bytes.Buffer, food *Food) {
+	putVarint(w, food.FDCID)
+	putVarint(w, food.FoodGroup)
+	putString(w, food.LongDescription)
+	putString(w, food.ShortDescription)
+	putString(w, food.CommonNames)
+	putString(w, food.ScientificName)
+	putString(w, food.Manufacturer)
+	putString(w, food.Ingredients)
+	putString(w, food.RefuseDescription)
+	putVarint(w, food.Refuse)
+	putBool(w, food.Survey)
+	putOptFloat32(w, food.NitrogenFactor)
+	putOptFloat32(w, food.ProteinFactor)
+	putOptFloat32(w, food.FatFactor)
+	putOptFloat32(w, food.CarbohydrateFactor)
+	putUvarint(w, len(food.Nutrients))
+	for _, n := range food.Nutrients {
+		putVarint(w, n.NutrientID)
+		putFloat32(w, n.Value)
+		putVarint(w, n.DataPoints)
+		putString(w, n.Source)
+		st := n.Stats
+		putBool(w, st != nil)
+		if st == nil {
+			continue
+		}
+		putOptFloat32(w, st.StdError)
+		putString(w, st.SourceCode)
+		putString(w, st.DerivationCode)
+		putString(w, st.RefNDBID)
+		putBool(w, st.Added)
+		putOptVarint(w, st.Studies)
+		putOptFloat32(w, st.Min)
+		putOptFloat32(w, st.Max)
+		putOptVarint(w, st.DegreesOfFreedom)
+		putOptFloat32(w, st.LowerErrorBound)
+		putOptFloat32(w, st.UpperErrorBound)
+		putString(w, st.Comments)
+		putString(w, st.Modified)
+		putString(w, st.ConfidenceCode)
+	}
+	putUvarint(w, len(food.Weights))
+	for _, wt := range food.Weights {
+		putVarint(w, wt.Sequence)
+		putFloat32(w, wt.Amount)
+		putString(w, wt.Description)
+		putFloat32(w, wt.WeightG)
+		putOptVarint(w, wt.DataPoints)
+		putOptFloat32(w, wt.StdDev)
+	}
+	if b := food.Branded; b != nil {
+		w.WriteByte(1)
+		putString(w, b.GTIN)
+		putString(w, b.BrandOwner)
+		putString(w, b.BrandName)
+		putFloat32(w, b.ServingSize)
+		putString(w, b.ServingSizeUnit)
+		putString(w, b.HouseholdServing)
+		putString(w, b.Category)
+	} else {
+		w.WriteByte(0)
+	}
+	putUvarint(w, len(food.Footnotes))
+	for _, f := range food.Footnotes {
+		putVarint(w, f.Number)
+		putString(w, f.Type)
+		putVarint(w, f.NutrientID)
+		putString(w, f.Text)
+	}
+	putString(w, food.Source)
+}
+
+func putVarint(w io.Writer, v int) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutVarint(buf[:], int64(v))])
+}
+
+func putFloat32(w io.Writer, f float32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], math.Float32bits(f))
+	w.Write(buf[:])
+}
+
+func putBool(w *bytes.Buffer, b bool) {
+	if b {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func putOptVarint(w *bytes.Buffer, v *int) {
+	putBool(w, v != nil)
+	if v != nil {
+		putVarint(w, *v)
+	}
+}
+
+func putOptFloat32(w *bytes.Buffer, f *float32) {
+	putBool(w, f != nil)
+	if f != nil {
+		putFloat32(w, *f)
+	}
+}
+
+// A MappedDB is a read-only database in a file written by WriteMappedDB, which is
+// memory-mapped rather than read. The food groups and nutrients are decoded when
+// it is opened, but each Food only when it is accessed, and it is not kept, so
+// opening it is fast and uses little memory however large the database is. It is safe for
+// concurrent use. It can only be searched once its search index is opened with
+// OpenSearchIndex.
+type MappedDB struct {
+	FoodGroups []FoodGroup
+	Nutrients  []Nutrient
+
+	file string
+
+	// Guards the sections of the file, which Close unmaps.
+	mu     sync.RWMutex
+	data   []byte // The mapped file.
+	index  []byte // The food index within |data|.
+	gtins  []byte // The barcode index within |data|.
+	search *SearchIndex
+}
+
+// OpenMappedDB maps the database |file|, which must not be changed while it is
+// open. Close the MappedDB to unmap it.
+func OpenMappedDB(file string) (*MappedDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("OpenMappedDB: %v", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("OpenMappedDB: %v", err)
+	}
+	if fi.Size() < int64(kMappedDBHeaderSize) || fi.Size() > math.MaxInt {
+		return nil, fmt.Errorf("OpenMappedDB(%s): not a mapped database", file)
+	}
+	data, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("OpenMappedDB(%s): %v", file, err)
+	}
+
+	db := &MappedDB{file: file, data: data}
+	if err := db.readSections(); err != nil {
+		unmapFile(data)
+		return nil, fmt.Errorf("OpenMappedDB(%s): %v", file, err)
+	}
+	return db, nil
+}
+
+func (db *MappedDB) readSections() error {
+	if string(db.data[:len(kMappedDBMagic)]) != kMappedDBMagic {
+		return fmt.Errorf("not a mapped database, or an unsupported version: %q", db.data[:len(kMappedDBMagic)])
+	}
+	var header [5]uint64
+	for i := range header {
+		header[i] = binary.LittleEndian.Uint64(db.data[len(kMappedDBMagic)+i*8:])
+		if header[i] > uint64(len(db.data)) {
+			return fmt.Errorf("header field %d is past the end of the file", i)
+		}
+	}
+	groupsOffset, nutrientsOffset, indexOffset, count, gtinCount := int(header[0]), int(header[1]), int(header[2]), int(header[3]), int(header[4])
+	if groupsOffset < kMappedDBHeaderSize || nutrientsOffset < groupsOffset || indexOffset < nutrientsOffset {
+		return fmt.Errorf("the sections are out of order")
+	}
+	if indexOffset+count*kMappedDBIndexEntrySize > len(db.data) {
+		return fmt.Errorf("the index of %d foods is past the end of the file", count)
+	}
+	db.index = db.data[indexOffset : indexOffset+count*kMappedDBIndexEntrySize]
+	gtinsOffset := indexOffset + len(db.index)
+	if gtinsOffset+gtinCount*kMappedDBGTINEntrySize > len(db.data) {
+		return fmt.Errorf("the index of %d barcodes is past the end of the file", gtinCount)
+	}
+	db.gtins = db.data[gtinsOffset : gtinsOffset+gtinCount*kMappedDBGTINEntrySize]
+
+	d := &recordDecoder{data: db.data[groupsOffset:nutrientsOffset]}
+	if n := d.count(); n > 0 {
+		db.FoodGroups = make([]FoodGroup, n)
+	}
+	for i := range db.FoodGroups {
+		db.FoodGroups[i] = FoodGroup{GroupCode: d.varint(), Description: d.string()}
+	}
+	if d.err != nil {
+		return fmt.Errorf("food groups: %v", d.err)
+	}
+
+	d = &recordDecoder{data: db.data[nutrientsOffset:indexOffset]}
+	if n := d.count(); n > 0 {
+		db.Nutrients = make([]Nutrient, n)
+	}
+	for i := range db.Nutrients {
+		db.Nutrients[i] = Nutrient{
+			NutrientID:  d.varint(),
+			Units:       d.string(),
+			Description: d.string(),
+			SortOrder:   d.varint(),
+			Tagname:     d.string(),
+			Decimals:    d.varint(),
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("nutrients: %v", d.err)
+	}
+
+	// Check the index, so that looking up a food cannot go out of bounds.
+	var last []byte
+	for i := 0; i < count; i++ {
+		offset, idLen, recLen := db.entry(i)
+		if offset < 0 || idLen > recLen || offset > len(db.data) || recLen > len(db.data)-offset {
+			return fmt.Errorf("food %d is past the end of the file", i)
+		}
+		id := db.data[offset : offset+idLen]
+		if i > 0 && bytes.Compare(id, last) <= 0 {
+			return fmt.Errorf("food %q is out of order", id)
+		}
+		last = id
+	}
+	for i := 0; i < gtinCount; i++ {
+		gtin, food := db.gtinEntry(i)
+		if i > 0 {
+			if prev, _ := db.gtinEntry(i - 1); gtin <= prev {
+				return fmt.Errorf("barcode %d is out of order", gtin)
+			}
+		}
+		if food >= uint64(count) {
+			return fmt.Errorf("barcode %d is for food %d of %d", gtin, food, count)
+		}
+	}
+	return nil
+}
+
+// entry returns the offset of the |i|th food record, and the lengths of its NDBID
+// and of the whole record.
+func (db *MappedDB) entry(i int) (offset, idLen, recLen int) {
+	e := db.index[i*kMappedDBIndexEntrySize:]
+	return int(binary.LittleEndian.Uint64(e)), int(binary.LittleEndian.Uint32(e[8:])), int(binary.LittleEndian.Uint32(e[12:]))
+}
+
+// gtinEntry returns the |i|th barcode, and the position of its food in the food
+// index.
+func (db *MappedDB) gtinEntry(i int) (gtin, food uint64) {
+	e := db.gtins[i*kMappedDBGTINEntrySize:]
+	return binary.LittleEndian.Uint64(e), binary.LittleEndian.Uint64(e[8:])
+}
+
+func (db *MappedDB) id(i int) string {
+	offset, idLen, _ := db.entry(i)
+	return string(db.data[offset : offset+idLen])
+}
+
+func (db *MappedDB) numFoods() int {
+	return len(db.index) / kMappedDBIndexEntrySize
+}
+
+// Len returns the number of foods, which is 0 once the MappedDB is closed.
+func (db *MappedDB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.numFoods()
+}
+
+// FoodIDs returns the NDBIDs of all the foods, sorted.
+func (db *MappedDB) FoodIDs() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	ids := make([]string, db.numFoods())
+	for i := range ids {
+		ids[i] = db.id(i)
+	}
+	return ids
+}
+
+// AllFoodGroups returns the food groups, for the Reader interface.
+func (db *MappedDB) AllFoodGroups() []FoodGroup {
+	return db.FoodGroups
+}
+
+// AllNutrients returns the nutrient definitions, for the Reader interface.
+func (db *MappedDB) AllNutrients() []Nutrient {
+	return db.Nutrients
+}
+
+// Food decodes the Food with NDBID |id|. Each call returns a new Food, which
+// the caller may keep after the MappedDB is closed.
+func (db *MappedDB) Food(id string) (*Food, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.food(id)
+}
+
+func (db *MappedDB) food(id string) (*Food, bool) {
+	n := db.numFoods()
+	i := sort.Search(n, func(i int) bool {
+		offset, idLen, _ := db.entry(i)
+		return string(db.data[offset:offset+idLen]) >= id
+	})
+	if i == n || db.id(i) != id {
+		return nil, false
+	}
+	offset, idLen, recLen := db.entry(i)
+	d := &recordDecoder{data: db.data[offset+idLen : offset+recLen]}
+	food := d.food()
+	food.NDBID = id
+	if d.err != nil {
+		log.Printf("%s: food %s: %v", db.file, id, d.err)
+		return nil, false
+	}
+	return food, true
+}
+
+// FindFoodByGTIN returns the branded Food with the barcode |gtin|, which can
+// be in any form accepted by NormalizeGTIN.
+func (db *MappedDB) FindFoodByGTIN(gtin string) (*Food, bool) {
+	gtin, ok := NormalizeGTIN(gtin)
+	if !ok {
+		return nil, false
+	}
+	n, _ := strconv.ParseUint(gtin, 10, 64)
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	count := len(db.gtins) / kMappedDBGTINEntrySize
+	i := sort.Search(count, func(i int) bool {
+		g, _ := db.gtinEntry(i)
+		return g >= n
+	})
+	if i == count {
+		return nil, false
+	}
+	g, food := db.gtinEntry(i)
+	if g != n {
+		return nil, false
+	}
+	return db.food(db.id(int(food)))
+}
+
+// OpenSearchIndex opens the search index |file|, which must have been written by
+// WriteSearchIndex from the same foods, for Search. The MappedDB closes it when
+// it is closed.
+func (db *MappedDB) OpenSearchIndex(file string) error {
+	ix, err := OpenSearchIndex(file)
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	// The tokens and the foods are both sorted, so check that each token is a
+	// food in one pass.
+	j, n := 0, db.numFoods()
+	for i := 0; i < ix.numTokens(); i++ {
+		token := ix.token(i)
+		for j < n && db.id(j) < token {
+			j++
+		}
+		if j == n || db.id(j) != token {
+			ix.Close()
+			return fmt.Errorf("OpenSearchIndex(%s): the index is not for this database: no food %s", file, token)
+		}
+	}
+	if db.search != nil {
+		db.search.Close()
+	}
+	db.search = ix
+	return nil
+}
+
+func (db *MappedDB) searchIndex() *SearchIndex {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.search
+}
+
+// FindFood returns the NDBIDs of the foods named |name|, as ASCIIDB.FindFood
+// does, or nil if there are none or the MappedDB has no search index.
+func (db *MappedDB) FindFood(name string) []string {
+	ix := db.searchIndex()
+	if ix == nil {
+		return nil
+	}
+	postings := ix.FindPostings(name)
+	if postings == nil {
+		return nil
+	}
+	ids := make([]string, len(postings))
+	for i, p := range postings {
+		ids[i] = p.Token
+	}
+	return ids
+}
+
+// Search finds the foods matching any of the space-separated terms of |query|,
+// best first, as ASCIIDB.Search does. The result is empty if the MappedDB has
+// no search index.
+func (db *MappedDB) Search(query string) []SearchResult {
+	ix := db.searchIndex()
+	if ix == nil {
+		return []SearchResult{}
+	}
+	return search(query, ix.FindPostings, db.Food)
+}
+
+// Close unmaps the file, and the search index. The foods that have been returned
+// can still be used, but the MappedDB has no foods after it is closed.
+func (db *MappedDB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.search != nil {
+		db.search.Close()
+		db.search = nil
+	}
+	data := db.data
+	db.data, db.index, db.gtins = nil, nil, nil
+	if data == nil {
+		return nil
+	}
+	return unmapFile(data)
+}
+
+// A recordDecoder reads the fields of a record written by WriteMappedDB. After
+// an error, every read returns the zero value, so check err once at the end.
+type recordDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *recordDecoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+	d.data = nil
+}
+
+func (d *recordDecoder) uvarint() int {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 || v > math.MaxInt32 {
+		d.fail("invalid number")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(v)
+}
+
+func (d *recordDecoder) varint() int {
+	v, n := binary.Varint(d.data)
+	if n <= 0 || v > math.MaxInt32 || v < math.MinInt32 {
+		d.fail("invalid number")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(v)
+}
+
+// count reads the number of items in a list, each of which takes at least a
+// byte.
+func (d *recordDecoder) count() int {
+	n := d.uvarint()
+	if n > len(d.data) {
+		d.fail("count %d is more than the %d bytes left", n, len(d.data))
+		return 0
+	}
+	return n
+}
+
+func (d *recordDecoder) string() string {
+	n := d.uvarint()
+	if n > len(d.data) {
+		d.fail("string length %d is more than the %d bytes left", n, len(d.data))
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *recordDecoder) float32() float32 {
+	if len(d.data) < 4 {
+		d.fail("truncated float")
+		return 0
+	}
+	f := math.Float32frombits(binary.LittleEndian.Uint32(d.data))
+	d.data = d.data[4:]
+	return f
+}
+
+func (d *recordDecoder) byte() byte {
+	if len(d.data) < 1 {
+		d.fail("truncated record")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *recordDecoder) bool() bool {
+	switch d.byte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	d.fail("invalid boolean")
+	return false
+}
+
+func (d *recordDecoder) optVarint() *int {
+	if !d.bool() {
+		return nil
+	}
+	v := d.varint()
+	return &v
+}
+
+func (d *recordDecoder) optFloat32() *float32 {
+	if !d.bool() {
+		return nil
+	}
+	f := d.float32()
+	return &f
+}
+
+// stats reads the NutrientStats of a FoodNutrient written by putFood.
+func (d *recordDecoder) stats() *NutrientStats {
+	st := &NutrientStats{StdError: d.optFloat32()}
+	st.SourceCode = d.string()
+	st.DerivationCode = d.string()
+	st.RefNDBID = d.string()
+	st.Added = d.bool()
+	st.Studies = d.optVarint()
+	st.Min = d.optFloat32()
+	st.Max = d.optFloat32()
+	st.DegreesOfFreedom = d.optVarint()
+	st.LowerErrorBound = d.optFloat32()
+	st.UpperErrorBound = d.optFloat32()
+	st.Comments = d.string()
+	st.Modified = d.string()
+	st.ConfidenceCode = d.string()
+	return st
+}
+
+// food reads the fields of a Food written by putFood.
+func (d *recordDecoder) food() *Food {
+	food := &Food{
+		FDCID:             d.varint(),
+		FoodGroup:         d.varint(),
+		LongDescription:   d.string(),
+		ShortDescription:  d.string(),
+		CommonNames:       d.string(),
+		ScientificName:    d.string(),
+		Manufacturer:      d.string(),
+		Ingredients:       d.string(),
+		RefuseDescription: d.string(),
+		Refuse:            d.varint(),
+		Survey:            d.bool(),
+	}
+	food.NitrogenFactor = d.optFloat32()
+	food.ProteinFactor = d.optFloat32()
+	food.FatFactor = d.optFloat32()
+	food.CarbohydrateFactor = d.optFloat32()
+	if n := d.count(); n > 0 {
+		food.Nutrients = make([]FoodNutrient, n)
+	}
+	for i := range food.Nutrients {
+		food.Nutrients[i] = FoodNutrient{NutrientID: d.varint(), Value: d.float32(), DataPoints: d.varint(), Source: d.string()}
+		if d.bool() {
+			food.Nutrients[i].Stats = d.stats()
+		}
+	}
+	if n := d.count(); n > 0 {
+		food.Weights = make([]Weight, n)
+	}
+	for i := range food.Weights {
+		food.Weights[i] = Weight{
+			Sequence:    d.varint(),
+			Amount:      d.float32(),
+			Description: d.string(),
+			WeightG:     d.float32(),
+			DataPoints:  d.optVarint(),
+			StdDev:      d.optFloat32(),
+		}
+	}
+	switch d.byte() {
+	case 0:
+	case 1:
+		food.Branded = &BrandedFood{
+			GTIN:             d.string(),
+			BrandOwner:       d.string(),
+			BrandName:        d.string(),
+			ServingSize:      d.float32(),
+			ServingSizeUnit:  d.string(),
+			HouseholdServing: d.string(),
+			Category:         d.string(),
+		}
+	default:
+		d.fail("invalid branded flag")
+	}
+	if n := d.count(); n > 0 {
+		food.Footnotes = make([]Footnote, n)
+	}
+	for i := range food.Footnotes {
+		food.Footnotes[i] = Footnote{Number: d.varint(), Type: d.string(), NutrientID: d.varint(), Text: d.string()}
+	}
+	food.Source = d.string()
+	if d.err == nil && len(d.data) != 0 {
+		d.fail("%d bytes left over", len(d.data))
+	}
+	return food
+}
@@ -0,0 +1,187 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchmarkDatabase returns a database the size of an SR release, made of copies
+// of the test foods.
+func benchmarkDatabase(b *testing.B) *ASCIIDB {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		b.Fatal(err)
+	}
+	foods := make([]*Food, 0, len(db.Foods))
+	for _, food := range db.Foods {
+		foods = append(foods, food)
+	}
+	for i := 0; len(db.Foods) < 8000; i++ {
+		food := *foods[i%len(foods)]
+		food.NDBID = fmt.Sprintf("B%05d", i)
+		db.Foods[food.NDBID] = &food
+	}
+	db.RebuildSearchIndex()
+	return db
+}
+
+// The benchmarks compare starting a server from a gob and its search index, as
+// appengine does, with starting it from a mapped database. Each iteration runs
+// TestOpenProcess in a new process, and the resident memory of the processes is
+// reported: unlike the heap, it counts the mapped pages that are read. The
+// rss-bytes are what the process keeps once it has started, and the
+// peak-rss-bytes include the garbage of starting it.
+func BenchmarkOpenGob(b *testing.B) {
+	db := benchmarkDatabase(b)
+	file := filepath.Join(b.TempDir(), "asciidb.gob.gz")
+	f, err := os.Create(file)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(db); err != nil {
+		b.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+	benchmarkOpen(b, "gob", file, writeSearchIndexFile(b, db))
+}
+
+func BenchmarkOpenMapped(b *testing.B) {
+	db := benchmarkDatabase(b)
+	benchmarkOpen(b, "mmap", writeMappedTestDB(b, db), writeSearchIndexFile(b, db))
+}
+
+func benchmarkOpen(b *testing.B, format, file, index string) {
+	var rss, peak int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestOpenProcess$")
+		cmd.Env = append(os.Environ(), "NDB_OPEN_FORMAT="+format, "NDB_OPEN_FILE="+file, "NDB_OPEN_INDEX="+index)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			b.Fatalf("%v: %s", err, out)
+		}
+		// The rusage of the process is no use, as its peak includes this
+		// process's memory from before it called exec.
+		m := regexp.MustCompile(`NDB_OPEN_RSS (\d+) (\d+)`).FindSubmatch(out)
+		if m == nil {
+			b.Fatalf("No memory use in the output: %s", out)
+		}
+		r, _ := strconv.Atoi(string(m[1]))
+		p, _ := strconv.Atoi(string(m[2]))
+		rss, peak = max(rss, r), max(peak, p)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(rss), "rss-bytes")
+	b.ReportMetric(float64(peak), "peak-rss-bytes")
+}
+
+// memoryUse returns the resident memory of this process and its peak, in bytes,
+// from /proc/self/status.
+func memoryUse() (rss, peak int, err error) {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		var kb int
+		if _, err := fmt.Sscanf(line, "VmRSS: %d kB", &kb); err == nil {
+			rss = kb * 1024
+		} else if _, err := fmt.Sscanf(line, "VmHWM: %d kB", &kb); err == nil {
+			peak = kb * 1024
+		}
+	}
+	if rss == 0 || peak == 0 {
+		return 0, 0, fmt.Errorf("no VmRSS and VmHWM in /proc/self/status")
+	}
+	return rss, peak, nil
+}
+
+// TestOpenProcess opens the database for benchmarkOpen, and serves a food and a
+// search from it, in the process that it starts. It does nothing otherwise.
+func TestOpenProcess(t *testing.T) {
+	file, index := os.Getenv("NDB_OPEN_FILE"), os.Getenv("NDB_OPEN_INDEX")
+	var db Reader
+	switch format := os.Getenv("NDB_OPEN_FORMAT"); format {
+	case "":
+		return
+	case "gob":
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var asciidb *ASCIIDB
+		if err := gob.NewDecoder(r).Decode(&asciidb); err != nil {
+			t.Fatal(err)
+		}
+		ix, err := os.Open(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ix.Close()
+		if err := asciidb.ReadSearchIndex(ix); err != nil {
+			t.Fatal(err)
+		}
+		db = asciidb
+	case "mmap":
+		mapped, err := OpenMappedDB(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer mapped.Close()
+		if err := mapped.OpenSearchIndex(index); err != nil {
+			t.Fatal(err)
+		}
+		db = mapped
+	default:
+		t.Fatalf("Unknown format %q", format)
+	}
+
+	if _, ok := db.Food("01001"); !ok {
+		t.Errorf("No food 01001")
+	}
+	if results := db.Search("butter"); len(results) == 0 {
+		t.Errorf("No results for butter")
+	}
+
+	debug.FreeOSMemory()
+	rss, peak, err := memoryUse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("NDB_OPEN_RSS %d %d\n", rss, peak)
+	runtime.KeepAlive(db)
+}
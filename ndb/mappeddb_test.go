@@ -0,0 +1,228 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeMappedTestDB writes |db| to a mapped database file in a temporary
+// directory.
+func writeMappedTestDB(tb testing.TB, db *ASCIIDB) string {
+	file := filepath.Join(tb.TempDir(), "asciidb.mmap")
+	f, err := os.Create(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := WriteMappedDB(db, f); err != nil {
+		tb.Fatal(err)
+	}
+	return file
+}
+
+func TestMappedDB(t *testing.T) {
+	sr, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branded, err := ReadFDCJSON("testdata/fdc/branded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr.putFood(&Food{NDBID: "U1", LongDescription: "Granola", Source: "overlay.json"})
+
+	for _, db := range []*ASCIIDB{sr, branded} {
+		mapped, err := OpenMappedDB(writeMappedTestDB(t, db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(db.FoodGroups, mapped.FoodGroups) {
+			t.Errorf("Expected food groups %v, got %v", db.FoodGroups, mapped.FoodGroups)
+		}
+		if !reflect.DeepEqual(db.Nutrients, mapped.Nutrients) {
+			t.Errorf("Expected nutrients %v, got %v", db.Nutrients, mapped.Nutrients)
+		}
+		if !reflect.DeepEqual(db.FoodIDs(), mapped.FoodIDs()) || mapped.Len() != len(db.Foods) {
+			t.Errorf("Expected foods %v, got %v", db.FoodIDs(), mapped.FoodIDs())
+		}
+		for id, expected := range db.Foods {
+			food, ok := mapped.Food(id)
+			if !ok {
+				t.Errorf("Expected food %s", id)
+				continue
+			}
+			if !reflect.DeepEqual(expected, food) {
+				t.Errorf("Expected %+v, got %+v", expected, food)
+			}
+			// Each call decodes a new Food, so changing one does not change the next.
+			food.LongDescription = "changed"
+			if again, _ := mapped.Food(id); !reflect.DeepEqual(expected, again) {
+				t.Errorf("Expected %+v again, got %+v", expected, again)
+			}
+		}
+		if _, ok := mapped.Food("00000"); ok {
+			t.Errorf("Expected no food 00000")
+		}
+
+		if err := mapped.Close(); err != nil {
+			t.Error(err)
+		}
+		if _, ok := mapped.Food(db.FoodIDs()[0]); ok {
+			t.Errorf("Expected no foods after Close")
+		}
+		if n, ids := mapped.Len(), mapped.FoodIDs(); n != 0 || len(ids) != 0 {
+			t.Errorf("Expected no foods after Close, got %d: %v", n, ids)
+		}
+	}
+}
+
+func TestMappedDBSearch(t *testing.T) {
+	sr, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branded, err := ReadFDCJSON("testdata/fdc/branded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, db := range []*ASCIIDB{sr, branded} {
+		mapped, err := OpenMappedDB(writeMappedTestDB(t, db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer mapped.Close()
+		if results := mapped.Search("butter"); len(results) != 0 {
+			t.Errorf("Expected no results without a search index, got %v", results)
+		}
+		if err := mapped.OpenSearchIndex(writeSearchIndexFile(t, db)); err != nil {
+			t.Fatal(err)
+		}
+
+		for term := range allPostings(db) {
+			if expected, actual := db.FindFood(term), mapped.FindFood(term); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("FindFood(%q): expected %v, got %v", term, expected, actual)
+			}
+		}
+		for _, query := range []string{"butter", "cheese salted", "oil", "cheddar cheese", "nothing"} {
+			if expected, actual := db.Search(query), mapped.Search(query); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Search(%q): expected %v, got %v", query, expected, actual)
+			}
+		}
+		for _, food := range db.Foods {
+			if food.Branded == nil {
+				continue
+			}
+			if actual, ok := mapped.FindFoodByGTIN(food.Branded.GTIN); !ok || actual.NDBID != food.NDBID {
+				t.Errorf("FindFoodByGTIN(%q): expected %s, got %v", food.Branded.GTIN, food.NDBID, actual)
+			}
+		}
+		if food, ok := mapped.FindFoodByGTIN("99999999999999"); ok {
+			t.Errorf("Expected no food for an unknown barcode, got %v", food)
+		}
+	}
+
+	// The search index must be for the same foods.
+	mapped, err := OpenMappedDB(writeMappedTestDB(t, branded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	err = mapped.OpenSearchIndex(writeSearchIndexFile(t, sr))
+	if err == nil || !strings.Contains(err.Error(), "not for this database") {
+		t.Errorf("Expected an error for the index of another database, got %v", err)
+	}
+}
+
+func TestMappedDBConcurrentClose(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := OpenMappedDB(writeMappedTestDB(t, db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run with -race: the accessors must not read the file while Close unmaps it.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for _, id := range mapped.FoodIDs() {
+					mapped.Food(id)
+				}
+				mapped.FindFoodByGTIN("00012345678905")
+				mapped.Len()
+			}
+		}()
+	}
+	if err := mapped.Close(); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+}
+
+func TestMappedDBCorrupt(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := writeMappedTestDB(t, db)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A truncated file is an error.
+	corrupt := filepath.Join(t.TempDir(), "corrupt.mmap")
+	for i := 0; i < len(data); i++ {
+		if err := os.WriteFile(corrupt, data[:i], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if mapped, err := OpenMappedDB(corrupt); err == nil {
+			mapped.Close()
+			t.Errorf("Expected an error for the file cut to %d bytes", i)
+		}
+	}
+
+	// A corrupt byte must not crash the server.
+	for i := kMappedDBHeaderSize; i < len(data); i++ {
+		changed := append([]byte(nil), data...)
+		changed[i] ^= 0xff
+		if err := os.WriteFile(corrupt, changed, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mapped, err := OpenMappedDB(corrupt)
+		if err != nil {
+			continue
+		}
+		for _, id := range db.FoodIDs() {
+			mapped.Food(id)
+		}
+		mapped.Close()
+	}
+}
@@ -17,6 +17,10 @@
 
 package ndb
 
+import (
+	"strings"
+)
+
 // A Nutrient represents either a macro or micronutrient that is measured
 // for a food item in the database.
 type Nutrient struct {
@@ -28,6 +32,10 @@ type Nutrient struct {
 	Description string
 	// The order used in official reports.
 	SortOrder int
+	// The INFOODS tagname, e.g. PROCNT.
+	Tagname string `json:",omitempty"`
+	// The number of decimal places the values are rounded to.
+	Decimals int `json:",omitempty"`
 }
 
 type FoodGroup struct {
@@ -42,6 +50,8 @@ type Food struct {
 	// 5-digit identification number for the food. Key.
 	// String to preserve leading zeros (apparently).
 	NDBID string
+	// FoodData Central identifier, if the food was loaded from an FDC download.
+	FDCID int
 	// 4-digit code indicating food group to which this food belongs.
 	FoodGroup int
 	// Long (200 chars) description.
@@ -54,14 +64,60 @@ type Food struct {
 	ScientificName string
 	// If applicable, the manufacturer of the food.
 	Manufacturer string
+	// The ingredient statement, for manufactured foods.
+	Ingredients string `json:",omitempty"`
 	// Description of the inedible parts of the food.
 	RefuseDescription string
 	// The percentage of the food that is refuse.
 	Refuse int
+	// Whether the food is in the Food and Nutrient Database for Dietary Studies.
+	Survey bool `json:",omitempty"`
+	// The factors for calculating protein from nitrogen, and calories from
+	// protein, fat and carbohydrate, or nil if they are not known.
+	NitrogenFactor     *float32 `json:",omitempty"`
+	ProteinFactor      *float32 `json:",omitempty"`
+	FatFactor          *float32 `json:",omitempty"`
+	CarbohydrateFactor *float32 `json:",omitempty"`
 	// Nutrients of the food.
 	Nutrients []FoodNutrient
 	// The common household weights/units.
 	Weights []Weight
+	// Label information, if this is a manufacturer's branded product.
+	Branded *BrandedFood `json:",omitempty"`
+	// Comments on the food, its weights and its nutrient values.
+	Footnotes []Footnote `json:",omitempty"`
+	// The overlay that added the food, or empty if it is from the USDA data.
+	Source string `json:",omitempty"`
+}
+
+// FindWeight returns the first of the food's Weights whose description contains
+// |measure|, ignoring case, or nil if none does.
+func (f *Food) FindWeight(measure string) *Weight {
+	measure = strings.ToLower(measure)
+	for i := range f.Weights {
+		if strings.Contains(strings.ToLower(f.Weights[i].Description), measure) {
+			return &f.Weights[i]
+		}
+	}
+	return nil
+}
+
+// A BrandedFood is the label information for a manufacturer's product, as
+// reported to the USDA Branded Food Products Database.
+type BrandedFood struct {
+	// The UPC/GTIN barcode, as printed on the package.
+	GTIN string
+	// The company that owns the brand.
+	BrandOwner string
+	// The brand name, if different from the owner.
+	BrandName string
+	// The label serving size, in ServingSizeUnit (typically g or ml).
+	ServingSize     float32
+	ServingSizeUnit string
+	// The household description of the serving, e.g. "1 cup".
+	HouseholdServing string
+	// The product category assigned by the data provider.
+	Category string
 }
 
 // A FoodNutrieint is a measured nutrient value for a food item.
@@ -72,6 +128,62 @@ type FoodNutrient struct {
 	Value float32
 	// Number of data points used to calculate the value.
 	DataPoints int
+	// The overlay that added or corrected the value, or empty if it is from
+	// the USDA data.
+	Source string `json:",omitempty"`
+	// The statistics of the value from an SR release, or nil if there are none.
+	Stats *NutrientStats `json:",omitempty"`
+}
+
+// NutrientStats are the statistics and sources of an SR nutrient value. The
+// numbers are nil if they are not known.
+type NutrientStats struct {
+	// Standard error of the mean.
+	StdError *float32 `json:",omitempty"`
+	// Code indicating the type of data.
+	SourceCode string `json:",omitempty"`
+	// Code indicating how the value was calculated.
+	DerivationCode string `json:",omitempty"`
+	// The NDBID of the food the value was imputed from, if any.
+	RefNDBID string `json:",omitempty"`
+	// Whether the nutrient was added for fortification or enrichment.
+	Added bool `json:",omitempty"`
+	// The number of analytical studies.
+	Studies *int `json:",omitempty"`
+	// The minimum and maximum values.
+	Min *float32 `json:",omitempty"`
+	Max *float32 `json:",omitempty"`
+	// Degrees of freedom.
+	DegreesOfFreedom *int `json:",omitempty"`
+	// The lower and upper 95% error bounds.
+	LowerErrorBound *float32 `json:",omitempty"`
+	UpperErrorBound *float32 `json:",omitempty"`
+	// Statistical comments.
+	Comments string `json:",omitempty"`
+	// The month the value was added or last modified, as MM/YYYY.
+	Modified string `json:",omitempty"`
+	// Confidence code indicating the quality of the data.
+	ConfidenceCode string `json:",omitempty"`
+}
+
+// The types of Footnote.
+const (
+	FootnoteDescription = "D" // A comment on the food description.
+	FootnoteMeasure     = "M" // A comment on the Weight with the same Sequence.
+	FootnoteNutrient    = "N" // A comment on the value of a nutrient.
+)
+
+// A Footnote is a comment on a food, one of its weights or one of its nutrient
+// values.
+type Footnote struct {
+	// Sequence number. For a FootnoteMeasure, the Sequence of the Weight.
+	Number int
+	// One of FootnoteDescription, FootnoteMeasure or FootnoteNutrient.
+	Type string
+	// For a FootnoteNutrient, the NutrientID it describes. Otherwise it is 0.
+	NutrientID int `json:",omitempty"`
+	// The text of the footnote.
+	Text string
 }
 
 // A Weight is a common measure of a food item that contains a factor for
@@ -90,4 +202,8 @@ type Weight struct {
 	Description string
 	// The weight in grams for this unit.
 	WeightG float32
+	// The number of data points and the standard deviation of WeightG, or nil
+	// if they are not known.
+	DataPoints *int     `json:",omitempty"`
+	StdDev     *float32 `json:",omitempty"`
 }
@@ -0,0 +1,278 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// The NDBIDs of custom foods start with this prefix. USDA NDBIDs are numeric,
+// so the two can never collide.
+const CustomNDBIDPrefix = "U"
+
+// An Overlay adds custom foods and corrected nutrient values to a database,
+// without changing the USDA files.
+type Overlay struct {
+	// New foods, whose NDBIDs must start with CustomNDBIDPrefix.
+	Foods []*Food
+	// Corrected nutrient values for foods that are already in the database.
+	Overrides []NutrientOverride
+}
+
+// A NutrientOverride replaces nutrient values of an existing food. Nutrients
+// that the food does not have are added.
+type NutrientOverride struct {
+	NDBID     string
+	Nutrients []FoodNutrient
+}
+
+// IsCustomNDBID returns true if |id| is in the namespace of custom foods.
+func IsCustomNDBID(id string) bool {
+	return strings.HasPrefix(id, CustomNDBIDPrefix)
+}
+
+// ReadOverlay reads an Overlay from |file|, which is either a JSON file of an
+// Overlay, or a directory of files in the USDA format. In a directory, the
+// FOOD_DES.txt, WEIGHT.txt and INGREDIENTS.txt files describe custom foods, and
+// the NUT_DATA.txt lines for custom foods are their nutrients; other NUT_DATA.txt
+// lines are overrides. All of the files are optional.
+func ReadOverlay(file string) (*Overlay, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("ReadOverlay: %v", err)
+	}
+	if fi.IsDir() {
+		return readOverlayDir(file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("ReadOverlay: %v", err)
+	}
+	defer f.Close()
+
+	overlay := &Overlay{}
+	if err := json.NewDecoder(f).Decode(overlay); err != nil {
+		return nil, fmt.Errorf("ReadOverlay(%s): %v", file, err)
+	}
+	return overlay, nil
+}
+
+func readOverlayDir(base string) (*Overlay, error) {
+	overlay := &Overlay{}
+	foods := make(map[string]*Food)
+	overrides := make(map[string]int)
+
+	// readFile reads the file |name| if it exists.
+	readFile := func(name string, processor LineProcessor) error {
+		file := path.Join(base, name)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return nil
+		}
+		return ReadFile(file, &ReadOptions{Ordered: true}, processor)
+	}
+	findFood := func(id string) (*Food, error) {
+		food, ok := foods[id]
+		if !ok {
+			return nil, fieldError(1, fmt.Errorf("readOverlay: Could not find custom food %s", id))
+		}
+		return food, nil
+	}
+
+	err := readFile("FOOD_DES.txt", func(line string) error {
+		var r foodRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		food := r.food()
+		foods[food.NDBID] = food
+		overlay.Foods = append(overlay.Foods, food)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readFile("NUT_DATA.txt", func(line string) error {
+		var r foodNutrientRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		n := r.nutrient()
+		if food, ok := foods[r.NDBID]; ok {
+			food.Nutrients = append(food.Nutrients, n)
+			return nil
+		}
+		i, ok := overrides[r.NDBID]
+		if !ok {
+			i = len(overlay.Overrides)
+			overrides[r.NDBID] = i
+			overlay.Overrides = append(overlay.Overrides, NutrientOverride{NDBID: r.NDBID})
+		}
+		overlay.Overrides[i].Nutrients = append(overlay.Overrides[i].Nutrients, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readFile("WEIGHT.txt", func(line string) error {
+		var r weightRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		food, err := findFood(r.NDBID)
+		if err != nil {
+			return err
+		}
+		food.Weights = append(food.Weights, r.weight())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readFile("INGREDIENTS.txt", func(line string) error {
+		var r ingredientsRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			return err
+		}
+		food, err := findFood(r.NDBID)
+		if err != nil {
+			return err
+		}
+		food.Ingredients = r.Ingredients
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return overlay, nil
+}
+
+// LoadOverlay reads the overlay at |file| and applies it to the database, with
+// the file as the Source.
+func (db *ASCIIDB) LoadOverlay(file string) error {
+	overlay, err := ReadOverlay(file)
+	if err != nil {
+		return err
+	}
+	return db.ApplyOverlay(overlay, file)
+}
+
+// ApplyOverlay adds the custom foods of |overlay| to the database and search
+// index, and applies its nutrient overrides. The foods and values that it adds
+// are marked with the |source|. The overlay is checked before any change is
+// made, so on error the database is unchanged. Overridden foods are replaced by
+// copies, so that the Foods already read from the database do not change.
+func (db *ASCIIDB) ApplyOverlay(overlay *Overlay, source string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.checkOverlay(overlay); err != nil {
+		return fmt.Errorf("ApplyOverlay(%s): %v", source, err)
+	}
+
+	for _, food := range overlay.Foods {
+		food.Source = source
+		for i := range food.Nutrients {
+			food.Nutrients[i].Source = source
+		}
+		db.Foods[food.NDBID] = food
+		db.reindexFood(nil, food)
+	}
+
+	for _, o := range overlay.Overrides {
+		copied := *db.Foods[o.NDBID]
+		food := &copied
+		food.Nutrients = append([]FoodNutrient(nil), food.Nutrients...)
+		db.Foods[o.NDBID] = food
+		for _, n := range o.Nutrients {
+			n.Source = source
+			replaced := false
+			for i := range food.Nutrients {
+				if food.Nutrients[i].NutrientID == n.NutrientID {
+					food.Nutrients[i] = n
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				food.Nutrients = append(food.Nutrients, n)
+			}
+		}
+	}
+	return nil
+}
+
+func (db *ASCIIDB) checkOverlay(overlay *Overlay) error {
+	added := make(map[string]bool, len(overlay.Foods))
+	for _, food := range overlay.Foods {
+		if err := db.checkCustomFood(food); err != nil {
+			return err
+		}
+		if _, ok := db.Foods[food.NDBID]; ok || added[food.NDBID] {
+			return fmt.Errorf("Duplicate custom food %s", food.NDBID)
+		}
+		added[food.NDBID] = true
+	}
+
+	for _, o := range overlay.Overrides {
+		if _, ok := db.Foods[o.NDBID]; !ok {
+			return fmt.Errorf("Cannot override nutrients of unknown food %s", o.NDBID)
+		}
+		if err := db.checkNutrients(o.NDBID, o.Nutrients); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCustomFood checks that |food| is in the custom NDBID namespace and that
+// its nutrients are defined.
+func (db *ASCIIDB) checkCustomFood(food *Food) error {
+	if food == nil {
+		return fmt.Errorf("Empty custom food")
+	}
+	if !IsCustomNDBID(food.NDBID) {
+		return fmt.Errorf("Custom food NDBIDs must start with %q, got %q", CustomNDBIDPrefix, food.NDBID)
+	}
+	return db.checkNutrients(food.NDBID, food.Nutrients)
+}
+
+// checkNutrients checks that the |nutrients| of the food |id| are in db.Nutrients.
+func (db *ASCIIDB) checkNutrients(id string, nutrients []FoodNutrient) error {
+	for _, n := range nutrients {
+		found := false
+		for _, def := range db.Nutrients {
+			if def.NutrientID == n.NutrientID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Food %s has unknown nutrient %d", id, n.NutrientID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func containsID(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoadOverlayDir(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const kSource = "testdata/overlay"
+	if err := db.LoadOverlay(kSource); err != nil {
+		t.Fatal(err)
+	}
+
+	granola, ok := db.Foods["U0001"]
+	if !ok {
+		t.Fatal("Expected the custom food to be added")
+	}
+	f := func(v float32) *float32 { return &v }
+	i := func(v int) *int { return &v }
+	// The files are read as ReadDatabase reads them, so every column is kept.
+	expected := &Food{
+		NDBID:              "U0001",
+		FoodGroup:          1900,
+		LongDescription:    "Granola, house blend",
+		ShortDescription:   "GRANOLA,HOUSE",
+		Manufacturer:       "Test Kitchen",
+		Survey:             true,
+		NitrogenFactor:     f(6.25),
+		ProteinFactor:      f(4),
+		FatFactor:          f(9),
+		CarbohydrateFactor: f(4),
+		Nutrients: []FoodNutrient{{NutrientID: 208, Value: 471, DataPoints: 1, Source: kSource,
+			Stats: &NutrientStats{SourceCode: "4"}}},
+		Weights: []Weight{{Sequence: 1, Amount: 0.5, Description: "cup", WeightG: 61, DataPoints: i(2), StdDev: f(1.5)}},
+		Source:  kSource,
+	}
+	if !reflect.DeepEqual(expected, granola) {
+		t.Errorf("Expected %+v, got %+v", expected, granola)
+	}
+	if ids := db.FindFood("granola"); !containsID(ids, "U0001") {
+		t.Errorf("Expected the custom food to be indexed, got %v", ids)
+	}
+
+	butter := db.Foods["01001"]
+	if butter.Source != "" {
+		t.Errorf("Expected an overridden food to keep its USDA source, got %q", butter.Source)
+	}
+	// The corrected values lose the USDA statistics, and the others keep them.
+	expectedNutrients := []FoodNutrient{
+		{NutrientID: 203, Value: 0.9, DataPoints: 3, Source: kSource},
+		{NutrientID: 204, Value: 81.11, DataPoints: 580, Stats: butter.Nutrients[1].Stats},
+		{NutrientID: 208, Value: 717, DataPoints: 0, Stats: butter.Nutrients[2].Stats},
+		{NutrientID: 320, Value: 700, DataPoints: 1, Source: kSource},
+	}
+	if butter.Nutrients[1].Stats == nil || butter.Nutrients[2].Stats == nil {
+		t.Errorf("Expected the uncorrected values to keep their statistics")
+	}
+	if !reflect.DeepEqual(expectedNutrients, butter.Nutrients) {
+		t.Errorf("Expected nutrients %v, got %v", expectedNutrients, butter.Nutrients)
+	}
+}
+
+func TestLoadOverlayJSON(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.LoadOverlay("testdata/overlay.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	soup, ok := db.Foods["U0002"]
+	if !ok || soup.Source != "testdata/overlay.json" || len(soup.Weights) != 1 {
+		t.Errorf("Expected the custom food with its source, got %+v", soup)
+	}
+	if ids := db.FindFood("cafeteria"); !containsID(ids, "U0002") {
+		t.Errorf("Expected the custom food to be indexed, got %v", ids)
+	}
+
+	broccoli := db.Foods["11090"]
+	if n := broccoli.Nutrients[1]; n.NutrientID != 208 || n.Value != 35 || n.Source != "testdata/overlay.json" {
+		t.Errorf("Expected the overridden energy value, got %+v", n)
+	}
+}
+
+func TestApplyOverlayErrors(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlays := []*Overlay{
+		{Foods: []*Food{{NDBID: "99999"}}},
+		{Foods: []*Food{{NDBID: "U1"}, {NDBID: "U1"}}},
+		{Foods: []*Food{{NDBID: "U1", Nutrients: []FoodNutrient{{NutrientID: 999}}}}},
+		{Overrides: []NutrientOverride{{NDBID: "99999"}}},
+		{Overrides: []NutrientOverride{{NDBID: "01001", Nutrients: []FoodNutrient{{NutrientID: 999}}}}},
+	}
+	for i, overlay := range overlays {
+		if err := db.ApplyOverlay(overlay, "test"); err == nil {
+			t.Errorf("Overlay %d: expected an error", i)
+		}
+	}
+	if _, ok := db.Foods["U1"]; ok {
+		t.Errorf("Expected an invalid overlay to leave the database unchanged")
+	}
+}
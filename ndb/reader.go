@@ -0,0 +1,38 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+// A Reader is a read-only view of a database, which both an ASCIIDB and a
+// MappedDB provide, so that the servers can serve either. The food groups and
+// nutrients do not change, and the methods are safe for concurrent use.
+type Reader interface {
+	AllFoodGroups() []FoodGroup
+	AllNutrients() []Nutrient
+	// Food returns the Food with NDBID |id|, which must not be modified.
+	Food(id string) (*Food, bool)
+	// FoodIDs returns the NDBIDs of all the foods, sorted.
+	FoodIDs() []string
+	FindFood(name string) []string
+	FindFoodByGTIN(gtin string) (*Food, bool)
+	Search(query string) []SearchResult
+}
+
+var (
+	_ Reader = (*ASCIIDB)(nil)
+	_ Reader = (*MappedDB)(nil)
+)
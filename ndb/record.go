@@ -0,0 +1,346 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// UnmarshalRecord parses a line of an SR file into the struct pointed to by |v|.
+// The fields of a line are separated by carets, and text fields are quoted with
+// tildes, e.g. ~01001~^~0100~^~Butter, salted~.
+//
+// The struct fields to set are tagged with their 1-based column number:
+//
+//	type weightRecord struct {
+//		_           struct{}   `sr:"table=WEIGHT,fields=7"`
+//		NDBID       string     `sr:"col=1,width=5"`
+//		Sequence    int        `sr:"col=2"`
+//		Amount      float32    `sr:"col=3"`
+//		Description string     `sr:"col=4"`
+//		WeightG     float32    `sr:"col=5"`
+//		_           int        `sr:"col=6,nullable"`
+//		StdDev      *float32   `sr:"col=7"`
+//		Numbers     NumberText `sr:"numbers"`
+//	}
+//
+// String fields have their tildes removed. Int and float fields are parsed as
+// numbers, and are an error if empty unless they are "nullable", in which case
+// they are left as zero. Pointers to ints and floats are nil if the field is
+// empty, so that MarshalRecord can tell it apart from zero. Blank fields declare
+// columns that are not decoded, so that MarshalRecord can write them. The
+// optional tag on the blank struct{} field names the table for errors, and
+// gives the exact number of fields a line must have. Otherwise a line only needs
+// enough fields for the highest column. The optional NumberText field gets the
+// text of the numbers that MarshalRecord would write differently.
+//
+// Errors about a field are *ParseErrors with the Column set.
+func UnmarshalRecord(line string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalRecord: expected a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	layout, err := recordLayoutFor(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(line, "^")
+	if layout.fields > 0 && len(parts) != layout.fields {
+		return fmt.Errorf("Expected %d parts, got %d from a %s", layout.fields, len(parts), layout.table)
+	}
+	if len(parts) < layout.maxColumn {
+		return fmt.Errorf("Expected at least %d parts, got %d from a %s", layout.maxColumn, len(parts), layout.table)
+	}
+
+	var numbers NumberText
+	for _, col := range layout.columns {
+		if col.blank {
+			continue
+		}
+		s := trimString(parts[col.column-1])
+		f := rv.Field(col.index)
+		if err := col.set(f, s); err != nil {
+			return fieldError(col.column, fmt.Errorf("%s: %s: %v", layout.table, col.name, err))
+		}
+		if layout.numbers >= 0 && f.Kind() != reflect.String && col.format(f) != s {
+			if numbers == nil {
+				numbers = make(NumberText)
+			}
+			numbers[col.column] = s
+		}
+	}
+	if layout.numbers >= 0 {
+		rv.Field(layout.numbers).Set(reflect.ValueOf(numbers))
+	}
+	return nil
+}
+
+// NumberText is the text of the number fields of a line, by column, that
+// MarshalRecord would not write the same way, like "5.0" or ".5". The SR files
+// have no fixed precision, so a record keeps it to be written back as it was.
+type NumberText map[int]string
+
+// MarshalRecord formats the struct pointed to by |v|, which is tagged as for
+// UnmarshalRecord, as a line of an SR file without the line ending. Text fields
+// are quoted with tildes, and "type=text" fields are too even if they are
+// numbers. Ints with a "width" are zero-padded, and floats have as many digits
+// as they need, unless the NumberText field has the text of a number that still
+// has the field's value. Blank fields, columns without a field, and zero
+// "nullable" fields of "type=text" are written empty.
+func MarshalRecord(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("MarshalRecord: expected a struct, got %T", v)
+	}
+
+	layout, err := recordLayoutFor(rv.Type())
+	if err != nil {
+		return "", err
+	}
+
+	n := layout.fields
+	if n < layout.maxColumn {
+		n = layout.maxColumn
+	}
+	var numbers NumberText
+	if layout.numbers >= 0 {
+		numbers = rv.Field(layout.numbers).Interface().(NumberText)
+	}
+	parts := make([]string, n)
+	for _, col := range layout.columns {
+		var s string
+		if f := rv.Field(col.index); !col.blank && !(col.nullable && col.text && f.IsZero()) {
+			s = col.format(f)
+			if text, ok := numbers[col.column]; ok && col.sameNumber(f, text) {
+				s = text
+			}
+		}
+		if col.text {
+			if strings.ContainsAny(s, "~^\r\n") {
+				return "", fmt.Errorf("MarshalRecord: %s: %s: %q cannot contain ~, ^ or a line break", layout.table, col.name, s)
+			}
+			s = "~" + s + "~"
+		}
+		parts[col.column-1] = s
+	}
+	return strings.Join(parts, "^"), nil
+}
+
+// A recordLayout is the parsed sr tags of a record struct.
+type recordLayout struct {
+	table     string // The name of the table, for errors.
+	fields    int    // The exact number of fields in a line, or 0 if not checked.
+	maxColumn int
+	columns   []recordColumn
+	numbers   int // The index of the NumberText field, or -1.
+}
+
+type recordColumn struct {
+	index    int    // The index of the struct field.
+	name     string // The name of the struct field.
+	column   int    // The 1-based column in the line.
+	nullable bool
+	text     bool // Whether the column is quoted with tildes.
+	width    int  // The number of digits to zero-pad an int to.
+	blank    bool // Whether the column has no struct field to decode into.
+}
+
+// Caches the *recordLayout of each record type. The loaders decode lines
+// concurrently, so this is a sync.Map.
+var recordLayouts sync.Map
+
+func recordLayoutFor(t reflect.Type) (*recordLayout, error) {
+	if layout, ok := recordLayouts.Load(t); ok {
+		return layout.(*recordLayout), nil
+	}
+
+	layout := &recordLayout{table: t.Name(), numbers: -1}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("sr")
+		if !ok {
+			continue
+		}
+		opts, err := parseRecordTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("UnmarshalRecord: %s.%s: %v", t.Name(), f.Name, err)
+		}
+
+		if f.Name == "_" && f.Type.Kind() == reflect.Struct {
+			if table, ok := opts["table"]; ok {
+				layout.table = table
+			}
+			if fields, ok := opts["fields"]; ok {
+				if layout.fields, err = strconv.Atoi(fields); err != nil {
+					return nil, fmt.Errorf("UnmarshalRecord: %s: invalid fields %q", t.Name(), fields)
+				}
+			}
+			continue
+		}
+		if _, ok := opts["numbers"]; ok {
+			if f.Type != reflect.TypeOf(NumberText(nil)) {
+				return nil, fmt.Errorf("UnmarshalRecord: %s.%s: numbers must be a NumberText", t.Name(), f.Name)
+			}
+			layout.numbers = i
+			continue
+		}
+
+		column, err := strconv.Atoi(opts["col"])
+		if err != nil || column < 1 {
+			return nil, fmt.Errorf("UnmarshalRecord: %s.%s: invalid col %q", t.Name(), f.Name, opts["col"])
+		}
+		kind := f.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = f.Type.Elem().Kind()
+			if kind == reflect.String {
+				kind = reflect.Invalid
+			}
+		}
+		switch kind {
+		case reflect.String, reflect.Int, reflect.Float32, reflect.Float64:
+		default:
+			return nil, fmt.Errorf("UnmarshalRecord: %s.%s: unsupported type %s", t.Name(), f.Name, f.Type)
+		}
+
+		_, nullable := opts["nullable"]
+		col := recordColumn{
+			index:    i,
+			name:     f.Name,
+			column:   column,
+			nullable: nullable,
+			text:     f.Type.Kind() == reflect.String,
+			blank:    f.Name == "_",
+		}
+		switch opts["type"] {
+		case "":
+		case "text":
+			col.text = true
+		case "numeric":
+			col.text = false
+		default:
+			return nil, fmt.Errorf("UnmarshalRecord: %s.%s: invalid type %q", t.Name(), f.Name, opts["type"])
+		}
+		if width, ok := opts["width"]; ok {
+			if col.width, err = strconv.Atoi(width); err != nil {
+				return nil, fmt.Errorf("UnmarshalRecord: %s.%s: invalid width %q", t.Name(), f.Name, width)
+			}
+		}
+		layout.columns = append(layout.columns, col)
+		if column > layout.maxColumn {
+			layout.maxColumn = column
+		}
+	}
+
+	actual, _ := recordLayouts.LoadOrStore(t, layout)
+	return actual.(*recordLayout), nil
+}
+
+// parseRecordTag parses the comma-separated key=value pairs of an sr tag. Keys
+// without a value, like "nullable", map to the empty string.
+func parseRecordTag(tag string) (map[string]string, error) {
+	opts := make(map[string]string)
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid sr tag %q", tag)
+		}
+		opts[key] = value
+	}
+	return opts, nil
+}
+
+// format returns the text of the struct field |v|, without quotes.
+func (col recordColumn) format(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int:
+		return fmt.Sprintf("%0*d", col.width, v.Int())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return v.String()
+}
+
+// sameNumber returns whether |text| is the number in the struct field |v|.
+func (col recordColumn) sameNumber(v reflect.Value, text string) bool {
+	if v.Kind() == reflect.String {
+		return false
+	}
+	parsed := reflect.New(v.Type()).Elem()
+	return col.set(parsed, text) == nil && col.format(parsed) == col.format(v)
+}
+
+// set stores the field text |s| in the struct field |v|.
+func (col recordColumn) set(v reflect.Value, s string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := col.set(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if s == "" {
+		if col.nullable {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return fmt.Errorf("missing value")
+	}
+
+	switch v.Kind() {
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
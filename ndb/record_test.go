@@ -0,0 +1,156 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testRecord struct {
+	_      struct{} `sr:"table=TEST,fields=5"`
+	ID     string   `sr:"col=1"`
+	Count  int      `sr:"col=2"`
+	Value  float64  `sr:"col=3"`
+	Refuse int      `sr:"col=5,nullable"`
+	Other  string
+}
+
+func TestUnmarshalRecord(t *testing.T) {
+	var r testRecord
+	if err := UnmarshalRecord("~01001~^~0100~^81.11^~~^", &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "01001" || r.Count != 100 || r.Value != 81.11 || r.Refuse != 0 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+
+	if err := UnmarshalRecord("~01001~^~0100~^81.11^~~^39", &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Refuse != 39 {
+		t.Errorf("Expected Refuse 39, got %d", r.Refuse)
+	}
+}
+
+func TestUnmarshalRecordErrors(t *testing.T) {
+	expectations := []struct {
+		line   string
+		column int
+	}{
+		{"~01001~^~0100~^81.11^~~", 0},
+		{"~01001~^^81.11^~~^", 2},
+		{"~01001~^~0100~^abc^~~^", 3},
+		{"~01001~^~0100~^1^~~^1.5", 5},
+	}
+	for _, e := range expectations {
+		var r testRecord
+		err := UnmarshalRecord(e.line, &r)
+		if err == nil {
+			t.Errorf("%q: expected an error", e.line)
+			continue
+		}
+		var pe *ParseError
+		column := 0
+		if errors.As(err, &pe) {
+			column = pe.Column
+		}
+		if column != e.column {
+			t.Errorf("%q: expected an error in column %d, got %v", e.line, e.column, err)
+		}
+	}
+
+	var bad struct {
+		Values []int `sr:"col=1"`
+	}
+	if err := UnmarshalRecord("1", &bad); err == nil {
+		t.Errorf("Expected an error for an unsupported field type")
+	}
+	if err := UnmarshalRecord("1", testRecord{}); err == nil {
+		t.Errorf("Expected an error for a non-pointer")
+	}
+}
+
+func TestRecordPointerFields(t *testing.T) {
+	type statsRecord struct {
+		ID      string   `sr:"col=1"`
+		Studies *int     `sr:"col=2"`
+		Min     *float32 `sr:"col=3"`
+	}
+	for _, line := range []string{"~01001~^^1.5", "~01001~^0^", "~01001~^3^0"} {
+		var r statsRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			t.Fatal(err)
+		}
+		if actual, err := MarshalRecord(&r); err != nil || actual != line {
+			t.Errorf("Expected %q to be written back unchanged, got %q, %v", line, actual, err)
+		}
+	}
+
+	var r statsRecord
+	if err := UnmarshalRecord("~01001~^^1.5", &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Studies != nil || r.Min == nil || *r.Min != 1.5 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+	if err := UnmarshalRecord("~01001~^x^", &r); err == nil {
+		t.Errorf("Expected an error for an invalid number")
+	}
+}
+
+func TestRecordNumberText(t *testing.T) {
+	type weightRecord struct {
+		ID      string     `sr:"col=1"`
+		Amount  float32    `sr:"col=2"`
+		WeightG float32    `sr:"col=3"`
+		StdDev  *float32   `sr:"col=4"`
+		Numbers NumberText `sr:"numbers"`
+	}
+	for _, line := range []string{"~01001~^1^5.0^", "~11090~^.5^44^", "~11090~^1^91^6.164", "~01001~^1^0.0^0.10"} {
+		var r weightRecord
+		if err := UnmarshalRecord(line, &r); err != nil {
+			t.Fatal(err)
+		}
+		if actual, err := MarshalRecord(&r); err != nil || actual != line {
+			t.Errorf("Expected %q to be written back unchanged, got %q, %v", line, actual, err)
+		}
+	}
+
+	var r weightRecord
+	if err := UnmarshalRecord("~01001~^1^5.0^", &r); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (NumberText{3: "5.0"}); !reflect.DeepEqual(expected, r.Numbers) {
+		t.Errorf("Expected numbers %v, got %v", expected, r.Numbers)
+	}
+	// A changed value is written with its own digits.
+	r.WeightG = 5.5
+	if actual, err := MarshalRecord(&r); err != nil || actual != "~01001~^1^5.5^" {
+		t.Errorf("Expected the new weight, got %q, %v", actual, err)
+	}
+
+	var bad struct {
+		ID      string         `sr:"col=1"`
+		Numbers map[int]string `sr:"numbers"`
+	}
+	if err := UnmarshalRecord("1", &bad); err == nil {
+		t.Errorf("Expected an error for a numbers field that is not a NumberText")
+	}
+}
@@ -18,32 +18,204 @@
 package ndb
 
 import (
-	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/rsesek/usda-ndb/bst"
 )
 
+// The fields of a Food that are indexed for search, as numbered in the
+// bst.Positions of FindFoodPostings.
+const (
+	SearchFieldLongDescription = iota
+	SearchFieldShortDescription
+	SearchFieldCommonNames
+	SearchFieldManufacturer
+)
+
 func (db *ASCIIDB) addTermsForFood(food *Food) {
-	// Join all the descriptions together to create search terms.
-	search := strings.ToLower(fmt.Sprintf("%s %s %s %s",
-		food.LongDescription, food.ShortDescription, food.CommonNames, food.Manufacturer))
-	var last int
-	for i := 0; i < len(search); i++ {
-		c := search[i]
-		if c == ',' || c == ' ' || c == '&' || c == '/' || c == '!' || c == '-' || c == '.' {
-			part := search[last:i]
-			if len(part) > 2 {
-				db.searchTree.Insert(bst.Pair{part, food.NDBID})
+	for _, term := range foodTerms(food) {
+		db.searchTree.InsertAt(bst.Pair{Value: term.text, Token: food.NDBID}, term.pos)
+	}
+}
+
+// removeTermsForFood removes |food| from the search tree. The food must have the
+// same descriptions as when it was added.
+func (db *ASCIIDB) removeTermsForFood(food *Food) {
+	for _, term := range foodTerms(food) {
+		db.searchTree.RemoveToken(term.text, food.NDBID)
+	}
+}
+
+// A searchTerm is a word of a food's descriptions, and where it occurs.
+type searchTerm struct {
+	text string
+	pos  bst.Position
+}
+
+// foodTerms returns the search terms of |food|. A term can occur more than once.
+func foodTerms(food *Food) []searchTerm {
+	fields := []string{
+		SearchFieldLongDescription:  food.LongDescription,
+		SearchFieldShortDescription: food.ShortDescription,
+		SearchFieldCommonNames:      food.CommonNames,
+		SearchFieldManufacturer:     food.Manufacturer,
+	}
+	var terms []searchTerm
+	for field, text := range fields {
+		// The fields used to be joined by spaces, so the text after the last
+		// delimiter of every field but the last is a term.
+		search := strings.ToLower(text)
+		if field < len(fields)-1 {
+			search += " "
+		}
+		var last, offset int
+		for i := 0; i < len(search); i++ {
+			c := search[i]
+			if c == ',' || c == ' ' || c == '&' || c == '/' || c == '!' || c == '-' || c == '.' {
+				part := search[last:i]
+				if len(part) > 2 {
+					terms = append(terms, searchTerm{part, bst.Position{Field: field, Offset: offset}})
+				}
+				if len(part) > 0 {
+					offset++
+				}
+				last = i + 1
 			}
-			last = i + 1
 		}
 	}
+	return terms
+}
+
+// ReindexFood updates the search and barcode indexes for a change to a single
+// food, from |old| to |food|, instead of rebuilding them. Either may be nil, to
+// index a new food or to remove a deleted one. |old| must be the Food as it was
+// indexed.
+func (db *ASCIIDB) ReindexFood(old, food *Food) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.reindexFood(old, food)
+}
+
+func (db *ASCIIDB) reindexFood(old, food *Food) {
+	if old != nil {
+		db.removeTermsForFood(old)
+		db.removeGTINForFood(old)
+	}
+	if food != nil {
+		db.addTermsForFood(food)
+		db.addGTINForFood(food)
+	}
 }
 
 func (db *ASCIIDB) RebuildSearchIndex() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	db.searchTree = bst.NewTree()
+	db.gtinIndex = nil
 	for _, food := range db.Foods {
 		db.addTermsForFood(food)
+		db.addGTINForFood(food)
+	}
+}
+
+// A SearchResult is a food found by Search.
+type SearchResult struct {
+	NDBID        string
+	FoodGroup    int
+	Description  string
+	Manufacturer string `json:",omitempty"`
+	// The number of search terms that match the food.
+	Score int
+
+	// Tie-breakers for foods that match the same number of terms.
+	leading   int // The number of terms that start the long description.
+	frequency int // The number of times the terms occur.
+}
+
+type resultList []SearchResult
+
+func (l resultList) Len() int {
+	return len(l)
+}
+
+func (l resultList) Less(i, j int) bool {
+	if l[i].Score != l[j].Score {
+		return l[i].Score > l[j].Score
+	}
+	if l[i].leading != l[j].leading {
+		return l[i].leading > l[j].leading
+	}
+	if l[i].frequency != l[j].frequency {
+		return l[i].frequency > l[j].frequency
+	}
+	return l[i].NDBID < l[j].NDBID
+}
+
+func (l resultList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// Search finds the foods matching any of the space-separated terms of |query|,
+// best first. The result is empty, rather than nil, if nothing matches.
+func (db *ASCIIDB) Search(query string) []SearchResult {
+	return search(query, db.FindFoodPostings, db.Food)
+}
+
+// search runs |query| with |findPostings|, which gives the postings of a term,
+// and |food|, which looks up the foods it finds. Both must be safe to call
+// concurrently; ASCIIDB's are, even while foods are being edited.
+func search(query string, findPostings func(string) []bst.Posting, food func(string) (*Food, bool)) []SearchResult {
+	terms := strings.Split(strings.ToLower(query), " ")
+
+	// For each search term, start a new goroutine to search the index.
+	queries := make(chan []bst.Posting)
+	for _, term := range terms {
+		go func(term string) {
+			queries <- findPostings(term)
+		}(term)
+	}
+
+	// The score is the number of search terms that match the food. Ties go to
+	// foods named by the terms, e.g. "Butter, salted" for "butter", and then to
+	// foods that repeat them.
+	scores := make(map[string]*SearchResult)
+	for i := 0; i < len(terms); i++ {
+		for _, posting := range <-queries {
+			score, ok := scores[posting.Token]
+			if !ok {
+				score = &SearchResult{}
+				scores[posting.Token] = score
+			}
+			score.Score++
+			score.frequency += posting.Frequency
+			for _, pos := range posting.Positions {
+				if pos.Field == SearchFieldLongDescription && pos.Offset == 0 {
+					score.leading++
+					break
+				}
+			}
+		}
+	}
+
+	// Collect the results into a response list.
+	results := make(resultList, 0, len(scores))
+	for id, score := range scores {
+		// The food may have been deleted since the search.
+		f, ok := food(id)
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			NDBID:        f.NDBID,
+			FoodGroup:    f.FoodGroup,
+			Description:  f.LongDescription,
+			Manufacturer: f.Manufacturer,
+			Score:        score.Score,
+			leading:      score.leading,
+			frequency:    score.frequency,
+		})
 	}
+	sort.Sort(results)
+	return results
 }
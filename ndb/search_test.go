@@ -0,0 +1,223 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/bst"
+)
+
+func TestReindexFood(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	butter := db.Foods["01001"]
+	if ids := db.FindFood("butter"); !containsID(ids, "01001") {
+		t.Fatalf("Expected butter to be indexed, got %v", ids)
+	}
+
+	margarine := *butter
+	margarine.LongDescription = "Margarine, regular, salted"
+	margarine.ShortDescription = "MARGARINE,REG,SALTED"
+	margarine.Branded = &BrandedFood{GTIN: "012345678905"}
+	db.Foods["01001"] = &margarine
+	db.ReindexFood(butter, &margarine)
+
+	if ids := db.FindFood("butter"); containsID(ids, "01001") {
+		t.Errorf("Expected the old terms to be removed, got %v", ids)
+	}
+	if ids := db.FindFood("margarine"); !containsID(ids, "01001") {
+		t.Errorf("Expected the new terms to be indexed, got %v", ids)
+	}
+	if ids := db.FindFood("salted"); !containsID(ids, "01001") {
+		t.Errorf("Expected a term shared by both descriptions to be kept, got %v", ids)
+	}
+	if food, ok := db.FindFoodByGTIN("12345678905"); !ok || food != &margarine {
+		t.Errorf("Expected the barcode to be indexed, got %v", food)
+	}
+
+	delete(db.Foods, "01001")
+	db.ReindexFood(&margarine, nil)
+	if ids := db.FindFood("margarine"); len(ids) != 0 {
+		t.Errorf("Expected the deleted food to be removed, got %v", ids)
+	}
+	if _, ok := db.FindFoodByGTIN("12345678905"); ok {
+		t.Errorf("Expected the barcode to be removed")
+	}
+}
+
+func TestFindFoodPostings(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "Butter, salted" and "BUTTER,WITH SALT" both have "butter".
+	if ids := db.FindFood("butter"); !reflect.DeepEqual(ids, []string{"01001"}) {
+		t.Errorf("Expected butter once, got %v", ids)
+	}
+	postings := db.FindFoodPostings("butter")
+	expected := []bst.Posting{{
+		Token:     "01001",
+		Frequency: 2,
+		Positions: []bst.Position{
+			{Field: SearchFieldLongDescription, Offset: 0},
+			{Field: SearchFieldShortDescription, Offset: 0},
+		},
+	}}
+	if !reflect.DeepEqual(expected, postings) {
+		t.Errorf("Expected %+v, got %+v", expected, postings)
+	}
+
+	food := &Food{
+		NDBID:           "U1",
+		LongDescription: "Soup, chicken and chicken noodle",
+		CommonNames:     "chicken soup",
+	}
+	db.putFood(food)
+	postings = db.FindFoodPostings("chicken")
+	expected = []bst.Posting{{
+		Token:     "U1",
+		Frequency: 3,
+		Positions: []bst.Position{
+			{Field: SearchFieldLongDescription, Offset: 1},
+			{Field: SearchFieldLongDescription, Offset: 3},
+			{Field: SearchFieldCommonNames, Offset: 0},
+		},
+	}}
+	if !reflect.DeepEqual(expected, postings) {
+		t.Errorf("Expected %+v, got %+v", expected, postings)
+	}
+
+	db.deleteFood("U1")
+	if postings := db.FindFoodPostings("chicken"); postings != nil {
+		t.Errorf("Expected every occurrence to be removed, got %+v", postings)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, food := range []*Food{
+		{NDBID: "U1", LongDescription: "Pie, chicken, frozen"},
+		{NDBID: "U2", LongDescription: "Soup, chicken and chicken noodle, canned"},
+		{NDBID: "U3", LongDescription: "Chicken, broilers, roasted"},
+		{NDBID: "U4", LongDescription: "Pie, apple, frozen"},
+	} {
+		db.putFood(food)
+	}
+
+	ids := func(results []SearchResult) []string {
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.NDBID)
+		}
+		return ids
+	}
+
+	// Named by the term first, then repeating it.
+	if expected, actual := []string{"U3", "U2", "U1"}, ids(db.Search("chicken")); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	// Matching more of the terms first, then by NDBID.
+	results := db.Search("Chicken Pie")
+	if expected, actual := []string{"U1", "U3", "U4", "U2"}, ids(results); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	if results[0].Score != 2 || results[0].Description != "Pie, chicken, frozen" {
+		t.Errorf("Unexpected first result %+v", results[0])
+	}
+
+	if results := db.Search("zzz"); results == nil || len(results) != 0 {
+		t.Errorf("Expected empty results, got %#v", results)
+	}
+}
+
+// Searches while foods are edited and the index rebuilt. This is for the race
+// detector: go test -race.
+func TestConcurrentSearchAndReindex(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var readers, started sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		started.Add(1)
+		go func() {
+			defer readers.Done()
+			started.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, id := range db.FindFood("pastry") {
+					if food, ok := db.Food(id); ok && food.LongDescription == "" {
+						t.Errorf("Food %s has no description", id)
+					}
+				}
+				db.FindFoodByGTIN("012345678905")
+				for _, id := range db.FoodIDs() {
+					if food, ok := db.Food(id); ok {
+						_ = len(food.Nutrients)
+					}
+				}
+			}
+		}()
+	}
+
+	started.Wait()
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("U%d", i%10)
+		food := &Food{
+			NDBID:           id,
+			LongDescription: fmt.Sprintf("Pastry, test %d", i),
+			Branded:         &BrandedFood{GTIN: "012345678905"},
+		}
+		switch i % 4 {
+		case 0, 1:
+			db.putFood(food)
+		case 2:
+			db.deleteFood(id)
+		case 3:
+			err := db.ApplyOverlay(&Overlay{
+				Overrides: []NutrientOverride{{NDBID: "01001", Nutrients: []FoodNutrient{{NutrientID: 203, Value: float32(i)}}}},
+			}, "test")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		if i%500 == 0 {
+			db.RebuildSearchIndex()
+		}
+	}
+	close(done)
+	readers.Wait()
+}
@@ -0,0 +1,401 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/rsesek/usda-ndb/bst"
+)
+
+// A search index file starts with this, and the version of the format.
+const kSearchIndexMagic = "NDBSRCH2"
+
+// The header is the magic bytes and then the offsets of the token table, the
+// term table, the strings and the postings sections, as little-endian uint64s.
+const kSearchIndexHeaderSize = len(kSearchIndexMagic) + 4*8
+
+// Each entry of the token table is the offset of an NDBID within the strings
+// section and its length, as little-endian uint32s.
+const kSearchIndexTokenSize = 8
+
+// Each entry of the term table is the offset of a term within the strings
+// section and its length, as uint32s, and the offset of its postings within the
+// postings section as a uint64.
+const kSearchIndexTermSize = 16
+
+// WriteSearchIndex writes the search index of the database to |w|, so that
+// ReadSearchIndex can restore it instead of rebuilding it from the Foods, or
+// OpenSearchIndex can look terms up in it without reading it.
+//
+// After the header come:
+//
+//	The token table: an entry for each NDBID, sorted.
+//	The term table: an entry for each term, sorted.
+//	The strings section: the NDBIDs and terms that the tables point to.
+//	The postings section: for each term, the number of postings and each
+//	    posting: the index of its NDBID in the token table, its frequency, the
+//	    number of positions and each position's field and offset, all as
+//	    unsigned varints.
+//
+// The tables have fixed-size entries, so that a reader can binary-search them in
+// place and decode only the postings of the terms it looks up.
+func (db *ASCIIDB) WriteSearchIndex(w io.Writer) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tokenSet := make(map[string]int)
+	for _, postings := range db.searchTree.AllPostings() {
+		for _, p := range postings {
+			tokenSet[p.Token] = 0
+		}
+	}
+	tokens := make([]string, 0, len(tokenSet))
+	for token := range tokenSet {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	var strs bytes.Buffer
+	tokenTable := make([]byte, len(tokens)*kSearchIndexTokenSize)
+	for i, token := range tokens {
+		tokenSet[token] = i
+		entry := tokenTable[i*kSearchIndexTokenSize:]
+		binary.LittleEndian.PutUint32(entry, uint32(strs.Len()))
+		binary.LittleEndian.PutUint32(entry[4:], uint32(len(token)))
+		strs.WriteString(token)
+	}
+
+	var termTable []byte
+	var postingsBuf bytes.Buffer
+	for term, postings := range db.searchTree.AllPostings() {
+		var entry [kSearchIndexTermSize]byte
+		binary.LittleEndian.PutUint32(entry[:], uint32(strs.Len()))
+		binary.LittleEndian.PutUint32(entry[4:], uint32(len(term)))
+		binary.LittleEndian.PutUint64(entry[8:], uint64(postingsBuf.Len()))
+		termTable = append(termTable, entry[:]...)
+		strs.WriteString(term)
+
+		putUvarint(&postingsBuf, len(postings))
+		for _, p := range postings {
+			putUvarint(&postingsBuf, tokenSet[p.Token])
+			putUvarint(&postingsBuf, p.Frequency)
+			putUvarint(&postingsBuf, len(p.Positions))
+			for _, pos := range p.Positions {
+				putUvarint(&postingsBuf, pos.Field)
+				putUvarint(&postingsBuf, pos.Offset)
+			}
+		}
+	}
+	if strs.Len() > math.MaxUint32 {
+		return fmt.Errorf("WriteSearchIndex: %d bytes of terms is too many", strs.Len())
+	}
+
+	tokensOffset := kSearchIndexHeaderSize
+	termsOffset := tokensOffset + len(tokenTable)
+	stringsOffset := termsOffset + len(termTable)
+	postingsOffset := stringsOffset + strs.Len()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(kSearchIndexMagic)
+	for _, v := range []int{tokensOffset, termsOffset, stringsOffset, postingsOffset} {
+		binary.Write(bw, binary.LittleEndian, uint64(v))
+	}
+	bw.Write(tokenTable)
+	bw.Write(termTable)
+	strs.WriteTo(bw)
+	postingsBuf.WriteTo(bw)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("WriteSearchIndex: %v", err)
+	}
+	return nil
+}
+
+// ReadSearchIndex replaces the search index of the database with one written by
+// WriteSearchIndex, and rebuilds the barcode index. This is for a database that
+// was decoded without its indexes, e.g. from a gob, and is much faster than
+// RebuildSearchIndex. The index must have been written from the same foods.
+func (db *ASCIIDB) ReadSearchIndex(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("ReadSearchIndex: %v", err)
+	}
+	ix := &SearchIndex{data: data}
+	if err := ix.readSections(); err != nil {
+		return fmt.Errorf("ReadSearchIndex: %v", err)
+	}
+	tree, tokens, err := ix.tree()
+	if err != nil {
+		return fmt.Errorf("ReadSearchIndex: %v", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, token := range tokens {
+		if _, ok := db.Foods[token]; !ok {
+			return fmt.Errorf("ReadSearchIndex: the index is not for this database: no food %s", token)
+		}
+	}
+	db.searchTree = tree
+	db.gtinIndex = nil
+	for _, food := range db.Foods {
+		db.addGTINForFood(food)
+	}
+	return nil
+}
+
+// A SearchIndex is a search index file written by WriteSearchIndex, which is
+// memory-mapped rather than read, like a MappedDB. The postings of a term are
+// decoded each time it is looked up, so opening the index is fast and it uses
+// little memory however large it is. It is safe for concurrent use.
+type SearchIndex struct {
+	file string
+
+	// Guards the sections of the file, which Close unmaps.
+	mu       sync.RWMutex
+	data     []byte // The mapped file.
+	tokens   []byte // The token table within |data|.
+	terms    []byte // The term table within |data|.
+	strings  []byte // The strings section within |data|.
+	postings []byte // The postings section within |data|.
+}
+
+// OpenSearchIndex maps the search index |file|, which must not be changed while
+// it is open. Close the SearchIndex to unmap it.
+func OpenSearchIndex(file string) (*SearchIndex, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("OpenSearchIndex: %v", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("OpenSearchIndex: %v", err)
+	}
+	if fi.Size() < int64(kSearchIndexHeaderSize) || fi.Size() > math.MaxInt {
+		return nil, fmt.Errorf("OpenSearchIndex(%s): not a search index", file)
+	}
+	data, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("OpenSearchIndex(%s): %v", file, err)
+	}
+
+	ix := &SearchIndex{file: file, data: data}
+	if err := ix.readSections(); err != nil {
+		unmapFile(data)
+		return nil, fmt.Errorf("OpenSearchIndex(%s): %v", file, err)
+	}
+	return ix, nil
+}
+
+func (ix *SearchIndex) readSections() error {
+	if len(ix.data) < len(kSearchIndexMagic) || string(ix.data[:len(kSearchIndexMagic)]) != kSearchIndexMagic {
+		return fmt.Errorf("not a search index, or an unsupported version: %q", ix.data[:min(len(ix.data), len(kSearchIndexMagic))])
+	}
+	if len(ix.data) < kSearchIndexHeaderSize {
+		return fmt.Errorf("the header is cut off")
+	}
+	var header [4]uint64
+	for i := range header {
+		header[i] = binary.LittleEndian.Uint64(ix.data[len(kSearchIndexMagic)+i*8:])
+		if header[i] > uint64(len(ix.data)) {
+			return fmt.Errorf("header field %d is past the end of the file", i)
+		}
+	}
+	tokensOffset, termsOffset, stringsOffset, postingsOffset := int(header[0]), int(header[1]), int(header[2]), int(header[3])
+	if tokensOffset < kSearchIndexHeaderSize || termsOffset < tokensOffset || stringsOffset < termsOffset || postingsOffset < stringsOffset {
+		return fmt.Errorf("the sections are out of order")
+	}
+	if (termsOffset-tokensOffset)%kSearchIndexTokenSize != 0 || (stringsOffset-termsOffset)%kSearchIndexTermSize != 0 {
+		return fmt.Errorf("the tables have partial entries")
+	}
+	ix.tokens = ix.data[tokensOffset:termsOffset]
+	ix.terms = ix.data[termsOffset:stringsOffset]
+	ix.strings = ix.data[stringsOffset:postingsOffset]
+	ix.postings = ix.data[postingsOffset:]
+
+	// Check the tables, so that looking up a term cannot go out of bounds.
+	for i := 0; i < ix.numTokens(); i++ {
+		if _, ok := ix.entryString(ix.tokens[i*kSearchIndexTokenSize:]); !ok {
+			return fmt.Errorf("token %d is past the end of the strings", i)
+		}
+	}
+	var last []byte
+	for i := 0; i < ix.numTerms(); i++ {
+		term, ok := ix.entryString(ix.terms[i*kSearchIndexTermSize:])
+		if !ok {
+			return fmt.Errorf("term %d is past the end of the strings", i)
+		}
+		if i > 0 && bytes.Compare(term, last) <= 0 {
+			return fmt.Errorf("term %q is out of order", term)
+		}
+		last = term
+		if binary.LittleEndian.Uint64(ix.terms[i*kSearchIndexTermSize+8:]) > uint64(len(ix.postings)) {
+			return fmt.Errorf("the postings for %q are past the end of the file", term)
+		}
+	}
+	return nil
+}
+
+func (ix *SearchIndex) numTokens() int {
+	return len(ix.tokens) / kSearchIndexTokenSize
+}
+
+func (ix *SearchIndex) numTerms() int {
+	return len(ix.terms) / kSearchIndexTermSize
+}
+
+// entryString returns the string that the table entry |e| points to, and whether
+// it is within the strings section.
+func (ix *SearchIndex) entryString(e []byte) ([]byte, bool) {
+	offset, n := int(binary.LittleEndian.Uint32(e)), int(binary.LittleEndian.Uint32(e[4:]))
+	if offset > len(ix.strings) || n > len(ix.strings)-offset {
+		return nil, false
+	}
+	return ix.strings[offset : offset+n], true
+}
+
+func (ix *SearchIndex) token(i int) string {
+	s, _ := ix.entryString(ix.tokens[i*kSearchIndexTokenSize:])
+	return string(s)
+}
+
+func (ix *SearchIndex) term(i int) []byte {
+	s, _ := ix.entryString(ix.terms[i*kSearchIndexTermSize:])
+	return s
+}
+
+// postingsAt decodes the postings of the |i|th term. The tokens are taken from
+// |tokens| if it is not nil, and otherwise decoded from the token table.
+func (ix *SearchIndex) postingsAt(i int, tokens []string) ([]bst.Posting, error) {
+	offset := binary.LittleEndian.Uint64(ix.terms[i*kSearchIndexTermSize+8:])
+	d := &recordDecoder{data: ix.postings[offset:]}
+	postings := make([]bst.Posting, d.count())
+	for j := range postings {
+		p := &postings[j]
+		token := d.uvarint()
+		if token >= ix.numTokens() {
+			d.fail("token %d is not in the table of %d", token, ix.numTokens())
+			break
+		}
+		if tokens != nil {
+			p.Token = tokens[token]
+		} else {
+			p.Token = ix.token(token)
+		}
+		p.Frequency = d.uvarint()
+		if n := d.count(); n > 0 {
+			p.Positions = make([]bst.Position, n)
+		}
+		for k := range p.Positions {
+			p.Positions[k] = bst.Position{Field: d.uvarint(), Offset: d.uvarint()}
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return postings, nil
+}
+
+// FindPostings returns the postings for |term|, as bst.Tree.FindPostings does
+// for the tree the index was written from, or nil if there are none.
+func (ix *SearchIndex) FindPostings(term string) []bst.Posting {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	n := ix.numTerms()
+	i := sort.Search(n, func(i int) bool {
+		return string(ix.term(i)) >= term
+	})
+	if i == n || string(ix.term(i)) != term {
+		return nil
+	}
+	postings, err := ix.postingsAt(i, nil)
+	if err != nil {
+		log.Printf("%s: postings for %q: %v", ix.file, term, err)
+		return nil
+	}
+	if len(postings) == 0 {
+		return nil
+	}
+	return postings
+}
+
+// tree decodes the whole index into a bst.Tree, and returns it and the tokens.
+func (ix *SearchIndex) tree() (*bst.Tree, []string, error) {
+	tokens := make([]string, ix.numTokens())
+	for i := range tokens {
+		tokens[i] = ix.token(i)
+	}
+	postings := make([][]bst.Posting, ix.numTerms())
+	for i := range postings {
+		var err error
+		if postings[i], err = ix.postingsAt(i, tokens); err != nil {
+			return nil, nil, fmt.Errorf("postings for %q: %v", ix.term(i), err)
+		}
+	}
+
+	// The terms are sorted, so insert them middle first to keep the tree
+	// balanced.
+	tree := bst.NewTree()
+	var insert func(lo, hi int)
+	insert = func(lo, hi int) {
+		if lo >= hi {
+			return
+		}
+		mid := lo + (hi-lo)/2
+		term := string(ix.term(mid))
+		for _, p := range postings[mid] {
+			tree.InsertPosting(term, p)
+		}
+		insert(lo, mid)
+		insert(mid+1, hi)
+	}
+	insert(0, len(postings))
+	return tree, tokens, nil
+}
+
+// Close unmaps the file. FindPostings finds nothing once the index is closed.
+func (ix *SearchIndex) Close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	data := ix.data
+	ix.data, ix.tokens, ix.terms, ix.strings, ix.postings = nil, nil, nil, nil, nil
+	if data == nil {
+		return nil
+	}
+	return unmapFile(data)
+}
+
+func putUvarint(w io.Writer, v int) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], uint64(v))])
+}
+
+func putString(w io.Writer, s string) {
+	putUvarint(w, len(s))
+	io.WriteString(w, s)
+}
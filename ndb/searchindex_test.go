@@ -0,0 +1,198 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/bst"
+)
+
+// allPostings returns the whole search index of |db|.
+func allPostings(db *ASCIIDB) map[string][]bst.Posting {
+	all := make(map[string][]bst.Posting)
+	for term, postings := range db.searchTree.AllPostings() {
+		all[term] = postings
+	}
+	return all
+}
+
+func TestSearchIndexRoundTrip(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.putFood(&Food{
+		NDBID:           "U1",
+		LongDescription: "Soup, chicken and chicken noodle",
+		Branded:         &BrandedFood{GTIN: "012345678905"},
+	})
+
+	var index bytes.Buffer
+	if err := db.WriteSearchIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the database the way the appengine server does.
+	var encoded bytes.Buffer
+	if err := gob.NewEncoder(&encoded).Encode(db); err != nil {
+		t.Fatal(err)
+	}
+	var actual *ASCIIDB
+	if err := gob.NewDecoder(&encoded).Decode(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if err := actual.ReadSearchIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := allPostings(db); !reflect.DeepEqual(expected, allPostings(actual)) {
+		t.Errorf("Expected the index %v, got %v", expected, allPostings(actual))
+	}
+	if postings := actual.FindFoodPostings("chicken"); len(postings) != 1 || postings[0].Frequency != 2 {
+		t.Errorf("Expected chicken twice in U1, got %+v", postings)
+	}
+	if food, ok := actual.FindFoodByGTIN("12345678905"); !ok || food.NDBID != "U1" {
+		t.Errorf("Expected the barcode index to be rebuilt, got %v", food)
+	}
+
+	// The index can still be updated.
+	actual.deleteFood("U1")
+	if ids := actual.FindFood("chicken"); len(ids) != 0 {
+		t.Errorf("Expected U1 to be removed, got %v", ids)
+	}
+}
+
+func TestSearchIndexErrors(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index bytes.Buffer
+	if err := db.WriteSearchIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+	data := index.Bytes()
+
+	// The index is left alone on an error.
+	before := allPostings(db)
+	for i := 0; i < len(data); i++ {
+		if err := db.ReadSearchIndex(bytes.NewReader(data[:i])); err == nil {
+			t.Errorf("Expected an error for the index cut to %d bytes", i)
+		}
+	}
+	if !reflect.DeepEqual(before, allPostings(db)) {
+		t.Errorf("Expected the failed reads to leave the index alone")
+	}
+
+	err = db.ReadSearchIndex(strings.NewReader("NDBSRCH9"))
+	if err == nil || !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("Expected a version error, got %v", err)
+	}
+
+	other := &ASCIIDB{Foods: map[string]*Food{"01001": db.Foods["01001"]}}
+	err = other.ReadSearchIndex(bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "not for this database") {
+		t.Errorf("Expected an error for the index of another database, got %v", err)
+	}
+}
+
+// writeSearchIndexFile writes the search index of |db| to a file in a temporary
+// directory.
+func writeSearchIndexFile(tb testing.TB, db *ASCIIDB) string {
+	file := filepath.Join(tb.TempDir(), "asciidb.search")
+	f, err := os.Create(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := db.WriteSearchIndex(f); err != nil {
+		tb.Fatal(err)
+	}
+	return file
+}
+
+func TestOpenSearchIndex(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix, err := OpenSearchIndex(writeSearchIndexFile(t, db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for term, expected := range allPostings(db) {
+		if postings := ix.FindPostings(term); !reflect.DeepEqual(expected, postings) {
+			t.Errorf("Expected the postings %v for %q, got %v", expected, term, postings)
+		}
+	}
+	for _, term := range []string{"", "aaa", "butte", "butterz", "zzz"} {
+		if postings := ix.FindPostings(term); postings != nil {
+			t.Errorf("Expected no postings for %q, got %v", term, postings)
+		}
+	}
+
+	if err := ix.Close(); err != nil {
+		t.Error(err)
+	}
+	if postings := ix.FindPostings("butter"); postings != nil {
+		t.Errorf("Expected no postings after Close, got %v", postings)
+	}
+}
+
+func TestOpenSearchIndexCorrupt(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(writeSearchIndexFile(t, db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var terms []string
+	for term := range allPostings(db) {
+		terms = append(terms, term)
+	}
+
+	// A cut or corrupt file must not crash the server, however much of it
+	// is checked when it is opened.
+	corrupt := filepath.Join(t.TempDir(), "corrupt.search")
+	for i := 0; i < len(data); i++ {
+		flipped := append([]byte(nil), data...)
+		flipped[i] ^= 0xff
+		for _, changed := range [][]byte{data[:i], flipped} {
+			if err := os.WriteFile(corrupt, changed, 0644); err != nil {
+				t.Fatal(err)
+			}
+			ix, err := OpenSearchIndex(corrupt)
+			if err != nil {
+				continue
+			}
+			for _, term := range terms {
+				ix.FindPostings(term)
+			}
+			ix.Close()
+		}
+	}
+}
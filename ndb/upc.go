@@ -0,0 +1,84 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"strings"
+)
+
+// The length of a GTIN-14, which can hold any of the shorter barcode forms.
+const kGTINLength = 14
+
+// NormalizeGTIN converts a UPC-A (12 digit), EAN-13 or GTIN-14 barcode into a
+// zero-padded GTIN-14, so that the same product matches regardless of which
+// form was printed or scanned. Returns false if |code| is not a barcode.
+func NormalizeGTIN(code string) (string, bool) {
+	code = strings.TrimSpace(code)
+	if code == "" || len(code) > kGTINLength {
+		return "", false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return "", false
+		}
+	}
+	return strings.Repeat("0", kGTINLength-len(code)) + code, true
+}
+
+// FindFoodByGTIN returns the branded Food with the barcode |gtin|, which can
+// be in any form accepted by NormalizeGTIN.
+func (db *ASCIIDB) FindFoodByGTIN(gtin string) (*Food, bool) {
+	gtin, ok := NormalizeGTIN(gtin)
+	if !ok {
+		return nil, false
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	id, ok := db.gtinIndex[gtin]
+	if !ok {
+		return nil, false
+	}
+	food, ok := db.Foods[id]
+	return food, ok
+}
+
+// addGTINForFood adds |food| to the barcode index if it is a branded food.
+func (db *ASCIIDB) addGTINForFood(food *Food) {
+	if food.Branded == nil {
+		return
+	}
+	gtin, ok := NormalizeGTIN(food.Branded.GTIN)
+	if !ok {
+		return
+	}
+	if db.gtinIndex == nil {
+		db.gtinIndex = make(map[string]string)
+	}
+	db.gtinIndex[gtin] = food.NDBID
+}
+
+// removeGTINForFood removes |food| from the barcode index.
+func (db *ASCIIDB) removeGTINForFood(food *Food) {
+	if food.Branded == nil {
+		return
+	}
+	gtin, ok := NormalizeGTIN(food.Branded.GTIN)
+	if ok && db.gtinIndex[gtin] == food.NDBID {
+		delete(db.gtinIndex, gtin)
+	}
+}
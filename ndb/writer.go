@@ -0,0 +1,263 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+)
+
+// WriteDatabase writes |db| to the directory |base| in the format of the USDA
+// release: FD_GROUP.txt, NUTR_DEF.txt, FOOD_DES.txt, NUT_DATA.txt and WEIGHT.txt,
+// with tilde-quoted, caret-delimited fields and CRLF line endings, in the
+// Encoding |enc|. Foods that have ingredient statements are also written to
+// INGREDIENTS.txt, and footnotes to FOOTNOTE.txt. The statistics of nutrient
+// values without Stats are left empty.
+//
+// Foods are written in NDBID order, and their nutrients and weights in the order
+// of the Food, so ReadDatabase(base) returns the same database. Every column is
+// kept, and so is the text of numbers that were read from a file and still have
+// the same value, like "5.0" or ".5", so a database read from an SR release is
+// written back as it was.
+func WriteDatabase(db *ASCIIDB, base string, enc Encoding) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]string, 0, len(db.Foods))
+	for id := range db.Foods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	err := writeTable(base, "FD_GROUP.txt", enc, func(emit func(interface{}) error) error {
+		for _, g := range db.FoodGroups {
+			if err := emit(&foodGroupRecord{GroupCode: g.GroupCode, Description: g.Description}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTable(base, "NUTR_DEF.txt", enc, func(emit func(interface{}) error) error {
+		for _, n := range db.Nutrients {
+			err := emit(&nutrientRecord{
+				NutrientID:  n.NutrientID,
+				Units:       n.Units,
+				Description: n.Description,
+				SortOrder:   n.SortOrder,
+				Tagname:     n.Tagname,
+				Decimals:    n.Decimals,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTable(base, "FOOD_DES.txt", enc, func(emit func(interface{}) error) error {
+		for _, id := range ids {
+			food := db.Foods[id]
+			err := emit(&foodRecord{
+				NDBID:              food.NDBID,
+				FoodGroup:          food.FoodGroup,
+				LongDescription:    food.LongDescription,
+				ShortDescription:   food.ShortDescription,
+				CommonNames:        food.CommonNames,
+				Manufacturer:       food.Manufacturer,
+				RefuseDescription:  food.RefuseDescription,
+				Refuse:             food.Refuse,
+				ScientificName:     food.ScientificName,
+				Survey:             flag(food.Survey),
+				NitrogenFactor:     food.NitrogenFactor,
+				ProteinFactor:      food.ProteinFactor,
+				FatFactor:          food.FatFactor,
+				CarbohydrateFactor: food.CarbohydrateFactor,
+				Numbers:            db.numbers[numberKey("FOOD_DES", id, 0)],
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTable(base, "NUT_DATA.txt", enc, func(emit func(interface{}) error) error {
+		for _, id := range ids {
+			for _, n := range db.Foods[id].Nutrients {
+				r := &foodNutrientRecord{
+					NDBID:      id,
+					NutrientID: n.NutrientID,
+					Value:      n.Value,
+					DataPoints: n.DataPoints,
+					Numbers:    db.numbers[numberKey("NUT_DATA", id, n.NutrientID)],
+				}
+				if st := n.Stats; st != nil {
+					r.StdError = st.StdError
+					r.SourceCode = st.SourceCode
+					r.DerivationCode = st.DerivationCode
+					r.RefNDBID = st.RefNDBID
+					r.Added = flag(st.Added)
+					r.Studies = st.Studies
+					r.Min = st.Min
+					r.Max = st.Max
+					r.DegreesOfFreedom = st.DegreesOfFreedom
+					r.LowerErrorBound = st.LowerErrorBound
+					r.UpperErrorBound = st.UpperErrorBound
+					r.Comments = st.Comments
+					r.Modified = st.Modified
+					r.ConfidenceCode = st.ConfidenceCode
+				}
+				if err := emit(r); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTable(base, "WEIGHT.txt", enc, func(emit func(interface{}) error) error {
+		for _, id := range ids {
+			for _, w := range db.Foods[id].Weights {
+				err := emit(&weightRecord{
+					NDBID:       id,
+					Sequence:    w.Sequence,
+					Amount:      w.Amount,
+					Description: w.Description,
+					WeightG:     w.WeightG,
+					DataPoints:  w.DataPoints,
+					StdDev:      w.StdDev,
+					Numbers:     db.numbers[numberKey("WEIGHT", id, w.Sequence)],
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	hasIngredients, hasFootnotes := false, false
+	for _, food := range db.Foods {
+		hasIngredients = hasIngredients || food.Ingredients != ""
+		hasFootnotes = hasFootnotes || len(food.Footnotes) > 0
+	}
+
+	if hasIngredients {
+		err = writeTable(base, "INGREDIENTS.txt", enc, func(emit func(interface{}) error) error {
+			for _, id := range ids {
+				if food := db.Foods[id]; food.Ingredients != "" {
+					if err := emit(&ingredientsRecord{NDBID: id, Ingredients: food.Ingredients}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !hasFootnotes {
+		return nil
+	}
+	return writeTable(base, "FOOTNOTE.txt", enc, func(emit func(interface{}) error) error {
+		for _, id := range ids {
+			for _, f := range db.Foods[id].Footnotes {
+				err := emit(&footnoteRecord{
+					NDBID:      id,
+					Number:     f.Number,
+					Type:       f.Type,
+					NutrientID: f.NutrientID,
+					Text:       f.Text,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// flag returns the text of a Y/N column, which is "Y" or empty.
+func flag(b bool) string {
+	if b {
+		return "Y"
+	}
+	return ""
+}
+
+// writeTable creates the file |name| in |base| and calls |records| to write its
+// lines, which it does by calling |emit| with each record struct.
+func writeTable(base, name string, enc Encoding, records func(emit func(interface{}) error) error) error {
+	file := path.Join(base, name)
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("WriteDatabase: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	line := 0
+	err = records(func(v interface{}) error {
+		line++
+		s, err := MarshalRecord(v)
+		if err == nil {
+			var b []byte
+			if b, err = enc.encode(s); err == nil {
+				w.Write(b)
+				_, err = w.WriteString("\r\n")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("WriteDatabase: %s:%d: %v", file, line, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("WriteDatabase: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("WriteDatabase: %v", err)
+	}
+	return nil
+}
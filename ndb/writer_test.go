@@ -0,0 +1,168 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteDatabaseRoundTrip(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A locally added food, with text that only Windows-1252 can encode.
+	db.Foods["99001"] = &Food{
+		NDBID:            "99001",
+		FoodGroup:        1900,
+		LongDescription:  "Tart, “house” lemon",
+		ShortDescription: "TART,LEMON",
+		Manufacturer:     "Bäckerei",
+		ScientificName:   "Citrus limon",
+		Ingredients:      "FLOUR, BUTTER, SUGAR, LEMON",
+		Refuse:           5,
+		Nutrients: []FoodNutrient{
+			{NutrientID: 208, Value: 412.5, DataPoints: 1},
+			{NutrientID: 203, Value: 0.1207, DataPoints: 0},
+		},
+		Weights: []Weight{{Sequence: 1, Amount: 0.5, Description: "slice", WeightG: 85.25}},
+	}
+
+	base := t.TempDir()
+	if err := WriteDatabase(db, base, Windows1252); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ReadDatabase(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(db.FoodGroups, actual.FoodGroups) {
+		t.Errorf("Expected food groups %v, got %v", db.FoodGroups, actual.FoodGroups)
+	}
+	if !reflect.DeepEqual(db.Nutrients, actual.Nutrients) {
+		t.Errorf("Expected nutrients %v, got %v", db.Nutrients, actual.Nutrients)
+	}
+	if !reflect.DeepEqual(db.Foods, actual.Foods) {
+		for id, food := range db.Foods {
+			if !reflect.DeepEqual(food, actual.Foods[id]) {
+				t.Errorf("Expected food %+v, got %+v", food, actual.Foods[id])
+			}
+		}
+	}
+	if ids := actual.FindFood("lemon"); len(ids) == 0 || ids[0] != "99001" {
+		t.Errorf("Expected the added food to be indexed, got %v", ids)
+	}
+}
+
+func TestWriteDatabaseFormat(t *testing.T) {
+	db, err := ReadDatabase(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := t.TempDir()
+	if err := WriteDatabase(db, base, Windows1252); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every column is kept, so the files are written exactly as they were read.
+	files, err := os.ReadDir(kTestDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		compareSRFile(t, file.Name(), kTestDatabase, base)
+	}
+
+	db.Foods["01001"].LongDescription = "Butter ^ salted"
+	if err := WriteDatabase(db, t.TempDir(), Windows1252); err == nil {
+		t.Errorf("Expected an error for a caret in a text field")
+	}
+	db.Foods["01001"].LongDescription = "Butter, salted ✓"
+	if err := WriteDatabase(db, t.TempDir(), Windows1252); err == nil {
+		t.Errorf("Expected an error for a character that cannot be encoded")
+	}
+}
+
+// The SR25 release in data/ is written back exactly as it was. It has no
+// NUT_DATA.txt, so an empty one stands in.
+func TestWriteDatabaseRelease(t *testing.T) {
+	const release = "../data"
+	tables := []string{"FD_GROUP.txt", "NUTR_DEF.txt", "FOOD_DES.txt", "WEIGHT.txt", "FOOTNOTE.txt"}
+	input := t.TempDir()
+	for _, name := range tables {
+		data, err := os.ReadFile(filepath.Join(release, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(input, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(input, "NUT_DATA.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := ReadDatabase(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := WriteDatabase(db, output, Windows1252); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range tables {
+		compareSRFile(t, name, input, output)
+	}
+}
+
+// compareSRFile reports the first line of the file |name| in |actualDir| that
+// differs from the one in |expectedDir|.
+func compareSRFile(t *testing.T, name, expectedDir, actualDir string) {
+	t.Helper()
+	expected, err := os.ReadFile(filepath.Join(expectedDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(filepath.Join(actualDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+	el, al := bytes.SplitAfter(expected, []byte("\n")), bytes.SplitAfter(actual, []byte("\n"))
+	for i := 0; i < len(el) || i < len(al); i++ {
+		var e, a []byte
+		if i < len(el) {
+			e = el[i]
+		}
+		if i < len(al) {
+			a = al[i]
+		}
+		if !bytes.Equal(e, a) {
+			t.Errorf("%s:%d: expected %q, got %q", name, i+1, e, a)
+			return
+		}
+	}
+}
@@ -0,0 +1,322 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ndbapi has the response types of the versioned JSON API that the
+// server has at /api/v1/. Unlike the types of github.com/rsesek/usda-ndb/ndb,
+// which the /_/ endpoints encode as they are, these only change compatibly:
+// fields may be added, but not renamed or removed.
+package ndbapi
+
+import (
+	"github.com/rsesek/usda-ndb/ingredients"
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// The prefix of the URLs of the API.
+const PathPrefix = "/api/v1/"
+
+// An ErrorResponse is the body of a response that is not a success.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	// The HTTP status code.
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// A SearchResponse is the response of /api/v1/search.
+type SearchResponse struct {
+	// The matching foods, best first.
+	Results []SearchResult `json:"results"`
+}
+
+type SearchResult struct {
+	NDBID        string `json:"ndb_id"`
+	FoodGroup    int    `json:"food_group"`
+	Description  string `json:"description"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	// The number of words of the query that match the food.
+	Score int `json:"score"`
+}
+
+// A FoodGroupsResponse is the response of /api/v1/food_groups.
+type FoodGroupsResponse struct {
+	FoodGroups []FoodGroup `json:"food_groups"`
+}
+
+type FoodGroup struct {
+	// 4-digit code identifying the food group.
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+// A NutrientsResponse is the response of /api/v1/nutrients.
+type NutrientsResponse struct {
+	Nutrients []Nutrient `json:"nutrients"`
+}
+
+type Nutrient struct {
+	// 3-digit code that identifies the nutrient.
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Units string `json:"units"`
+	// The order used in official reports.
+	SortOrder int `json:"sort_order"`
+}
+
+// A Food is the response of /api/v1/foods/<ndb_id> and /api/v1/upc/<gtin>.
+type Food struct {
+	// 5-digit identification number for the food, with leading zeros.
+	NDBID string `json:"ndb_id"`
+	// FoodData Central identifier, if the food was loaded from an FDC download.
+	FDCID            int       `json:"fdc_id,omitempty"`
+	FoodGroup        FoodGroup `json:"food_group"`
+	LongDescription  string    `json:"long_description"`
+	ShortDescription string    `json:"short_description"`
+	CommonNames      string    `json:"common_names,omitempty"`
+	ScientificName   string    `json:"scientific_name,omitempty"`
+	Manufacturer     string    `json:"manufacturer,omitempty"`
+	// Description of the inedible parts of the food.
+	RefuseDescription string `json:"refuse_description,omitempty"`
+	// The percentage of the food that is refuse.
+	RefusePercent int            `json:"refuse_percent"`
+	Nutrients     []FoodNutrient `json:"nutrients"`
+	// The common household measures.
+	Weights   []Weight     `json:"weights"`
+	Footnotes []Footnote   `json:"footnotes"`
+	Branded   *BrandedFood `json:"branded,omitempty"`
+	// The ingredient statement, for manufactured foods.
+	Ingredients *Ingredients `json:"ingredients,omitempty"`
+	// The overlay that added the food, or empty if it is from the USDA data.
+	Source string `json:"source,omitempty"`
+}
+
+// A FoodNutrient is the value of a nutrient of a food, with the definition of
+// the nutrient.
+type FoodNutrient struct {
+	NutrientID int    `json:"nutrient_id"`
+	Name       string `json:"name"`
+	Units      string `json:"units"`
+	// The amount in 100 grams of the edible portion, in Units.
+	Value float32 `json:"value"`
+	// Number of data points used to calculate the value.
+	DataPoints int `json:"data_points"`
+	// The overlay that added or corrected the value, or empty if it is from the
+	// USDA data.
+	Source string `json:"source,omitempty"`
+}
+
+// A Weight is a common measure of a food. The nutrient values of the measure
+// are Value * Grams / 100.
+type Weight struct {
+	Sequence int `json:"sequence"`
+	// Amount of units, e.g. 1 in 1 cup.
+	Amount      float32 `json:"amount"`
+	Description string  `json:"description"`
+	Grams       float32 `json:"grams"`
+}
+
+// The types of Footnote.
+const (
+	FootnoteDescription = "description"
+	FootnoteMeasure     = "measure"
+	FootnoteNutrient    = "nutrient"
+)
+
+// A Footnote is a comment on a food, one of its weights or one of its nutrient
+// values.
+type Footnote struct {
+	// Sequence number. For a FootnoteMeasure, the Sequence of the Weight.
+	Number int `json:"number"`
+	// One of FootnoteDescription, FootnoteMeasure or FootnoteNutrient.
+	Type string `json:"type"`
+	// For a FootnoteNutrient, the nutrient it describes.
+	NutrientID int    `json:"nutrient_id,omitempty"`
+	Text       string `json:"text"`
+}
+
+// A BrandedFood is the label information for a manufacturer's product.
+type BrandedFood struct {
+	// The UPC/GTIN barcode, as printed on the package.
+	GTIN       string `json:"gtin"`
+	BrandOwner string `json:"brand_owner"`
+	BrandName  string `json:"brand_name,omitempty"`
+	// The label serving size, in ServingSizeUnit (typically g or ml).
+	ServingSize     float32 `json:"serving_size"`
+	ServingSizeUnit string  `json:"serving_size_unit"`
+	// The household description of the serving, e.g. "1 cup".
+	HouseholdServing string `json:"household_serving,omitempty"`
+	Category         string `json:"category,omitempty"`
+}
+
+// Ingredients is an ingredient statement, and what it contains.
+type Ingredients struct {
+	// The statement, as printed on the label.
+	Statement string `json:"statement"`
+	// The top-level ingredients, in label order.
+	Parsed []Ingredient `json:"parsed"`
+	// The major allergens of the ingredients, e.g. "milk" or "tree nuts".
+	Allergens []string `json:"allergens"`
+	Additives []string `json:"additives"`
+}
+
+type Ingredient struct {
+	Name string `json:"name"`
+	// The function of the ingredient, if the label gives one, e.g. "color".
+	Purpose string `json:"purpose,omitempty"`
+	// True if the ingredient follows a "contains 2% or less of" phrase.
+	Minor bool `json:"minor,omitempty"`
+	// The sub-ingredients listed in parentheses or brackets.
+	Children []Ingredient `json:"children,omitempty"`
+}
+
+// NewSearchResponse converts |results|.
+func NewSearchResponse(results []ndb.SearchResult) SearchResponse {
+	resp := SearchResponse{Results: make([]SearchResult, 0, len(results))}
+	for _, r := range results {
+		resp.Results = append(resp.Results, SearchResult{
+			NDBID:        r.NDBID,
+			FoodGroup:    r.FoodGroup,
+			Description:  r.Description,
+			Manufacturer: r.Manufacturer,
+			Score:        r.Score,
+		})
+	}
+	return resp
+}
+
+func NewFoodGroup(g ndb.FoodGroup) FoodGroup {
+	return FoodGroup{Code: g.GroupCode, Description: g.Description}
+}
+
+func NewNutrient(n ndb.Nutrient) Nutrient {
+	return Nutrient{ID: n.NutrientID, Name: n.Description, Units: n.Units, SortOrder: n.SortOrder}
+}
+
+// NewFood converts |food|, with the definitions of its food group and nutrients
+// from |db|. The values of nutrients that |db| does not define are left out, as
+// they have no units.
+func NewFood(db ndb.Reader, food *ndb.Food) *Food {
+	nutrients := make(map[int]ndb.Nutrient, len(db.AllNutrients()))
+	for _, n := range db.AllNutrients() {
+		nutrients[n.NutrientID] = n
+	}
+
+	f := &Food{
+		NDBID:             food.NDBID,
+		FDCID:             food.FDCID,
+		FoodGroup:         FoodGroup{Code: food.FoodGroup},
+		LongDescription:   food.LongDescription,
+		ShortDescription:  food.ShortDescription,
+		CommonNames:       food.CommonNames,
+		ScientificName:    food.ScientificName,
+		Manufacturer:      food.Manufacturer,
+		RefuseDescription: food.RefuseDescription,
+		RefusePercent:     food.Refuse,
+		Nutrients:         make([]FoodNutrient, 0, len(food.Nutrients)),
+		Weights:           make([]Weight, 0, len(food.Weights)),
+		Footnotes:         make([]Footnote, 0, len(food.Footnotes)),
+		Source:            food.Source,
+	}
+	for _, g := range db.AllFoodGroups() {
+		if g.GroupCode == food.FoodGroup {
+			f.FoodGroup = NewFoodGroup(g)
+			break
+		}
+	}
+	for _, fn := range food.Nutrients {
+		n, ok := nutrients[fn.NutrientID]
+		if !ok {
+			continue
+		}
+		f.Nutrients = append(f.Nutrients, FoodNutrient{
+			NutrientID: fn.NutrientID,
+			Name:       n.Description,
+			Units:      n.Units,
+			Value:      fn.Value,
+			DataPoints: fn.DataPoints,
+			Source:     fn.Source,
+		})
+	}
+	for _, w := range food.Weights {
+		f.Weights = append(f.Weights, Weight{
+			Sequence:    w.Sequence,
+			Amount:      w.Amount,
+			Description: w.Description,
+			Grams:       w.WeightG,
+		})
+	}
+	for _, fn := range food.Footnotes {
+		f.Footnotes = append(f.Footnotes, Footnote{
+			Number:     fn.Number,
+			Type:       footnoteTypes[fn.Type],
+			NutrientID: fn.NutrientID,
+			Text:       fn.Text,
+		})
+	}
+	if b := food.Branded; b != nil {
+		f.Branded = &BrandedFood{
+			GTIN:             b.GTIN,
+			BrandOwner:       b.BrandOwner,
+			BrandName:        b.BrandName,
+			ServingSize:      b.ServingSize,
+			ServingSizeUnit:  b.ServingSizeUnit,
+			HouseholdServing: b.HouseholdServing,
+			Category:         b.Category,
+		}
+	}
+	if food.Ingredients != "" {
+		f.Ingredients = newIngredients(food.Ingredients)
+	}
+	return f
+}
+
+var footnoteTypes = map[string]string{
+	ndb.FootnoteDescription: FootnoteDescription,
+	ndb.FootnoteMeasure:     FootnoteMeasure,
+	ndb.FootnoteNutrient:    FootnoteNutrient,
+}
+
+func newIngredients(statement string) *Ingredients {
+	st := ingredients.Parse(statement)
+	in := &Ingredients{
+		Statement: statement,
+		Parsed:    newIngredientList(st.Ingredients),
+		Allergens: make([]string, 0),
+		Additives: make([]string, 0),
+	}
+	for _, a := range st.Allergens() {
+		in.Allergens = append(in.Allergens, string(a))
+	}
+	in.Additives = append(in.Additives, st.Additives()...)
+	return in
+}
+
+func newIngredientList(list []*ingredients.Ingredient) []Ingredient {
+	result := make([]Ingredient, 0, len(list))
+	for _, i := range list {
+		result = append(result, Ingredient{
+			Name:     i.Name,
+			Purpose:  i.Purpose,
+			Minor:    i.Minor,
+			Children: newIngredientList(i.Children),
+		})
+	}
+	return result
+}
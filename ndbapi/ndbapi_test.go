@@ -0,0 +1,91 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func TestNewFood(t *testing.T) {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, _ := db.Food("01001")
+	food.Nutrients = append(food.Nutrients, ndb.FoodNutrient{NutrientID: 999, Value: 1})
+
+	actual, err := json.Marshal(NewFood(db, food))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+		"ndb_id": "01001",
+		"food_group": {"code": 100, "description": "Dairy and Egg Products"},
+		"long_description": "Butter, salted",
+		"short_description": "BUTTER,WITH SALT",
+		"refuse_percent": 0,
+		"nutrients": [
+			{"nutrient_id": 203, "name": "Protein", "units": "g", "value": 0.85, "data_points": 16},
+			{"nutrient_id": 204, "name": "Total lipid (fat)", "units": "g", "value": 81.11, "data_points": 580},
+			{"nutrient_id": 208, "name": "Energy", "units": "kcal", "value": 717, "data_points": 0},
+			{"nutrient_id": 320, "name": "Vitamin A, RAE", "units": "µg", "value": 684, "data_points": 0}
+		],
+		"weights": [
+			{"sequence": 1, "amount": 1, "description": "cup", "grams": 227},
+			{"sequence": 2, "amount": 1, "description": "tbsp", "grams": 14.2},
+			{"sequence": 3, "amount": 1, "description": "pat (1\" sq, 1/3\" high)", "grams": 5},
+			{"sequence": 4, "amount": 1, "description": "stick", "grams": 113}
+		],
+		"footnotes": [
+			{"number": 1, "type": "description", "text": "Salted with 1.5% to 2% salt"},
+			{"number": 2, "type": "measure", "text": "Measured from stick butter"}
+		]
+	}`
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if compact.String() != string(actual) {
+		t.Errorf("Expected %s, got %s", compact.String(), actual)
+	}
+}
+
+func TestNewFoodIngredients(t *testing.T) {
+	db, err := ndb.ReadFDCJSON("../ndb/testdata/fdc/branded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	food := NewFood(db, db.Foods[db.FoodIDs()[0]])
+	in := food.Ingredients
+	if in == nil || in.Statement == "" {
+		t.Fatalf("Expected an ingredient statement, got %+v", in)
+	}
+	if len(in.Parsed) != 5 || in.Parsed[0].Name != "PASTEURIZED MILK" || in.Parsed[4].Purpose != "color" {
+		t.Errorf("Unexpected ingredients %+v", in.Parsed)
+	}
+	if len(in.Allergens) != 1 || in.Allergens[0] != "milk" {
+		t.Errorf("Expected milk, got %v", in.Allergens)
+	}
+	if food.Branded == nil || food.Branded.GTIN != "0021000615261" {
+		t.Errorf("Unexpected branded food %+v", food.Branded)
+	}
+}
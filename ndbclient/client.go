@@ -0,0 +1,147 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ndbclient calls the versioned JSON API of a usda-ndb server, which is
+// described by the OpenAPI document that the server has at /_/openapi.json.
+package ndbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rsesek/usda-ndb/ndbapi"
+)
+
+// ErrNotFound is wrapped by the errors for foods that do not exist.
+var ErrNotFound = errors.New("not found")
+
+// An Error is a response from the server that is not a success.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ndbclient: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap returns ErrNotFound for a 404 response.
+func (e *Error) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// A Client calls a server. Its methods are safe to call concurrently.
+type Client struct {
+	// The URL of the server, e.g. "http://localhost:8077".
+	BaseURL string
+	// The client to send requests with. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// New creates a Client for the server at |baseURL|.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Search returns up to |limit| of the foods matching any of the space-separated
+// words of |query|, best first. If |limit| is 0, it returns all of them.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]ndbapi.SearchResult, error) {
+	params := url.Values{"q": {query}}
+	if limit != 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	var resp ndbapi.SearchResponse
+	err := c.get(ctx, "search?"+params.Encode(), &resp)
+	return resp.Results, err
+}
+
+// Food returns the food with NDBID |id|, or an error that wraps ErrNotFound.
+func (c *Client) Food(ctx context.Context, id string) (*ndbapi.Food, error) {
+	food := &ndbapi.Food{}
+	if err := c.get(ctx, "foods/"+url.PathEscape(id), food); err != nil {
+		return nil, err
+	}
+	return food, nil
+}
+
+// FoodByUPC returns the branded food with the UPC/GTIN barcode |gtin|, or an
+// error that wraps ErrNotFound.
+func (c *Client) FoodByUPC(ctx context.Context, gtin string) (*ndbapi.Food, error) {
+	food := &ndbapi.Food{}
+	if err := c.get(ctx, "upc/"+url.PathEscape(gtin), food); err != nil {
+		return nil, err
+	}
+	return food, nil
+}
+
+// FoodGroups returns the food groups.
+func (c *Client) FoodGroups(ctx context.Context) ([]ndbapi.FoodGroup, error) {
+	var resp ndbapi.FoodGroupsResponse
+	err := c.get(ctx, "food_groups", &resp)
+	return resp.FoodGroups, err
+}
+
+// Nutrients returns the nutrient definitions.
+func (c *Client) Nutrients(ctx context.Context) ([]ndbapi.Nutrient, error) {
+	var resp ndbapi.NutrientsResponse
+	err := c.get(ctx, "nutrients", &resp)
+	return resp.Nutrients, err
+}
+
+// get requests the API endpoint |path| and decodes the JSON response into |v|.
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+ndbapi.PathPrefix+path, nil)
+	if err != nil {
+		return fmt.Errorf("ndbclient: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ndbclient: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Limit the error text, in case the URL is not a usda-ndb server.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		var errResp ndbapi.ErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+			apiErr.Message = errResp.Error.Message
+		}
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("ndbclient: GET %s: %v", path, err)
+	}
+	return nil
+}
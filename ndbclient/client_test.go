@@ -0,0 +1,144 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/frontend"
+	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbapi"
+)
+
+// newTestServer serves the database read by |read| from |file|.
+func newTestServer(t *testing.T, read func(string) (*ndb.ASCIIDB, error), file string) (*ndb.ASCIIDB, *httptest.Server) {
+	db, err := read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(frontend.NewServer(db, t.TempDir()))
+	t.Cleanup(server.Close)
+	return db, server
+}
+
+func TestClient(t *testing.T) {
+	db, server := newTestServer(t, ndb.ReadDatabase, "../ndb/testdata/sr")
+	client := New(server.URL + "/")
+	ctx := context.Background()
+
+	results, err := client.Search(ctx, "cheese butter", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := ndbapi.NewSearchResponse(db.Search("cheese butter")).Results; !reflect.DeepEqual(expected, results) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+	if limited, err := client.Search(ctx, "cheese butter", 1); err != nil || !reflect.DeepEqual(results[:1], limited) {
+		t.Errorf("Expected %v, got %v, %v", results[:1], limited, err)
+	}
+	if results, err := client.Search(ctx, "zzz", 0); err != nil || len(results) != 0 {
+		t.Errorf("Expected no results, got %v, %v", results, err)
+	}
+	var apiErr *Error
+	if _, err := client.Search(ctx, "cheese", -1); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+
+	food, err := client.Food(ctx, "01001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := db.Food("01001")
+	if !reflect.DeepEqual(ndbapi.NewFood(db, expected), food) {
+		t.Errorf("Expected %+v, got %+v", ndbapi.NewFood(db, expected), food)
+	}
+
+	_, err = client.Food(ctx, "00000")
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if apiErr.Message != "Could not find food with id 00000" {
+		t.Errorf("Unexpected message %q", apiErr.Message)
+	}
+
+	groups, err := client.FoodGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != len(db.FoodGroups) {
+		t.Fatalf("Expected %v, got %v", db.FoodGroups, groups)
+	}
+	for i, g := range groups {
+		if g != ndbapi.NewFoodGroup(db.FoodGroups[i]) {
+			t.Errorf("Expected %v, got %v", db.FoodGroups[i], g)
+		}
+	}
+
+	nutrients, err := client.Nutrients(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nutrients) != len(db.Nutrients) {
+		t.Fatalf("Expected %v, got %v", db.Nutrients, nutrients)
+	}
+	for i, n := range nutrients {
+		if n != ndbapi.NewNutrient(db.Nutrients[i]) {
+			t.Errorf("Expected %v, got %v", db.Nutrients[i], n)
+		}
+	}
+}
+
+func TestClientUPC(t *testing.T) {
+	_, server := newTestServer(t, ndb.ReadFDCJSON, "../ndb/testdata/fdc/branded.json")
+	client := New(server.URL)
+	ctx := context.Background()
+
+	food, err := client.FoodByUPC(ctx, "21000615261")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Branded == nil || food.Branded.BrandName != "KRAFT" {
+		t.Errorf("Expected the KRAFT cheese, got %+v", food)
+	}
+	if food.Ingredients == nil || !reflect.DeepEqual([]string{"milk"}, food.Ingredients.Allergens) {
+		t.Errorf("Expected milk, got %+v", food.Ingredients)
+	}
+
+	_, err = client.FoodByUPC(ctx, "not-a-upc")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+	if _, err := client.FoodByUPC(ctx, "0012345678905"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClientCanceled(t *testing.T) {
+	_, server := newTestServer(t, ndb.ReadDatabase, "../ndb/testdata/sr")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(server.URL).FoodGroups(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
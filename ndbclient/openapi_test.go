@@ -0,0 +1,286 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// An apiDocument is the part of an OpenAPI document that the tests check.
+type apiDocument struct {
+	Paths      map[string]map[string]json.RawMessage
+	Components struct {
+		Schemas   map[string]*apiSchema
+		Responses map[string]*apiResponse
+	}
+}
+
+type apiOperation struct {
+	OperationID string
+	Responses   map[string]*apiResponse
+}
+
+type apiResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *apiSchema
+	}
+}
+
+type apiSchema struct {
+	Ref        string `json:"$ref"`
+	Type       string
+	Nullable   bool
+	Enum       []interface{}
+	Required   []string
+	Properties map[string]*apiSchema
+	Items      *apiSchema
+	AllOf      []*apiSchema
+}
+
+// The requests for each operation of the document, with the path parameters
+// filled in. They are sent to a server of the SR test database and to one of the
+// branded foods, neither of which has a journal, so editing is not allowed.
+var apiRequests = map[string][]struct {
+	url  string
+	body string
+}{
+	"search":      {{"/_/search?q=butter", ""}, {"/_/search?q=zzz", ""}},
+	"foodGroups":  {{"/_/foodGroups", ""}},
+	"nutrients":   {{"/_/nutrients", ""}},
+	"food":        {{"/_/food/01001", ""}, {"/_/food/11090", ""}, {"/_/food/00000", ""}},
+	"createFood":  {{"/_/food/", `{"NDBID": "U1", "LongDescription": "Granola"}`}},
+	"replaceFood": {{"/_/food/U1", `{"LongDescription": "Granola"}`}},
+	"deleteFood":  {{"/_/food/U1", ""}},
+	"upc":         {{"/_/upc/0021000615261", ""}, {"/_/upc/x", ""}},
+	"export":      {{"/_/export?q=butter&format=tsv", ""}, {"/_/export?nutrients=x", ""}},
+	"graphqlGet":  {{"/_/graphql?query=%7B+food(ndbId%3A+%2201001%22)+%7B+ndbId+%7D+%7D", ""}, {"/_/graphql", ""}},
+	"graphqlPost": {{"/_/graphql", `{"query": "{ nutrients { units } }"}`}},
+	"openAPI":     {{"/_/openapi.json", ""}},
+
+	"v1Search":     {{"/api/v1/search?q=cheese+butter&limit=1", ""}, {"/api/v1/search?q=zzz", ""}, {"/api/v1/search?q=cheese&limit=x", ""}},
+	"v1Food":       {{"/api/v1/foods/01001", ""}, {"/api/v1/foods/2041155", ""}, {"/api/v1/foods/00000", ""}},
+	"v1UPC":        {{"/api/v1/upc/0021000615261", ""}, {"/api/v1/upc/x", ""}},
+	"v1FoodGroups": {{"/api/v1/food_groups", ""}},
+	"v1Nutrients":  {{"/api/v1/nutrients", ""}},
+}
+
+// TestOpenAPI sends requests for every operation of the OpenAPI document to the
+// frontend handlers, and checks that each response has a documented status and
+// content type, and that JSON responses match their schemas. Properties that
+// the document does not list are errors, so that it stays complete.
+func TestOpenAPI(t *testing.T) {
+	_, server := newTestServer(t, ndb.ReadDatabase, "../ndb/testdata/sr")
+	_, branded := newTestServer(t, ndb.ReadFDCJSON, "../ndb/testdata/fdc/branded.json")
+	resp, err := http.Get(server.URL + "/_/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc apiDocument
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tested := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op apiOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+			requests, ok := apiRequests[op.OperationID]
+			if !ok {
+				t.Errorf("%s %s: no requests for %s", method, path, op.OperationID)
+			}
+			tested[op.OperationID] = true
+			for _, r := range requests {
+				for _, base := range []string{server.URL, branded.URL} {
+					checkOperation(t, &doc, &op, strings.ToUpper(method), base+r.url, r.body)
+				}
+			}
+		}
+	}
+	for id := range apiRequests {
+		if !tested[id] {
+			t.Errorf("The document has no operation %s", id)
+		}
+	}
+}
+
+func checkOperation(t *testing.T, doc *apiDocument, op *apiOperation, method, url, body string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented, ok := op.Responses[fmt.Sprint(resp.StatusCode)]
+	if !ok {
+		t.Errorf("%s %s: undocumented status %d: %s", method, url, resp.StatusCode, data)
+		return
+	}
+	if documented.Ref != "" {
+		documented = doc.Components.Responses[strings.TrimPrefix(documented.Ref, "#/components/responses/")]
+	}
+	if len(documented.Content) == 0 {
+		if len(data) != 0 {
+			t.Errorf("%s %s: expected no content, got %s", method, url, data)
+		}
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	content, ok := documented.Content[contentType]
+	if !ok {
+		t.Errorf("%s %s: undocumented content type %q for status %d", method, url, contentType, resp.StatusCode)
+		return
+	}
+	if contentType != "application/json" {
+		return
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Errorf("%s %s: %v", method, url, err)
+		return
+	}
+	for _, e := range doc.check(v, content.Schema, "response") {
+		t.Errorf("%s %s: %s", method, url, e)
+	}
+}
+
+// check returns the differences between the JSON value |v| and |s|.
+func (doc *apiDocument) check(v interface{}, s *apiSchema, path string) []string {
+	s = doc.resolve(s)
+	if v == nil {
+		if s.Nullable {
+			return nil
+		}
+		return []string{path + ": null is not nullable"}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			found = found || e == v
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", path, v, s.Enum)}
+		}
+	}
+
+	properties, required, typ := doc.objectSchema(s)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if typ != "object" {
+			return []string{fmt.Sprintf("%s: expected %s, got an object", path, typ)}
+		}
+		var errs []string
+		for _, name := range required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required %s", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := properties[name]; ok {
+				errs = append(errs, doc.check(v[name], p, path+"."+name)...)
+			} else if properties != nil {
+				errs = append(errs, fmt.Sprintf("%s: undocumented property %s", path, name))
+			}
+		}
+		return errs
+	case []interface{}:
+		if typ != "array" {
+			return []string{fmt.Sprintf("%s: expected %s, got an array", path, typ)}
+		}
+		var errs []string
+		for i, item := range v {
+			errs = append(errs, doc.check(item, s.Items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	case string:
+		if typ != "string" {
+			return []string{fmt.Sprintf("%s: expected %s, got a string", path, typ)}
+		}
+	case bool:
+		if typ != "boolean" {
+			return []string{fmt.Sprintf("%s: expected %s, got a boolean", path, typ)}
+		}
+	case float64:
+		if typ != "number" && (typ != "integer" || v != math.Trunc(v)) {
+			return []string{fmt.Sprintf("%s: expected %s, got %v", path, typ, v)}
+		}
+	}
+	return nil
+}
+
+// objectSchema returns the properties, required properties and type of |s|,
+// including those of the schemas in its allOf. The properties are nil if the
+// schema does not list any, in which case any are allowed.
+func (doc *apiDocument) objectSchema(s *apiSchema) (map[string]*apiSchema, []string, string) {
+	properties, required, typ := s.Properties, s.Required, s.Type
+	for _, sub := range s.AllOf {
+		p, r, t := doc.objectSchema(doc.resolve(sub))
+		merged := make(map[string]*apiSchema, len(properties)+len(p))
+		for name, ps := range p {
+			merged[name] = ps
+		}
+		for name, ps := range properties {
+			merged[name] = ps
+		}
+		properties = merged
+		required = append(required, r...)
+		if typ == "" {
+			typ = t
+		}
+	}
+	return properties, required, typ
+}
+
+func (doc *apiDocument) resolve(s *apiSchema) *apiSchema {
+	for s.Ref != "" {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbgraphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// A request is the body of a POST request, or the parameters of a GET request.
+type request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// NewHandler creates a HTTP handler that runs the GraphQL queries of GET
+// requests, with the query, variables and operationName parameters, and of
+// POST requests, with a JSON body of the same fields. The response is the JSON
+// result, with any errors. NewHandler panics if the schema is invalid, which is
+// a bug in NewSchema.
+func NewHandler(db ndb.Reader) http.Handler {
+	schema, err := NewSchema(db)
+	if err != nil {
+		panic(err.Error())
+	}
+	return &handler{schema: schema}
+}
+
+type handler struct {
+	schema graphql.Schema
+}
+
+func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var r request
+	switch req.Method {
+	case "GET", "HEAD":
+		r.Query = req.FormValue("query")
+		r.OperationName = req.FormValue("operationName")
+		if v := req.FormValue("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &r.Variables); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(rw, "Error: Invalid variables: %v", err)
+				return
+			}
+		}
+	case "POST":
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(rw, "Error: Invalid request: %v", err)
+			return
+		}
+	default:
+		rw.Header().Set("Allow", "GET, HEAD, POST")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(rw, "Error: Method %s not allowed", req.Method)
+		return
+	}
+	if r.Query == "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(rw, "Error: No query")
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  r.Query,
+		VariableValues: r.Variables,
+		OperationName:  r.OperationName,
+		Context:        req.Context(),
+	})
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(result); err != nil {
+		panic(err.Error())
+	}
+}
@@ -0,0 +1,79 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbgraphql
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewHandler(db))
+	defer server.Close()
+
+	const q = `query Food($id: String!) { food(ndbId: $id) { shortDescription } }`
+	const expected = `{"data":{"food":{"shortDescription":"BUTTER,WITH SALT"}}}` + "\n"
+
+	get := url.Values{"query": {q}, "variables": {`{"id": "01001"}`}}
+	post := `{"query": "` + strings.ReplaceAll(q, `"`, `\"`) + `", "variables": {"id": "01001"}}`
+	for _, test := range []struct {
+		method string
+		url    string
+		body   string
+		status int
+		resp   string
+	}{
+		{"GET", "?" + get.Encode(), "", http.StatusOK, expected},
+		{"POST", "", post, http.StatusOK, expected},
+		{"GET", "?query=%7B+food+%7D", "", http.StatusOK, ""},
+		{"GET", "", "", http.StatusBadRequest, ""},
+		{"GET", "?query=x&variables=%7B", "", http.StatusBadRequest, ""},
+		{"POST", "", "{", http.StatusBadRequest, ""},
+		{"PUT", "", post, http.StatusMethodNotAllowed, ""},
+	} {
+		req, err := http.NewRequest(test.method, server.URL+test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %q: expected status %d, got %d: %s", test.method, test.url, test.status, resp.StatusCode, body)
+		}
+		if test.resp != "" && string(body) != test.resp {
+			t.Errorf("%s %q: expected %s, got %s", test.method, test.url, test.resp, body)
+		}
+	}
+}
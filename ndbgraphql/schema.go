@@ -0,0 +1,383 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ndbgraphql serves the nutrient database as a GraphQL API, so that
+// clients choose the fields, nutrients and measures of the foods they get.
+package ndbgraphql
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+// A nutrientValue is the source of a FoodNutrient: the value of a nutrient of a
+// food, scaled to a measure.
+type nutrientValue struct {
+	Nutrient   ndb.Nutrient
+	Value      float32
+	DataPoints int
+	Source     string
+	Per        string
+}
+
+// A footnote is the source of a Footnote, with the weight or nutrient that it
+// describes.
+type footnote struct {
+	Number   int
+	Type     string
+	Text     string
+	Nutrient *ndb.Nutrient
+	Weight   *ndb.Weight
+}
+
+type schema struct {
+	db ndb.Reader
+	// The definitions do not change after loading, unlike the foods.
+	nutrients map[int]ndb.Nutrient
+	groups    map[int]ndb.FoodGroup
+}
+
+// NewSchema creates the GraphQL schema for |db|, whose queries are:
+//
+//	food(ndbId: String!): Food
+//	upc(gtin: String!): Food
+//	search(query: String!, limit: Int): [SearchResult!]!
+//	foodGroups: [FoodGroup!]!
+//	nutrients: [Nutrient!]!
+//
+// A Food resolves its food group, nutrients, weights and footnotes. Its field
+// nutrients(ids: [Int!], per: String) has the values of the nutrients in |ids|,
+// or of all of them, per 100 g or per the first of the food's weights whose
+// description contains |per|, like "cup". It is null, with an error, if the
+// food has no such weight.
+func NewSchema(db ndb.Reader) (graphql.Schema, error) {
+	s := &schema{
+		db:        db,
+		nutrients: make(map[int]ndb.Nutrient, len(db.AllNutrients())),
+		groups:    make(map[int]ndb.FoodGroup, len(db.AllFoodGroups())),
+	}
+	for _, n := range db.AllNutrients() {
+		s.nutrients[n.NutrientID] = n
+	}
+	for _, g := range db.AllFoodGroups() {
+		s.groups[g.GroupCode] = g
+	}
+
+	// The fields without a Resolve are the struct fields of the same name,
+	// ignoring case.
+	nonNull := graphql.NewNonNull
+	listOf := func(t graphql.Type) graphql.Type {
+		return nonNull(graphql.NewList(nonNull(t)))
+	}
+
+	nutrientType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Nutrient",
+		Description: "A macro or micronutrient that is measured for foods.",
+		Fields: graphql.Fields{
+			"nutrientId":  &graphql.Field{Type: nonNull(graphql.Int)},
+			"units":       &graphql.Field{Type: nonNull(graphql.String)},
+			"description": &graphql.Field{Type: nonNull(graphql.String)},
+			"sortOrder":   &graphql.Field{Type: nonNull(graphql.Int)},
+		},
+	})
+
+	foodGroupType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FoodGroup",
+		Fields: graphql.Fields{
+			"groupCode":   &graphql.Field{Type: nonNull(graphql.Int)},
+			"description": &graphql.Field{Type: nonNull(graphql.String)},
+		},
+	})
+
+	weightType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Weight",
+		Description: "A common household measure of a food.",
+		Fields: graphql.Fields{
+			"sequence":    &graphql.Field{Type: nonNull(graphql.Int)},
+			"amount":      &graphql.Field{Type: nonNull(graphql.Float)},
+			"description": &graphql.Field{Type: nonNull(graphql.String)},
+			"weightG":     &graphql.Field{Type: nonNull(graphql.Float), Description: "The weight in grams of the measure."},
+		},
+	})
+
+	foodNutrientType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "FoodNutrient",
+		Description: "The value of a nutrient of a food.",
+		Fields: graphql.Fields{
+			"nutrient":   &graphql.Field{Type: nonNull(nutrientType)},
+			"value":      &graphql.Field{Type: nonNull(graphql.Float), Description: "The amount of the nutrient in the measure, in its units."},
+			"per":        &graphql.Field{Type: nonNull(graphql.String), Description: `The measure of the value, e.g. "100 g" or "1 cup".`},
+			"dataPoints": &graphql.Field{Type: nonNull(graphql.Int)},
+			"source":     &graphql.Field{Type: nonNull(graphql.String), Description: "The overlay that set the value, or empty for the USDA data."},
+		},
+	})
+
+	footnoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Footnote",
+		Description: "A comment on a food, one of its weights or one of its nutrient values.",
+		Fields: graphql.Fields{
+			"number":   &graphql.Field{Type: nonNull(graphql.Int)},
+			"type":     &graphql.Field{Type: nonNull(graphql.String), Description: `"D" for the food description, "M" for a measure or "N" for a nutrient.`},
+			"text":     &graphql.Field{Type: nonNull(graphql.String)},
+			"nutrient": &graphql.Field{Type: nutrientType, Description: "The nutrient of an N footnote."},
+			"weight":   &graphql.Field{Type: weightType, Description: "The weight of an M footnote."},
+		},
+	})
+
+	brandedType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "BrandedFood",
+		Description: "The label information of a manufacturer's product.",
+		Fields: graphql.Fields{
+			"gtin":             &graphql.Field{Type: nonNull(graphql.String)},
+			"brandOwner":       &graphql.Field{Type: nonNull(graphql.String)},
+			"brandName":        &graphql.Field{Type: nonNull(graphql.String)},
+			"servingSize":      &graphql.Field{Type: nonNull(graphql.Float)},
+			"servingSizeUnit":  &graphql.Field{Type: nonNull(graphql.String)},
+			"householdServing": &graphql.Field{Type: nonNull(graphql.String)},
+			"category":         &graphql.Field{Type: nonNull(graphql.String)},
+		},
+	})
+
+	foodType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Food",
+		Fields: graphql.Fields{
+			"ndbId":             &graphql.Field{Type: nonNull(graphql.String)},
+			"fdcId":             &graphql.Field{Type: nonNull(graphql.Int)},
+			"longDescription":   &graphql.Field{Type: nonNull(graphql.String)},
+			"shortDescription":  &graphql.Field{Type: nonNull(graphql.String)},
+			"commonNames":       &graphql.Field{Type: nonNull(graphql.String)},
+			"scientificName":    &graphql.Field{Type: nonNull(graphql.String)},
+			"manufacturer":      &graphql.Field{Type: nonNull(graphql.String)},
+			"ingredients":       &graphql.Field{Type: nonNull(graphql.String)},
+			"refuseDescription": &graphql.Field{Type: nonNull(graphql.String)},
+			"refuse":            &graphql.Field{Type: nonNull(graphql.Int)},
+			"source":            &graphql.Field{Type: nonNull(graphql.String)},
+			"branded":           &graphql.Field{Type: brandedType},
+			"foodGroup": &graphql.Field{
+				Type: foodGroupType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.foodGroup(p.Source.(*ndb.Food).FoodGroup), nil
+				},
+			},
+			"nutrients": &graphql.Field{
+				Type: graphql.NewList(nonNull(foodNutrientType)),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{
+						Type:        graphql.NewList(nonNull(graphql.Int)),
+						Description: "The nutrients to return, in order. Defaults to all of the food's nutrients.",
+					},
+					"per": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "A measure of the food, like \"cup\". Defaults to 100 g.",
+					},
+				},
+				Resolve: s.foodNutrients,
+			},
+			"weights": &graphql.Field{Type: listOf(weightType)},
+			"footnotes": &graphql.Field{
+				Type: listOf(footnoteType),
+				Args: graphql.FieldConfigArgument{
+					"type": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Only return the footnotes of this type.",
+					},
+				},
+				Resolve: s.footnotes,
+			},
+		},
+	})
+
+	searchResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SearchResult",
+		Description: "A food that matches a search.",
+		Fields: graphql.Fields{
+			"ndbId":        &graphql.Field{Type: nonNull(graphql.String)},
+			"description":  &graphql.Field{Type: nonNull(graphql.String)},
+			"manufacturer": &graphql.Field{Type: nonNull(graphql.String)},
+			"score":        &graphql.Field{Type: nonNull(graphql.Int), Description: "The number of words of the query that match the food."},
+			"foodGroup": &graphql.Field{
+				Type: foodGroupType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.foodGroup(p.Source.(ndb.SearchResult).FoodGroup), nil
+				},
+			},
+			"food": &graphql.Field{
+				Type: foodType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.food(p.Source.(ndb.SearchResult).NDBID), nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"food": &graphql.Field{
+				Type: foodType,
+				Args: graphql.FieldConfigArgument{
+					"ndbId": &graphql.ArgumentConfig{Type: nonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.food(p.Args["ndbId"].(string)), nil
+				},
+			},
+			"upc": &graphql.Field{
+				Type: foodType,
+				Args: graphql.FieldConfigArgument{
+					"gtin": &graphql.ArgumentConfig{Type: nonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					food, _ := s.db.FindFoodByGTIN(p.Args["gtin"].(string))
+					return food, nil
+				},
+			},
+			"search": &graphql.Field{
+				Type: listOf(searchResultType),
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{Type: nonNull(graphql.String)},
+					"limit": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "The most results to return. Defaults to all of them.",
+					},
+				},
+				Resolve: s.search,
+			},
+			"foodGroups": &graphql.Field{
+				Type: listOf(foodGroupType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.db.AllFoodGroups(), nil
+				},
+			},
+			"nutrients": &graphql.Field{
+				Type: listOf(nutrientType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.db.AllNutrients(), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// food returns the food with NDBID |id|, or nil if there is none, which
+// resolves to null.
+func (s *schema) food(id string) *ndb.Food {
+	food, _ := s.db.Food(id)
+	return food
+}
+
+// foodGroup returns the FoodGroup with |code|, or nil if there is none.
+func (s *schema) foodGroup(code int) interface{} {
+	if g, ok := s.groups[code]; ok {
+		return g
+	}
+	return nil
+}
+
+func (s *schema) search(p graphql.ResolveParams) (interface{}, error) {
+	results := s.db.Search(p.Args["query"].(string))
+	if limit, ok := p.Args["limit"].(int); ok {
+		if limit < 0 {
+			return nil, fmt.Errorf("negative limit %d", limit)
+		}
+		if len(results) > limit {
+			results = results[:limit]
+		}
+	}
+	return results, nil
+}
+
+func (s *schema) foodNutrients(p graphql.ResolveParams) (interface{}, error) {
+	food := p.Source.(*ndb.Food)
+
+	grams, per := float32(100), "100 g"
+	if measure, ok := p.Args["per"].(string); ok {
+		w := food.FindWeight(measure)
+		if w == nil {
+			return nil, fmt.Errorf("food %s has no measure %q", food.NDBID, measure)
+		}
+		grams, per = w.WeightG, fmt.Sprintf("%g %s", w.Amount, w.Description)
+	}
+
+	selected := food.Nutrients
+	if ids, ok := p.Args["ids"].([]interface{}); ok {
+		selected = make([]ndb.FoodNutrient, 0, len(ids))
+		for _, id := range ids {
+			id := id.(int)
+			if _, ok := s.nutrients[id]; !ok {
+				return nil, fmt.Errorf("unknown nutrient %d", id)
+			}
+			for _, fn := range food.Nutrients {
+				if fn.NutrientID == id {
+					selected = append(selected, fn)
+					break
+				}
+			}
+		}
+	}
+
+	values := make([]nutrientValue, 0, len(selected))
+	for _, fn := range selected {
+		n, ok := s.nutrients[fn.NutrientID]
+		if !ok {
+			// A value for a nutrient that is not defined has no units.
+			continue
+		}
+		values = append(values, nutrientValue{
+			Nutrient: n,
+			// N = (V*W) / 100, see ndb.Weight.
+			Value:      float32(float64(fn.Value) * float64(grams) / 100),
+			DataPoints: fn.DataPoints,
+			Source:     fn.Source,
+			Per:        per,
+		})
+	}
+	return values, nil
+}
+
+func (s *schema) footnotes(p graphql.ResolveParams) (interface{}, error) {
+	food := p.Source.(*ndb.Food)
+	typ, filter := p.Args["type"].(string)
+
+	footnotes := make([]footnote, 0, len(food.Footnotes))
+	for _, fn := range food.Footnotes {
+		if filter && fn.Type != typ {
+			continue
+		}
+		f := footnote{Number: fn.Number, Type: fn.Type, Text: fn.Text}
+		switch fn.Type {
+		case ndb.FootnoteNutrient:
+			if n, ok := s.nutrients[fn.NutrientID]; ok {
+				f.Nutrient = &n
+			}
+		case ndb.FootnoteMeasure:
+			for i := range food.Weights {
+				if food.Weights[i].Sequence == fn.Number {
+					f.Weight = &food.Weights[i]
+					break
+				}
+			}
+		}
+		footnotes = append(footnotes, f)
+	}
+	return footnotes, nil
+}
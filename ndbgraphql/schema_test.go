@@ -0,0 +1,161 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbgraphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func testSchema(t *testing.T) graphql.Schema {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := NewSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// query runs |q| and returns the JSON of its data, failing on any error.
+func query(t *testing.T, schema graphql.Schema, q string) string {
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: q})
+	if result.HasErrors() {
+		t.Fatalf("%s: %v", q, result.Errors)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// checkJSON compares the JSON |actual| to |expected|, ignoring formatting.
+func checkJSON(t *testing.T, expected, actual string) {
+	t.Helper()
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestFood(t *testing.T) {
+	schema := testSchema(t)
+
+	actual := query(t, schema, `{
+		food(ndbId: "01001") {
+			ndbId
+			longDescription
+			foodGroup { groupCode description }
+			nutrients(ids: [204, 203], per: "cup") {
+				nutrient { nutrientId description units }
+				value
+				per
+			}
+			weights { sequence description weightG }
+			footnotes { number type text weight { description } }
+		}
+	}`)
+	checkJSON(t, `{"food": {
+		"ndbId": "01001",
+		"longDescription": "Butter, salted",
+		"foodGroup": {"groupCode": 100, "description": "Dairy and Egg Products"},
+		"nutrients": [
+			{"nutrient": {"nutrientId": 204, "description": "Total lipid (fat)", "units": "g"}, "value": 184.1197, "per": "1 cup"},
+			{"nutrient": {"nutrientId": 203, "description": "Protein", "units": "g"}, "value": 1.9295001, "per": "1 cup"}
+		],
+		"weights": [
+			{"sequence": 1, "description": "cup", "weightG": 227},
+			{"sequence": 2, "description": "tbsp", "weightG": 14.2},
+			{"sequence": 3, "description": "pat (1\" sq, 1/3\" high)", "weightG": 5},
+			{"sequence": 4, "description": "stick", "weightG": 113}
+		],
+		"footnotes": [
+			{"number": 1, "type": "D", "text": "Salted with 1.5% to 2% salt", "weight": null},
+			{"number": 2, "type": "M", "text": "Measured from stick butter", "weight": {"description": "tbsp"}}
+		]
+	}}`, actual)
+
+	actual = query(t, schema, `{
+		food(ndbId: "11090") {
+			nutrients { nutrient { nutrientId } value per }
+			footnotes(type: "N") { text nutrient { description } }
+		}
+	}`)
+	checkJSON(t, `{"food": {
+		"nutrients": [
+			{"nutrient": {"nutrientId": 203}, "value": 2.82, "per": "100 g"},
+			{"nutrient": {"nutrientId": 208}, "value": 34, "per": "100 g"}
+		],
+		"footnotes": [{"text": "Calculated from the proximates", "nutrient": {"description": "Energy"}}]
+	}}`, actual)
+
+	checkJSON(t, `{"food": null}`, query(t, schema, `{ food(ndbId: "00000") { ndbId } }`))
+}
+
+func TestFoodErrors(t *testing.T) {
+	schema := testSchema(t)
+	for _, q := range []string{
+		`{ food(ndbId: "01001") { nutrients(per: "bushel") { value } } }`,
+		`{ food(ndbId: "01001") { nutrients(ids: [999]) { value } } }`,
+		`{ search(query: "butter", limit: -1) { ndbId } }`,
+		`{ food { ndbId } }`,
+		`{ food(ndbId: "01001") { color } }`,
+	} {
+		if result := graphql.Do(graphql.Params{Schema: schema, RequestString: q}); !result.HasErrors() {
+			t.Errorf("Expected an error for %s, got %v", q, result.Data)
+		}
+	}
+}
+
+func TestSearchAndLists(t *testing.T) {
+	schema := testSchema(t)
+
+	actual := query(t, schema, `{
+		search(query: "cheese blue", limit: 1) {
+			ndbId score foodGroup { groupCode } food { shortDescription }
+		}
+	}`)
+	checkJSON(t, `{"search": [
+		{"ndbId": "01004", "score": 2, "foodGroup": {"groupCode": 100}, "food": {"shortDescription": "CHEESE,BLUE"}}
+	]}`, actual)
+
+	actual = query(t, schema, `{ foodGroups { groupCode } nutrients { nutrientId units } }`)
+	checkJSON(t, `{
+		"foodGroups": [{"groupCode": 100}, {"groupCode": 1100}, {"groupCode": 1900}],
+		"nutrients": [
+			{"nutrientId": 203, "units": "g"},
+			{"nutrientId": 204, "units": "g"},
+			{"nutrientId": 208, "units": "kcal"},
+			{"nutrientId": 320, "units": "µg"}
+		]
+	}`, actual)
+}
@@ -0,0 +1,115 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ndbgrpc serves the nutrient database with the NutrientDB gRPC service
+// of github.com/rsesek/usda-ndb/ndbpb.
+package ndbgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbpb"
+)
+
+// NewServer creates a NutrientDB service for |db|, which may be shared with the
+// HTTP frontend.
+func NewServer(db ndb.Reader) ndbpb.NutrientDBServer {
+	return &server{db: db}
+}
+
+// Register adds a NutrientDB service for |db| to |s|.
+func Register(s *grpc.Server, db ndb.Reader) {
+	ndbpb.RegisterNutrientDBServer(s, NewServer(db))
+}
+
+type server struct {
+	ndbpb.UnimplementedNutrientDBServer
+	db ndb.Reader
+}
+
+func (s *server) GetFood(ctx context.Context, req *ndbpb.GetFoodRequest) (*ndbpb.Food, error) {
+	if req.GetNdbId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "GetFood: no ndb_id")
+	}
+	food, ok := s.db.Food(req.GetNdbId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "GetFood: could not find food with id %s", req.GetNdbId())
+	}
+	return ndbpb.FromFood(food), nil
+}
+
+func (s *server) Search(ctx context.Context, req *ndbpb.SearchRequest) (*ndbpb.SearchResponse, error) {
+	if req.GetLimit() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Search: negative limit %d", req.GetLimit())
+	}
+	results := s.db.Search(req.GetQuery())
+	if limit := int(req.GetLimit()); limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	resp := &ndbpb.SearchResponse{}
+	for _, r := range results {
+		resp.Results = append(resp.Results, &ndbpb.SearchResult{
+			NdbId:        r.NDBID,
+			FoodGroup:    int32(r.FoodGroup),
+			Description:  r.Description,
+			Manufacturer: r.Manufacturer,
+			Score:        int32(r.Score),
+		})
+	}
+	return resp, nil
+}
+
+func (s *server) ListFoodGroups(ctx context.Context, req *ndbpb.ListFoodGroupsRequest) (*ndbpb.ListFoodGroupsResponse, error) {
+	resp := &ndbpb.ListFoodGroupsResponse{}
+	for _, g := range s.db.AllFoodGroups() {
+		resp.FoodGroups = append(resp.FoodGroups, ndbpb.FromFoodGroup(g))
+	}
+	return resp, nil
+}
+
+func (s *server) ListNutrients(ctx context.Context, req *ndbpb.ListNutrientsRequest) (*ndbpb.ListNutrientsResponse, error) {
+	resp := &ndbpb.ListNutrientsResponse{}
+	for _, n := range s.db.AllNutrients() {
+		resp.Nutrients = append(resp.Nutrients, ndbpb.FromNutrient(n))
+	}
+	return resp, nil
+}
+
+// ListFoods sends the foods sorted by NDBID, stopping early if the client goes
+// away.
+func (s *server) ListFoods(req *ndbpb.ListFoodsRequest, stream ndbpb.NutrientDB_ListFoodsServer) error {
+	group := int(req.GetFoodGroup())
+	for _, id := range s.db.FoodIDs() {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		// The food may have been deleted since listing the IDs.
+		food, ok := s.db.Food(id)
+		if !ok || (group != 0 && food.FoodGroup != group) {
+			continue
+		}
+		if err := stream.Send(ndbpb.FromFood(food)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbgrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rsesek/usda-ndb/ndb"
+	"github.com/rsesek/usda-ndb/ndbpb"
+)
+
+// newTestClient serves the test database on an in-process listener, and
+// returns it with a client connected to it.
+func newTestClient(t *testing.T) (*ndb.ASCIIDB, ndbpb.NutrientDBClient) {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	Register(s, db)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return db, ndbpb.NewNutrientDBClient(conn)
+}
+
+func TestGetFood(t *testing.T) {
+	db, client := newTestClient(t)
+	ctx := context.Background()
+
+	food, err := client.GetFood(ctx, &ndbpb.GetFoodRequest{NdbId: "01001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := db.Food("01001")
+	if !proto.Equal(ndbpb.FromFood(expected), food) {
+		t.Errorf("Expected %v, got %v", ndbpb.FromFood(expected), food)
+	}
+
+	for _, test := range []struct {
+		id   string
+		code codes.Code
+	}{
+		{"00000", codes.NotFound},
+		{"", codes.InvalidArgument},
+	} {
+		_, err := client.GetFood(ctx, &ndbpb.GetFoodRequest{NdbId: test.id})
+		if status.Code(err) != test.code {
+			t.Errorf("Expected %v for %q, got %v", test.code, test.id, err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Search(ctx, &ndbpb.SearchRequest{Query: "cheese butter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range resp.Results {
+		ids = append(ids, r.NdbId)
+	}
+	if len(ids) != 2 || ids[0] != "01001" && ids[0] != "01004" {
+		t.Errorf("Expected butter and cheese, got %v", ids)
+	}
+	if r := resp.Results[0]; r.FoodGroup != 100 || r.Score != 1 || r.Description == "" {
+		t.Errorf("Unexpected result %v", r)
+	}
+
+	resp, err = client.Search(ctx, &ndbpb.SearchRequest{Query: "cheese butter", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].NdbId != ids[0] {
+		t.Errorf("Expected the first result %s, got %v", ids[0], resp.Results)
+	}
+
+	_, err = client.Search(ctx, &ndbpb.SearchRequest{Query: "cheese", Limit: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a negative limit, got %v", err)
+	}
+}
+
+func TestListFoodGroupsAndNutrients(t *testing.T) {
+	db, client := newTestClient(t)
+	ctx := context.Background()
+
+	groups, err := client.ListFoodGroups(ctx, &ndbpb.ListFoodGroupsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups.FoodGroups) != len(db.FoodGroups) {
+		t.Fatalf("Expected %d food groups, got %d", len(db.FoodGroups), len(groups.FoodGroups))
+	}
+	for i, g := range groups.FoodGroups {
+		if ndbpb.ToFoodGroup(g) != db.FoodGroups[i] {
+			t.Errorf("Expected %v, got %v", db.FoodGroups[i], g)
+		}
+	}
+
+	nutrients, err := client.ListNutrients(ctx, &ndbpb.ListNutrientsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nutrients.Nutrients) != len(db.Nutrients) {
+		t.Fatalf("Expected %d nutrients, got %d", len(db.Nutrients), len(nutrients.Nutrients))
+	}
+	for i, n := range nutrients.Nutrients {
+		if ndbpb.ToNutrient(n) != db.Nutrients[i] {
+			t.Errorf("Expected %v, got %v", db.Nutrients[i], n)
+		}
+	}
+}
+
+// listFoods returns the NDBIDs streamed by ListFoods.
+func listFoods(t *testing.T, client ndbpb.NutrientDBClient, group int32) []string {
+	stream, err := client.ListFoods(context.Background(), &ndbpb.ListFoodsRequest{FoodGroup: group})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for {
+		food, err := stream.Recv()
+		if err == io.EOF {
+			return ids
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, food.NdbId)
+	}
+}
+
+func TestListFoods(t *testing.T) {
+	db, client := newTestClient(t)
+
+	all := listFoods(t, client, 0)
+	expected := db.FoodIDs()
+	if len(all) != len(expected) {
+		t.Fatalf("Expected foods %v, got %v", expected, all)
+	}
+	for i := range all {
+		if all[i] != expected[i] {
+			t.Errorf("Expected foods %v, got %v", expected, all)
+			break
+		}
+	}
+
+	dairy := listFoods(t, client, 100)
+	if len(dairy) != 2 || dairy[0] != "01001" || dairy[1] != "01004" {
+		t.Errorf("Expected the dairy foods, got %v", dairy)
+	}
+	if none := listFoods(t, client, 9999); len(none) != 0 {
+		t.Errorf("Expected no foods, got %v", none)
+	}
+}
+
+// canceledStream is a ListFoods stream whose client has gone away.
+type canceledStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *canceledStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *canceledStream) Send(*ndbpb.Food) error {
+	s.sent++
+	return nil
+}
+
+func TestListFoodsCanceled(t *testing.T) {
+	db, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream := &canceledStream{ctx: ctx}
+	err = NewServer(db).ListFoods(&ndbpb.ListFoodsRequest{}, stream)
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Expected Canceled, got %v", err)
+	}
+	if stream.sent != 0 {
+		t.Errorf("Expected no foods sent, got %d", stream.sent)
+	}
+}
@@ -0,0 +1,282 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package ndbpb has the Protocol Buffers messages for the nutrient database, and
+// converts them to and from the types of github.com/rsesek/usda-ndb/ndb.
+package ndbpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ndb.proto nutrientdb.proto
+
+import (
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func FromNutrient(n ndb.Nutrient) *Nutrient {
+	return &Nutrient{
+		NutrientId:  int32(n.NutrientID),
+		Units:       n.Units,
+		Description: n.Description,
+		SortOrder:   int32(n.SortOrder),
+		Tagname:     n.Tagname,
+		Decimals:    int32(n.Decimals),
+	}
+}
+
+func ToNutrient(n *Nutrient) ndb.Nutrient {
+	return ndb.Nutrient{
+		NutrientID:  int(n.GetNutrientId()),
+		Units:       n.GetUnits(),
+		Description: n.GetDescription(),
+		SortOrder:   int(n.GetSortOrder()),
+		Tagname:     n.GetTagname(),
+		Decimals:    int(n.GetDecimals()),
+	}
+}
+
+func FromFoodGroup(g ndb.FoodGroup) *FoodGroup {
+	return &FoodGroup{GroupCode: int32(g.GroupCode), Description: g.Description}
+}
+
+func ToFoodGroup(g *FoodGroup) ndb.FoodGroup {
+	return ndb.FoodGroup{GroupCode: int(g.GetGroupCode()), Description: g.GetDescription()}
+}
+
+func FromFood(f *ndb.Food) *Food {
+	food := &Food{
+		NdbId:              f.NDBID,
+		FdcId:              int32(f.FDCID),
+		FoodGroup:          int32(f.FoodGroup),
+		LongDescription:    f.LongDescription,
+		ShortDescription:   f.ShortDescription,
+		CommonNames:        f.CommonNames,
+		ScientificName:     f.ScientificName,
+		Manufacturer:       f.Manufacturer,
+		Ingredients:        f.Ingredients,
+		RefuseDescription:  f.RefuseDescription,
+		Refuse:             int32(f.Refuse),
+		Source:             f.Source,
+		Survey:             f.Survey,
+		NitrogenFactor:     copyFloat(f.NitrogenFactor),
+		ProteinFactor:      copyFloat(f.ProteinFactor),
+		FatFactor:          copyFloat(f.FatFactor),
+		CarbohydrateFactor: copyFloat(f.CarbohydrateFactor),
+	}
+	for _, n := range f.Nutrients {
+		food.Nutrients = append(food.Nutrients, &FoodNutrient{
+			NutrientId: int32(n.NutrientID),
+			Value:      n.Value,
+			DataPoints: int32(n.DataPoints),
+			Source:     n.Source,
+			Stats:      fromStats(n.Stats),
+		})
+	}
+	for _, w := range f.Weights {
+		food.Weights = append(food.Weights, &Weight{
+			Sequence:    int32(w.Sequence),
+			Amount:      w.Amount,
+			Description: w.Description,
+			WeightG:     w.WeightG,
+			DataPoints:  fromInt(w.DataPoints),
+			StdDev:      copyFloat(w.StdDev),
+		})
+	}
+	if b := f.Branded; b != nil {
+		food.Branded = &BrandedFood{
+			Gtin:             b.GTIN,
+			BrandOwner:       b.BrandOwner,
+			BrandName:        b.BrandName,
+			ServingSize:      b.ServingSize,
+			ServingSizeUnit:  b.ServingSizeUnit,
+			HouseholdServing: b.HouseholdServing,
+			Category:         b.Category,
+		}
+	}
+	for _, fn := range f.Footnotes {
+		food.Footnotes = append(food.Footnotes, &Footnote{
+			Number:     int32(fn.Number),
+			Type:       fn.Type,
+			NutrientId: int32(fn.NutrientID),
+			Text:       fn.Text,
+		})
+	}
+	return food
+}
+
+func ToFood(f *Food) *ndb.Food {
+	food := &ndb.Food{
+		NDBID:              f.GetNdbId(),
+		FDCID:              int(f.GetFdcId()),
+		FoodGroup:          int(f.GetFoodGroup()),
+		LongDescription:    f.GetLongDescription(),
+		ShortDescription:   f.GetShortDescription(),
+		CommonNames:        f.GetCommonNames(),
+		ScientificName:     f.GetScientificName(),
+		Manufacturer:       f.GetManufacturer(),
+		Ingredients:        f.GetIngredients(),
+		RefuseDescription:  f.GetRefuseDescription(),
+		Refuse:             int(f.GetRefuse()),
+		Source:             f.GetSource(),
+		Survey:             f.GetSurvey(),
+		NitrogenFactor:     copyFloat(f.NitrogenFactor),
+		ProteinFactor:      copyFloat(f.ProteinFactor),
+		FatFactor:          copyFloat(f.FatFactor),
+		CarbohydrateFactor: copyFloat(f.CarbohydrateFactor),
+	}
+	for _, n := range f.GetNutrients() {
+		food.Nutrients = append(food.Nutrients, ndb.FoodNutrient{
+			NutrientID: int(n.GetNutrientId()),
+			Value:      n.GetValue(),
+			DataPoints: int(n.GetDataPoints()),
+			Source:     n.GetSource(),
+			Stats:      toStats(n.GetStats()),
+		})
+	}
+	for _, w := range f.GetWeights() {
+		food.Weights = append(food.Weights, ndb.Weight{
+			Sequence:    int(w.GetSequence()),
+			Amount:      w.GetAmount(),
+			Description: w.GetDescription(),
+			WeightG:     w.GetWeightG(),
+			DataPoints:  toInt(w.DataPoints),
+			StdDev:      copyFloat(w.StdDev),
+		})
+	}
+	if b := f.GetBranded(); b != nil {
+		food.Branded = &ndb.BrandedFood{
+			GTIN:             b.GetGtin(),
+			BrandOwner:       b.GetBrandOwner(),
+			BrandName:        b.GetBrandName(),
+			ServingSize:      b.GetServingSize(),
+			ServingSizeUnit:  b.GetServingSizeUnit(),
+			HouseholdServing: b.GetHouseholdServing(),
+			Category:         b.GetCategory(),
+		}
+	}
+	for _, fn := range f.GetFootnotes() {
+		food.Footnotes = append(food.Footnotes, ndb.Footnote{
+			Number:     int(fn.GetNumber()),
+			Type:       fn.GetType(),
+			NutrientID: int(fn.GetNutrientId()),
+			Text:       fn.GetText(),
+		})
+	}
+	return food
+}
+
+func fromStats(st *ndb.NutrientStats) *NutrientStats {
+	if st == nil {
+		return nil
+	}
+	return &NutrientStats{
+		StdError:         copyFloat(st.StdError),
+		SourceCode:       st.SourceCode,
+		DerivationCode:   st.DerivationCode,
+		RefNdbId:         st.RefNDBID,
+		Added:            st.Added,
+		Studies:          fromInt(st.Studies),
+		Min:              copyFloat(st.Min),
+		Max:              copyFloat(st.Max),
+		DegreesOfFreedom: fromInt(st.DegreesOfFreedom),
+		LowerErrorBound:  copyFloat(st.LowerErrorBound),
+		UpperErrorBound:  copyFloat(st.UpperErrorBound),
+		Comments:         st.Comments,
+		Modified:         st.Modified,
+		ConfidenceCode:   st.ConfidenceCode,
+	}
+}
+
+func toStats(st *NutrientStats) *ndb.NutrientStats {
+	if st == nil {
+		return nil
+	}
+	return &ndb.NutrientStats{
+		StdError:         copyFloat(st.StdError),
+		SourceCode:       st.GetSourceCode(),
+		DerivationCode:   st.GetDerivationCode(),
+		RefNDBID:         st.GetRefNdbId(),
+		Added:            st.GetAdded(),
+		Studies:          toInt(st.Studies),
+		Min:              copyFloat(st.Min),
+		Max:              copyFloat(st.Max),
+		DegreesOfFreedom: toInt(st.DegreesOfFreedom),
+		LowerErrorBound:  copyFloat(st.LowerErrorBound),
+		UpperErrorBound:  copyFloat(st.UpperErrorBound),
+		Comments:         st.GetComments(),
+		Modified:         st.GetModified(),
+		ConfidenceCode:   st.GetConfidenceCode(),
+	}
+}
+
+// The optional numbers are copied, so that the message and the ndb type do not
+// share them.
+
+func copyFloat(f *float32) *float32 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
+
+func fromInt(i *int) *int32 {
+	if i == nil {
+		return nil
+	}
+	v := int32(*i)
+	return &v
+}
+
+func toInt(i *int32) *int {
+	if i == nil {
+		return nil
+	}
+	v := int(*i)
+	return &v
+}
+
+// FromDatabase converts the tables of |db|, with the foods sorted by NDBID.
+func FromDatabase(db *ndb.ASCIIDB) *Database {
+	pb := &Database{}
+	for _, g := range db.FoodGroups {
+		pb.FoodGroups = append(pb.FoodGroups, FromFoodGroup(g))
+	}
+	for _, n := range db.Nutrients {
+		pb.Nutrients = append(pb.Nutrients, FromNutrient(n))
+	}
+	for _, id := range db.FoodIDs() {
+		if food, ok := db.Food(id); ok {
+			pb.Foods = append(pb.Foods, FromFood(food))
+		}
+	}
+	return pb
+}
+
+// ToDatabase converts |pb| to a database without a search index, like one
+// decoded from a gob. Call RebuildSearchIndex before searching it.
+func ToDatabase(pb *Database) *ndb.ASCIIDB {
+	db := &ndb.ASCIIDB{Foods: make(map[string]*ndb.Food, len(pb.GetFoods()))}
+	for _, g := range pb.GetFoodGroups() {
+		db.FoodGroups = append(db.FoodGroups, ToFoodGroup(g))
+	}
+	for _, n := range pb.GetNutrients() {
+		db.Nutrients = append(db.Nutrients, ToNutrient(n))
+	}
+	for _, f := range pb.GetFoods() {
+		db.Foods[f.GetNdbId()] = ToFood(f)
+	}
+	return db
+}
@@ -0,0 +1,70 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ndbpb
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rsesek/usda-ndb/ndb"
+)
+
+func TestDatabaseRoundTrip(t *testing.T) {
+	sr, err := ndb.ReadDatabase("../ndb/testdata/sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branded, err := ndb.ReadFDCJSON("../ndb/testdata/fdc/branded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, db := range []*ndb.ASCIIDB{sr, branded} {
+		data, err := proto.Marshal(FromDatabase(db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb := &Database{}
+		if err := proto.Unmarshal(data, pb); err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(pb.Foods); i++ {
+			if pb.Foods[i-1].NdbId >= pb.Foods[i].NdbId {
+				t.Errorf("Expected the foods sorted by NDBID, got %s before %s", pb.Foods[i-1].NdbId, pb.Foods[i].NdbId)
+			}
+		}
+
+		actual := ToDatabase(pb)
+		if !reflect.DeepEqual(db.FoodGroups, actual.FoodGroups) {
+			t.Errorf("Expected food groups %v, got %v", db.FoodGroups, actual.FoodGroups)
+		}
+		if !reflect.DeepEqual(db.Nutrients, actual.Nutrients) {
+			t.Errorf("Expected nutrients %v, got %v", db.Nutrients, actual.Nutrients)
+		}
+		if !reflect.DeepEqual(db.Foods, actual.Foods) {
+			t.Errorf("Expected foods %v, got %v", db.Foods, actual.Foods)
+		}
+
+		actual.RebuildSearchIndex()
+		if !reflect.DeepEqual(db.FindFood("cheese"), actual.FindFood("cheese")) {
+			t.Errorf("Expected the same search results, got %v", actual.FindFood("cheese"))
+		}
+	}
+}
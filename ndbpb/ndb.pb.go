@@ -0,0 +1,1149 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// The messages of the nutrient database, which mirror the types of
+// github.com/rsesek/usda-ndb/ndb.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: ndb.proto
+
+package ndbpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A Nutrient represents either a macro or micronutrient that is measured for a
+// food item in the database.
+type Nutrient struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 3-digit code that identifies the nutrient.
+	NutrientId int32 `protobuf:"varint,1,opt,name=nutrient_id,json=nutrientId,proto3" json:"nutrient_id,omitempty"`
+	// The units of measure.
+	Units       string `protobuf:"bytes,2,opt,name=units,proto3" json:"units,omitempty"`
+	Description string `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	// The order used in official reports.
+	SortOrder int32 `protobuf:"varint,4,opt,name=sort_order,json=sortOrder,proto3" json:"sort_order,omitempty"`
+	// The INFOODS tagname, e.g. PROCNT.
+	Tagname string `protobuf:"bytes,5,opt,name=tagname,proto3" json:"tagname,omitempty"`
+	// The number of decimal places the values are rounded to.
+	Decimals      int32 `protobuf:"varint,6,opt,name=decimals,proto3" json:"decimals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Nutrient) Reset() {
+	*x = Nutrient{}
+	mi := &file_ndb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Nutrient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Nutrient) ProtoMessage() {}
+
+func (x *Nutrient) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Nutrient.ProtoReflect.Descriptor instead.
+func (*Nutrient) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Nutrient) GetNutrientId() int32 {
+	if x != nil {
+		return x.NutrientId
+	}
+	return 0
+}
+
+func (x *Nutrient) GetUnits() string {
+	if x != nil {
+		return x.Units
+	}
+	return ""
+}
+
+func (x *Nutrient) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Nutrient) GetSortOrder() int32 {
+	if x != nil {
+		return x.SortOrder
+	}
+	return 0
+}
+
+func (x *Nutrient) GetTagname() string {
+	if x != nil {
+		return x.Tagname
+	}
+	return ""
+}
+
+func (x *Nutrient) GetDecimals() int32 {
+	if x != nil {
+		return x.Decimals
+	}
+	return 0
+}
+
+type FoodGroup struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 4-digit code identifying the food group.
+	GroupCode     int32  `protobuf:"varint,1,opt,name=group_code,json=groupCode,proto3" json:"group_code,omitempty"`
+	Description   string `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FoodGroup) Reset() {
+	*x = FoodGroup{}
+	mi := &file_ndb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FoodGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FoodGroup) ProtoMessage() {}
+
+func (x *FoodGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FoodGroup.ProtoReflect.Descriptor instead.
+func (*FoodGroup) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FoodGroup) GetGroupCode() int32 {
+	if x != nil {
+		return x.GroupCode
+	}
+	return 0
+}
+
+func (x *FoodGroup) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+// A Food is a foodstuff whose nutritional content has been measured.
+type Food struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 5-digit identification number for the food, with leading zeros.
+	NdbId string `protobuf:"bytes,1,opt,name=ndb_id,json=ndbId,proto3" json:"ndb_id,omitempty"`
+	// FoodData Central identifier, if the food was loaded from an FDC download.
+	FdcId int32 `protobuf:"varint,2,opt,name=fdc_id,json=fdcId,proto3" json:"fdc_id,omitempty"`
+	// 4-digit code of the food group to which the food belongs.
+	FoodGroup        int32  `protobuf:"varint,3,opt,name=food_group,json=foodGroup,proto3" json:"food_group,omitempty"`
+	LongDescription  string `protobuf:"bytes,4,opt,name=long_description,json=longDescription,proto3" json:"long_description,omitempty"`
+	ShortDescription string `protobuf:"bytes,5,opt,name=short_description,json=shortDescription,proto3" json:"short_description,omitempty"`
+	// Other names used to describe the food.
+	CommonNames    string `protobuf:"bytes,6,opt,name=common_names,json=commonNames,proto3" json:"common_names,omitempty"`
+	ScientificName string `protobuf:"bytes,7,opt,name=scientific_name,json=scientificName,proto3" json:"scientific_name,omitempty"`
+	// If applicable, the manufacturer of the food.
+	Manufacturer string `protobuf:"bytes,8,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	// The ingredient statement, for manufactured foods.
+	Ingredients string `protobuf:"bytes,9,opt,name=ingredients,proto3" json:"ingredients,omitempty"`
+	// Description of the inedible parts of the food.
+	RefuseDescription string `protobuf:"bytes,10,opt,name=refuse_description,json=refuseDescription,proto3" json:"refuse_description,omitempty"`
+	// The percentage of the food that is refuse.
+	Refuse    int32           `protobuf:"varint,11,opt,name=refuse,proto3" json:"refuse,omitempty"`
+	Nutrients []*FoodNutrient `protobuf:"bytes,12,rep,name=nutrients,proto3" json:"nutrients,omitempty"`
+	// The common household weights/units.
+	Weights []*Weight `protobuf:"bytes,13,rep,name=weights,proto3" json:"weights,omitempty"`
+	// Label information, if this is a manufacturer's branded product.
+	Branded *BrandedFood `protobuf:"bytes,14,opt,name=branded,proto3" json:"branded,omitempty"`
+	// The overlay that added the food, or empty if it is from the USDA data.
+	Source string `protobuf:"bytes,15,opt,name=source,proto3" json:"source,omitempty"`
+	// Comments on the food, its weights and its nutrient values.
+	Footnotes []*Footnote `protobuf:"bytes,16,rep,name=footnotes,proto3" json:"footnotes,omitempty"`
+	// Whether the food is in the Food and Nutrient Database for Dietary Studies.
+	Survey bool `protobuf:"varint,17,opt,name=survey,proto3" json:"survey,omitempty"`
+	// The factors for calculating protein from nitrogen, and calories from
+	// protein, fat and carbohydrate, if they are known.
+	NitrogenFactor     *float32 `protobuf:"fixed32,18,opt,name=nitrogen_factor,json=nitrogenFactor,proto3,oneof" json:"nitrogen_factor,omitempty"`
+	ProteinFactor      *float32 `protobuf:"fixed32,19,opt,name=protein_factor,json=proteinFactor,proto3,oneof" json:"protein_factor,omitempty"`
+	FatFactor          *float32 `protobuf:"fixed32,20,opt,name=fat_factor,json=fatFactor,proto3,oneof" json:"fat_factor,omitempty"`
+	CarbohydrateFactor *float32 `protobuf:"fixed32,21,opt,name=carbohydrate_factor,json=carbohydrateFactor,proto3,oneof" json:"carbohydrate_factor,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Food) Reset() {
+	*x = Food{}
+	mi := &file_ndb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Food) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Food) ProtoMessage() {}
+
+func (x *Food) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Food.ProtoReflect.Descriptor instead.
+func (*Food) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Food) GetNdbId() string {
+	if x != nil {
+		return x.NdbId
+	}
+	return ""
+}
+
+func (x *Food) GetFdcId() int32 {
+	if x != nil {
+		return x.FdcId
+	}
+	return 0
+}
+
+func (x *Food) GetFoodGroup() int32 {
+	if x != nil {
+		return x.FoodGroup
+	}
+	return 0
+}
+
+func (x *Food) GetLongDescription() string {
+	if x != nil {
+		return x.LongDescription
+	}
+	return ""
+}
+
+func (x *Food) GetShortDescription() string {
+	if x != nil {
+		return x.ShortDescription
+	}
+	return ""
+}
+
+func (x *Food) GetCommonNames() string {
+	if x != nil {
+		return x.CommonNames
+	}
+	return ""
+}
+
+func (x *Food) GetScientificName() string {
+	if x != nil {
+		return x.ScientificName
+	}
+	return ""
+}
+
+func (x *Food) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *Food) GetIngredients() string {
+	if x != nil {
+		return x.Ingredients
+	}
+	return ""
+}
+
+func (x *Food) GetRefuseDescription() string {
+	if x != nil {
+		return x.RefuseDescription
+	}
+	return ""
+}
+
+func (x *Food) GetRefuse() int32 {
+	if x != nil {
+		return x.Refuse
+	}
+	return 0
+}
+
+func (x *Food) GetNutrients() []*FoodNutrient {
+	if x != nil {
+		return x.Nutrients
+	}
+	return nil
+}
+
+func (x *Food) GetWeights() []*Weight {
+	if x != nil {
+		return x.Weights
+	}
+	return nil
+}
+
+func (x *Food) GetBranded() *BrandedFood {
+	if x != nil {
+		return x.Branded
+	}
+	return nil
+}
+
+func (x *Food) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Food) GetFootnotes() []*Footnote {
+	if x != nil {
+		return x.Footnotes
+	}
+	return nil
+}
+
+func (x *Food) GetSurvey() bool {
+	if x != nil {
+		return x.Survey
+	}
+	return false
+}
+
+func (x *Food) GetNitrogenFactor() float32 {
+	if x != nil && x.NitrogenFactor != nil {
+		return *x.NitrogenFactor
+	}
+	return 0
+}
+
+func (x *Food) GetProteinFactor() float32 {
+	if x != nil && x.ProteinFactor != nil {
+		return *x.ProteinFactor
+	}
+	return 0
+}
+
+func (x *Food) GetFatFactor() float32 {
+	if x != nil && x.FatFactor != nil {
+		return *x.FatFactor
+	}
+	return 0
+}
+
+func (x *Food) GetCarbohydrateFactor() float32 {
+	if x != nil && x.CarbohydrateFactor != nil {
+		return *x.CarbohydrateFactor
+	}
+	return 0
+}
+
+// A BrandedFood is the label information for a manufacturer's product.
+type BrandedFood struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The UPC/GTIN barcode, as printed on the package.
+	Gtin       string `protobuf:"bytes,1,opt,name=gtin,proto3" json:"gtin,omitempty"`
+	BrandOwner string `protobuf:"bytes,2,opt,name=brand_owner,json=brandOwner,proto3" json:"brand_owner,omitempty"`
+	BrandName  string `protobuf:"bytes,3,opt,name=brand_name,json=brandName,proto3" json:"brand_name,omitempty"`
+	// The label serving size, in serving_size_unit (typically g or ml).
+	ServingSize     float32 `protobuf:"fixed32,4,opt,name=serving_size,json=servingSize,proto3" json:"serving_size,omitempty"`
+	ServingSizeUnit string  `protobuf:"bytes,5,opt,name=serving_size_unit,json=servingSizeUnit,proto3" json:"serving_size_unit,omitempty"`
+	// The household description of the serving, e.g. "1 cup".
+	HouseholdServing string `protobuf:"bytes,6,opt,name=household_serving,json=householdServing,proto3" json:"household_serving,omitempty"`
+	Category         string `protobuf:"bytes,7,opt,name=category,proto3" json:"category,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BrandedFood) Reset() {
+	*x = BrandedFood{}
+	mi := &file_ndb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BrandedFood) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrandedFood) ProtoMessage() {}
+
+func (x *BrandedFood) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrandedFood.ProtoReflect.Descriptor instead.
+func (*BrandedFood) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BrandedFood) GetGtin() string {
+	if x != nil {
+		return x.Gtin
+	}
+	return ""
+}
+
+func (x *BrandedFood) GetBrandOwner() string {
+	if x != nil {
+		return x.BrandOwner
+	}
+	return ""
+}
+
+func (x *BrandedFood) GetBrandName() string {
+	if x != nil {
+		return x.BrandName
+	}
+	return ""
+}
+
+func (x *BrandedFood) GetServingSize() float32 {
+	if x != nil {
+		return x.ServingSize
+	}
+	return 0
+}
+
+func (x *BrandedFood) GetServingSizeUnit() string {
+	if x != nil {
+		return x.ServingSizeUnit
+	}
+	return ""
+}
+
+func (x *BrandedFood) GetHouseholdServing() string {
+	if x != nil {
+		return x.HouseholdServing
+	}
+	return ""
+}
+
+func (x *BrandedFood) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+// A FoodNutrient is a measured nutrient value for a food item.
+type FoodNutrient struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	NutrientId int32                  `protobuf:"varint,1,opt,name=nutrient_id,json=nutrientId,proto3" json:"nutrient_id,omitempty"`
+	// Edible portion (amount in 100 grams).
+	Value float32 `protobuf:"fixed32,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Number of data points used to calculate the value.
+	DataPoints int32 `protobuf:"varint,3,opt,name=data_points,json=dataPoints,proto3" json:"data_points,omitempty"`
+	// The overlay that added or corrected the value, or empty if it is from the
+	// USDA data.
+	Source string `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	// The statistics of the value from an SR release, if there are any.
+	Stats         *NutrientStats `protobuf:"bytes,5,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FoodNutrient) Reset() {
+	*x = FoodNutrient{}
+	mi := &file_ndb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FoodNutrient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FoodNutrient) ProtoMessage() {}
+
+func (x *FoodNutrient) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FoodNutrient.ProtoReflect.Descriptor instead.
+func (*FoodNutrient) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FoodNutrient) GetNutrientId() int32 {
+	if x != nil {
+		return x.NutrientId
+	}
+	return 0
+}
+
+func (x *FoodNutrient) GetValue() float32 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *FoodNutrient) GetDataPoints() int32 {
+	if x != nil {
+		return x.DataPoints
+	}
+	return 0
+}
+
+func (x *FoodNutrient) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *FoodNutrient) GetStats() *NutrientStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+// The statistics and sources of an SR nutrient value.
+type NutrientStats struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Standard error of the mean.
+	StdError *float32 `protobuf:"fixed32,1,opt,name=std_error,json=stdError,proto3,oneof" json:"std_error,omitempty"`
+	// Code indicating the type of data.
+	SourceCode string `protobuf:"bytes,2,opt,name=source_code,json=sourceCode,proto3" json:"source_code,omitempty"`
+	// Code indicating how the value was calculated.
+	DerivationCode string `protobuf:"bytes,3,opt,name=derivation_code,json=derivationCode,proto3" json:"derivation_code,omitempty"`
+	// The NDBID of the food the value was imputed from, if any.
+	RefNdbId string `protobuf:"bytes,4,opt,name=ref_ndb_id,json=refNdbId,proto3" json:"ref_ndb_id,omitempty"`
+	// Whether the nutrient was added for fortification or enrichment.
+	Added bool `protobuf:"varint,5,opt,name=added,proto3" json:"added,omitempty"`
+	// The number of analytical studies.
+	Studies          *int32   `protobuf:"varint,6,opt,name=studies,proto3,oneof" json:"studies,omitempty"`
+	Min              *float32 `protobuf:"fixed32,7,opt,name=min,proto3,oneof" json:"min,omitempty"`
+	Max              *float32 `protobuf:"fixed32,8,opt,name=max,proto3,oneof" json:"max,omitempty"`
+	DegreesOfFreedom *int32   `protobuf:"varint,9,opt,name=degrees_of_freedom,json=degreesOfFreedom,proto3,oneof" json:"degrees_of_freedom,omitempty"`
+	// The lower and upper 95% error bounds.
+	LowerErrorBound *float32 `protobuf:"fixed32,10,opt,name=lower_error_bound,json=lowerErrorBound,proto3,oneof" json:"lower_error_bound,omitempty"`
+	UpperErrorBound *float32 `protobuf:"fixed32,11,opt,name=upper_error_bound,json=upperErrorBound,proto3,oneof" json:"upper_error_bound,omitempty"`
+	// Statistical comments.
+	Comments string `protobuf:"bytes,12,opt,name=comments,proto3" json:"comments,omitempty"`
+	// The month the value was added or last modified, as MM/YYYY.
+	Modified string `protobuf:"bytes,13,opt,name=modified,proto3" json:"modified,omitempty"`
+	// Confidence code indicating the quality of the data.
+	ConfidenceCode string `protobuf:"bytes,14,opt,name=confidence_code,json=confidenceCode,proto3" json:"confidence_code,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NutrientStats) Reset() {
+	*x = NutrientStats{}
+	mi := &file_ndb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NutrientStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NutrientStats) ProtoMessage() {}
+
+func (x *NutrientStats) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NutrientStats.ProtoReflect.Descriptor instead.
+func (*NutrientStats) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NutrientStats) GetStdError() float32 {
+	if x != nil && x.StdError != nil {
+		return *x.StdError
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetSourceCode() string {
+	if x != nil {
+		return x.SourceCode
+	}
+	return ""
+}
+
+func (x *NutrientStats) GetDerivationCode() string {
+	if x != nil {
+		return x.DerivationCode
+	}
+	return ""
+}
+
+func (x *NutrientStats) GetRefNdbId() string {
+	if x != nil {
+		return x.RefNdbId
+	}
+	return ""
+}
+
+func (x *NutrientStats) GetAdded() bool {
+	if x != nil {
+		return x.Added
+	}
+	return false
+}
+
+func (x *NutrientStats) GetStudies() int32 {
+	if x != nil && x.Studies != nil {
+		return *x.Studies
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetMin() float32 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetMax() float32 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetDegreesOfFreedom() int32 {
+	if x != nil && x.DegreesOfFreedom != nil {
+		return *x.DegreesOfFreedom
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetLowerErrorBound() float32 {
+	if x != nil && x.LowerErrorBound != nil {
+		return *x.LowerErrorBound
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetUpperErrorBound() float32 {
+	if x != nil && x.UpperErrorBound != nil {
+		return *x.UpperErrorBound
+	}
+	return 0
+}
+
+func (x *NutrientStats) GetComments() string {
+	if x != nil {
+		return x.Comments
+	}
+	return ""
+}
+
+func (x *NutrientStats) GetModified() string {
+	if x != nil {
+		return x.Modified
+	}
+	return ""
+}
+
+func (x *NutrientStats) GetConfidenceCode() string {
+	if x != nil {
+		return x.ConfidenceCode
+	}
+	return ""
+}
+
+// A Weight is a common measure of a food item. The nutrient value for the
+// measure is value * weight_g / 100.
+type Weight struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Sequence int32                  `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Amount of units (e.g. 1 in 1 cup).
+	Amount      float32 `protobuf:"fixed32,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Description string  `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	// The weight in grams for this unit.
+	WeightG float32 `protobuf:"fixed32,4,opt,name=weight_g,json=weightG,proto3" json:"weight_g,omitempty"`
+	// The number of data points and the standard deviation of weight_g, if they
+	// are known.
+	DataPoints    *int32   `protobuf:"varint,5,opt,name=data_points,json=dataPoints,proto3,oneof" json:"data_points,omitempty"`
+	StdDev        *float32 `protobuf:"fixed32,6,opt,name=std_dev,json=stdDev,proto3,oneof" json:"std_dev,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Weight) Reset() {
+	*x = Weight{}
+	mi := &file_ndb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Weight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Weight) ProtoMessage() {}
+
+func (x *Weight) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Weight.ProtoReflect.Descriptor instead.
+func (*Weight) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Weight) GetSequence() int32 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *Weight) GetAmount() float32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Weight) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Weight) GetWeightG() float32 {
+	if x != nil {
+		return x.WeightG
+	}
+	return 0
+}
+
+func (x *Weight) GetDataPoints() int32 {
+	if x != nil && x.DataPoints != nil {
+		return *x.DataPoints
+	}
+	return 0
+}
+
+func (x *Weight) GetStdDev() float32 {
+	if x != nil && x.StdDev != nil {
+		return *x.StdDev
+	}
+	return 0
+}
+
+// A Footnote is a comment on a food, one of its weights or one of its nutrient
+// values.
+type Footnote struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sequence number. For a measure footnote, the sequence of the Weight.
+	Number int32 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	// "D" for the food description, "M" for a measure or "N" for a nutrient.
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// For a nutrient footnote, the nutrient_id it describes.
+	NutrientId    int32  `protobuf:"varint,3,opt,name=nutrient_id,json=nutrientId,proto3" json:"nutrient_id,omitempty"`
+	Text          string `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Footnote) Reset() {
+	*x = Footnote{}
+	mi := &file_ndb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Footnote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Footnote) ProtoMessage() {}
+
+func (x *Footnote) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Footnote.ProtoReflect.Descriptor instead.
+func (*Footnote) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Footnote) GetNumber() int32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *Footnote) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Footnote) GetNutrientId() int32 {
+	if x != nil {
+		return x.NutrientId
+	}
+	return 0
+}
+
+func (x *Footnote) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// A Database is a whole database, with the foods sorted by NDBID.
+type Database struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FoodGroups    []*FoodGroup           `protobuf:"bytes,1,rep,name=food_groups,json=foodGroups,proto3" json:"food_groups,omitempty"`
+	Nutrients     []*Nutrient            `protobuf:"bytes,2,rep,name=nutrients,proto3" json:"nutrients,omitempty"`
+	Foods         []*Food                `protobuf:"bytes,3,rep,name=foods,proto3" json:"foods,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Database) Reset() {
+	*x = Database{}
+	mi := &file_ndb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Database) ProtoMessage() {}
+
+func (x *Database) ProtoReflect() protoreflect.Message {
+	mi := &file_ndb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Database.ProtoReflect.Descriptor instead.
+func (*Database) Descriptor() ([]byte, []int) {
+	return file_ndb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Database) GetFoodGroups() []*FoodGroup {
+	if x != nil {
+		return x.FoodGroups
+	}
+	return nil
+}
+
+func (x *Database) GetNutrients() []*Nutrient {
+	if x != nil {
+		return x.Nutrients
+	}
+	return nil
+}
+
+func (x *Database) GetFoods() []*Food {
+	if x != nil {
+		return x.Foods
+	}
+	return nil
+}
+
+var File_ndb_proto protoreflect.FileDescriptor
+
+const file_ndb_proto_rawDesc = "" +
+	"\n" +
+	"\tndb.proto\x12\x03ndb\"\xb8\x01\n" +
+	"\bNutrient\x12\x1f\n" +
+	"\vnutrient_id\x18\x01 \x01(\x05R\n" +
+	"nutrientId\x12\x14\n" +
+	"\x05units\x18\x02 \x01(\tR\x05units\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"sort_order\x18\x04 \x01(\x05R\tsortOrder\x12\x18\n" +
+	"\atagname\x18\x05 \x01(\tR\atagname\x12\x1a\n" +
+	"\bdecimals\x18\x06 \x01(\x05R\bdecimals\"L\n" +
+	"\tFoodGroup\x12\x1d\n" +
+	"\n" +
+	"group_code\x18\x01 \x01(\x05R\tgroupCode\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"\xe7\x06\n" +
+	"\x04Food\x12\x15\n" +
+	"\x06ndb_id\x18\x01 \x01(\tR\x05ndbId\x12\x15\n" +
+	"\x06fdc_id\x18\x02 \x01(\x05R\x05fdcId\x12\x1d\n" +
+	"\n" +
+	"food_group\x18\x03 \x01(\x05R\tfoodGroup\x12)\n" +
+	"\x10long_description\x18\x04 \x01(\tR\x0flongDescription\x12+\n" +
+	"\x11short_description\x18\x05 \x01(\tR\x10shortDescription\x12!\n" +
+	"\fcommon_names\x18\x06 \x01(\tR\vcommonNames\x12'\n" +
+	"\x0fscientific_name\x18\a \x01(\tR\x0escientificName\x12\"\n" +
+	"\fmanufacturer\x18\b \x01(\tR\fmanufacturer\x12 \n" +
+	"\vingredients\x18\t \x01(\tR\vingredients\x12-\n" +
+	"\x12refuse_description\x18\n" +
+	" \x01(\tR\x11refuseDescription\x12\x16\n" +
+	"\x06refuse\x18\v \x01(\x05R\x06refuse\x12/\n" +
+	"\tnutrients\x18\f \x03(\v2\x11.ndb.FoodNutrientR\tnutrients\x12%\n" +
+	"\aweights\x18\r \x03(\v2\v.ndb.WeightR\aweights\x12*\n" +
+	"\abranded\x18\x0e \x01(\v2\x10.ndb.BrandedFoodR\abranded\x12\x16\n" +
+	"\x06source\x18\x0f \x01(\tR\x06source\x12+\n" +
+	"\tfootnotes\x18\x10 \x03(\v2\r.ndb.FootnoteR\tfootnotes\x12\x16\n" +
+	"\x06survey\x18\x11 \x01(\bR\x06survey\x12,\n" +
+	"\x0fnitrogen_factor\x18\x12 \x01(\x02H\x00R\x0enitrogenFactor\x88\x01\x01\x12*\n" +
+	"\x0eprotein_factor\x18\x13 \x01(\x02H\x01R\rproteinFactor\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"fat_factor\x18\x14 \x01(\x02H\x02R\tfatFactor\x88\x01\x01\x124\n" +
+	"\x13carbohydrate_factor\x18\x15 \x01(\x02H\x03R\x12carbohydrateFactor\x88\x01\x01B\x12\n" +
+	"\x10_nitrogen_factorB\x11\n" +
+	"\x0f_protein_factorB\r\n" +
+	"\v_fat_factorB\x16\n" +
+	"\x14_carbohydrate_factor\"\xf9\x01\n" +
+	"\vBrandedFood\x12\x12\n" +
+	"\x04gtin\x18\x01 \x01(\tR\x04gtin\x12\x1f\n" +
+	"\vbrand_owner\x18\x02 \x01(\tR\n" +
+	"brandOwner\x12\x1d\n" +
+	"\n" +
+	"brand_name\x18\x03 \x01(\tR\tbrandName\x12!\n" +
+	"\fserving_size\x18\x04 \x01(\x02R\vservingSize\x12*\n" +
+	"\x11serving_size_unit\x18\x05 \x01(\tR\x0fservingSizeUnit\x12+\n" +
+	"\x11household_serving\x18\x06 \x01(\tR\x10householdServing\x12\x1a\n" +
+	"\bcategory\x18\a \x01(\tR\bcategory\"\xa8\x01\n" +
+	"\fFoodNutrient\x12\x1f\n" +
+	"\vnutrient_id\x18\x01 \x01(\x05R\n" +
+	"nutrientId\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x02R\x05value\x12\x1f\n" +
+	"\vdata_points\x18\x03 \x01(\x05R\n" +
+	"dataPoints\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12(\n" +
+	"\x05stats\x18\x05 \x01(\v2\x12.ndb.NutrientStatsR\x05stats\"\xdf\x04\n" +
+	"\rNutrientStats\x12 \n" +
+	"\tstd_error\x18\x01 \x01(\x02H\x00R\bstdError\x88\x01\x01\x12\x1f\n" +
+	"\vsource_code\x18\x02 \x01(\tR\n" +
+	"sourceCode\x12'\n" +
+	"\x0fderivation_code\x18\x03 \x01(\tR\x0ederivationCode\x12\x1c\n" +
+	"\n" +
+	"ref_ndb_id\x18\x04 \x01(\tR\brefNdbId\x12\x14\n" +
+	"\x05added\x18\x05 \x01(\bR\x05added\x12\x1d\n" +
+	"\astudies\x18\x06 \x01(\x05H\x01R\astudies\x88\x01\x01\x12\x15\n" +
+	"\x03min\x18\a \x01(\x02H\x02R\x03min\x88\x01\x01\x12\x15\n" +
+	"\x03max\x18\b \x01(\x02H\x03R\x03max\x88\x01\x01\x121\n" +
+	"\x12degrees_of_freedom\x18\t \x01(\x05H\x04R\x10degreesOfFreedom\x88\x01\x01\x12/\n" +
+	"\x11lower_error_bound\x18\n" +
+	" \x01(\x02H\x05R\x0flowerErrorBound\x88\x01\x01\x12/\n" +
+	"\x11upper_error_bound\x18\v \x01(\x02H\x06R\x0fupperErrorBound\x88\x01\x01\x12\x1a\n" +
+	"\bcomments\x18\f \x01(\tR\bcomments\x12\x1a\n" +
+	"\bmodified\x18\r \x01(\tR\bmodified\x12'\n" +
+	"\x0fconfidence_code\x18\x0e \x01(\tR\x0econfidenceCodeB\f\n" +
+	"\n" +
+	"_std_errorB\n" +
+	"\n" +
+	"\b_studiesB\x06\n" +
+	"\x04_minB\x06\n" +
+	"\x04_maxB\x15\n" +
+	"\x13_degrees_of_freedomB\x14\n" +
+	"\x12_lower_error_boundB\x14\n" +
+	"\x12_upper_error_bound\"\xd9\x01\n" +
+	"\x06Weight\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x05R\bsequence\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x02R\x06amount\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x19\n" +
+	"\bweight_g\x18\x04 \x01(\x02R\aweightG\x12$\n" +
+	"\vdata_points\x18\x05 \x01(\x05H\x00R\n" +
+	"dataPoints\x88\x01\x01\x12\x1c\n" +
+	"\astd_dev\x18\x06 \x01(\x02H\x01R\x06stdDev\x88\x01\x01B\x0e\n" +
+	"\f_data_pointsB\n" +
+	"\n" +
+	"\b_std_dev\"k\n" +
+	"\bFootnote\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\x05R\x06number\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1f\n" +
+	"\vnutrient_id\x18\x03 \x01(\x05R\n" +
+	"nutrientId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\"\x89\x01\n" +
+	"\bDatabase\x12/\n" +
+	"\vfood_groups\x18\x01 \x03(\v2\x0e.ndb.FoodGroupR\n" +
+	"foodGroups\x12+\n" +
+	"\tnutrients\x18\x02 \x03(\v2\r.ndb.NutrientR\tnutrients\x12\x1f\n" +
+	"\x05foods\x18\x03 \x03(\v2\t.ndb.FoodR\x05foodsB\"Z github.com/rsesek/usda-ndb/ndbpbb\x06proto3"
+
+var (
+	file_ndb_proto_rawDescOnce sync.Once
+	file_ndb_proto_rawDescData []byte
+)
+
+func file_ndb_proto_rawDescGZIP() []byte {
+	file_ndb_proto_rawDescOnce.Do(func() {
+		file_ndb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ndb_proto_rawDesc), len(file_ndb_proto_rawDesc)))
+	})
+	return file_ndb_proto_rawDescData
+}
+
+var file_ndb_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_ndb_proto_goTypes = []any{
+	(*Nutrient)(nil),      // 0: ndb.Nutrient
+	(*FoodGroup)(nil),     // 1: ndb.FoodGroup
+	(*Food)(nil),          // 2: ndb.Food
+	(*BrandedFood)(nil),   // 3: ndb.BrandedFood
+	(*FoodNutrient)(nil),  // 4: ndb.FoodNutrient
+	(*NutrientStats)(nil), // 5: ndb.NutrientStats
+	(*Weight)(nil),        // 6: ndb.Weight
+	(*Footnote)(nil),      // 7: ndb.Footnote
+	(*Database)(nil),      // 8: ndb.Database
+}
+var file_ndb_proto_depIdxs = []int32{
+	4, // 0: ndb.Food.nutrients:type_name -> ndb.FoodNutrient
+	6, // 1: ndb.Food.weights:type_name -> ndb.Weight
+	3, // 2: ndb.Food.branded:type_name -> ndb.BrandedFood
+	7, // 3: ndb.Food.footnotes:type_name -> ndb.Footnote
+	5, // 4: ndb.FoodNutrient.stats:type_name -> ndb.NutrientStats
+	1, // 5: ndb.Database.food_groups:type_name -> ndb.FoodGroup
+	0, // 6: ndb.Database.nutrients:type_name -> ndb.Nutrient
+	2, // 7: ndb.Database.foods:type_name -> ndb.Food
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_ndb_proto_init() }
+func file_ndb_proto_init() {
+	if File_ndb_proto != nil {
+		return
+	}
+	file_ndb_proto_msgTypes[2].OneofWrappers = []any{}
+	file_ndb_proto_msgTypes[5].OneofWrappers = []any{}
+	file_ndb_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ndb_proto_rawDesc), len(file_ndb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ndb_proto_goTypes,
+		DependencyIndexes: file_ndb_proto_depIdxs,
+		MessageInfos:      file_ndb_proto_msgTypes,
+	}.Build()
+	File_ndb_proto = out.File
+	file_ndb_proto_goTypes = nil
+	file_ndb_proto_depIdxs = nil
+}
@@ -0,0 +1,576 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// The NutrientDB service, which serves the nutrient database.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: nutrientdb.proto
+
+package ndbpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetFoodRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NdbId         string                 `protobuf:"bytes,1,opt,name=ndb_id,json=ndbId,proto3" json:"ndb_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFoodRequest) Reset() {
+	*x = GetFoodRequest{}
+	mi := &file_nutrientdb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFoodRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFoodRequest) ProtoMessage() {}
+
+func (x *GetFoodRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFoodRequest.ProtoReflect.Descriptor instead.
+func (*GetFoodRequest) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetFoodRequest) GetNdbId() string {
+	if x != nil {
+		return x.NdbId
+	}
+	return ""
+}
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Space-separated words to search the food descriptions for.
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// The most results to return, or 0 for all of them.
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_nutrientdb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*SearchResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_nutrientdb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchResponse) GetResults() []*SearchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// A SearchResult is a food that matches a search, without its nutrients.
+type SearchResult struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	NdbId        string                 `protobuf:"bytes,1,opt,name=ndb_id,json=ndbId,proto3" json:"ndb_id,omitempty"`
+	FoodGroup    int32                  `protobuf:"varint,2,opt,name=food_group,json=foodGroup,proto3" json:"food_group,omitempty"`
+	Description  string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Manufacturer string                 `protobuf:"bytes,4,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	// The number of words of the query that match the food.
+	Score         int32 `protobuf:"varint,5,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResult) Reset() {
+	*x = SearchResult{}
+	mi := &file_nutrientdb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResult) ProtoMessage() {}
+
+func (x *SearchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
+func (*SearchResult) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchResult) GetNdbId() string {
+	if x != nil {
+		return x.NdbId
+	}
+	return ""
+}
+
+func (x *SearchResult) GetFoodGroup() int32 {
+	if x != nil {
+		return x.FoodGroup
+	}
+	return 0
+}
+
+func (x *SearchResult) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *SearchResult) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *SearchResult) GetScore() int32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type ListFoodGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFoodGroupsRequest) Reset() {
+	*x = ListFoodGroupsRequest{}
+	mi := &file_nutrientdb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFoodGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFoodGroupsRequest) ProtoMessage() {}
+
+func (x *ListFoodGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFoodGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListFoodGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{4}
+}
+
+type ListFoodGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FoodGroups    []*FoodGroup           `protobuf:"bytes,1,rep,name=food_groups,json=foodGroups,proto3" json:"food_groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFoodGroupsResponse) Reset() {
+	*x = ListFoodGroupsResponse{}
+	mi := &file_nutrientdb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFoodGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFoodGroupsResponse) ProtoMessage() {}
+
+func (x *ListFoodGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFoodGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListFoodGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListFoodGroupsResponse) GetFoodGroups() []*FoodGroup {
+	if x != nil {
+		return x.FoodGroups
+	}
+	return nil
+}
+
+type ListNutrientsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNutrientsRequest) Reset() {
+	*x = ListNutrientsRequest{}
+	mi := &file_nutrientdb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNutrientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNutrientsRequest) ProtoMessage() {}
+
+func (x *ListNutrientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNutrientsRequest.ProtoReflect.Descriptor instead.
+func (*ListNutrientsRequest) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{6}
+}
+
+type ListNutrientsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nutrients     []*Nutrient            `protobuf:"bytes,1,rep,name=nutrients,proto3" json:"nutrients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNutrientsResponse) Reset() {
+	*x = ListNutrientsResponse{}
+	mi := &file_nutrientdb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNutrientsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNutrientsResponse) ProtoMessage() {}
+
+func (x *ListNutrientsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNutrientsResponse.ProtoReflect.Descriptor instead.
+func (*ListNutrientsResponse) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListNutrientsResponse) GetNutrients() []*Nutrient {
+	if x != nil {
+		return x.Nutrients
+	}
+	return nil
+}
+
+type ListFoodsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only list the foods in this food group, if it is not 0.
+	FoodGroup     int32 `protobuf:"varint,1,opt,name=food_group,json=foodGroup,proto3" json:"food_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFoodsRequest) Reset() {
+	*x = ListFoodsRequest{}
+	mi := &file_nutrientdb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFoodsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFoodsRequest) ProtoMessage() {}
+
+func (x *ListFoodsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nutrientdb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFoodsRequest.ProtoReflect.Descriptor instead.
+func (*ListFoodsRequest) Descriptor() ([]byte, []int) {
+	return file_nutrientdb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListFoodsRequest) GetFoodGroup() int32 {
+	if x != nil {
+		return x.FoodGroup
+	}
+	return 0
+}
+
+var File_nutrientdb_proto protoreflect.FileDescriptor
+
+const file_nutrientdb_proto_rawDesc = "" +
+	"\n" +
+	"\x10nutrientdb.proto\x12\x03ndb\x1a\tndb.proto\"'\n" +
+	"\x0eGetFoodRequest\x12\x15\n" +
+	"\x06ndb_id\x18\x01 \x01(\tR\x05ndbId\";\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"=\n" +
+	"\x0eSearchResponse\x12+\n" +
+	"\aresults\x18\x01 \x03(\v2\x11.ndb.SearchResultR\aresults\"\xa0\x01\n" +
+	"\fSearchResult\x12\x15\n" +
+	"\x06ndb_id\x18\x01 \x01(\tR\x05ndbId\x12\x1d\n" +
+	"\n" +
+	"food_group\x18\x02 \x01(\x05R\tfoodGroup\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\"\n" +
+	"\fmanufacturer\x18\x04 \x01(\tR\fmanufacturer\x12\x14\n" +
+	"\x05score\x18\x05 \x01(\x05R\x05score\"\x17\n" +
+	"\x15ListFoodGroupsRequest\"I\n" +
+	"\x16ListFoodGroupsResponse\x12/\n" +
+	"\vfood_groups\x18\x01 \x03(\v2\x0e.ndb.FoodGroupR\n" +
+	"foodGroups\"\x16\n" +
+	"\x14ListNutrientsRequest\"D\n" +
+	"\x15ListNutrientsResponse\x12+\n" +
+	"\tnutrients\x18\x01 \x03(\v2\r.ndb.NutrientR\tnutrients\"1\n" +
+	"\x10ListFoodsRequest\x12\x1d\n" +
+	"\n" +
+	"food_group\x18\x01 \x01(\x05R\tfoodGroup2\xae\x02\n" +
+	"\n" +
+	"NutrientDB\x12)\n" +
+	"\aGetFood\x12\x13.ndb.GetFoodRequest\x1a\t.ndb.Food\x121\n" +
+	"\x06Search\x12\x12.ndb.SearchRequest\x1a\x13.ndb.SearchResponse\x12I\n" +
+	"\x0eListFoodGroups\x12\x1a.ndb.ListFoodGroupsRequest\x1a\x1b.ndb.ListFoodGroupsResponse\x12F\n" +
+	"\rListNutrients\x12\x19.ndb.ListNutrientsRequest\x1a\x1a.ndb.ListNutrientsResponse\x12/\n" +
+	"\tListFoods\x12\x15.ndb.ListFoodsRequest\x1a\t.ndb.Food0\x01B\"Z github.com/rsesek/usda-ndb/ndbpbb\x06proto3"
+
+var (
+	file_nutrientdb_proto_rawDescOnce sync.Once
+	file_nutrientdb_proto_rawDescData []byte
+)
+
+func file_nutrientdb_proto_rawDescGZIP() []byte {
+	file_nutrientdb_proto_rawDescOnce.Do(func() {
+		file_nutrientdb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_nutrientdb_proto_rawDesc), len(file_nutrientdb_proto_rawDesc)))
+	})
+	return file_nutrientdb_proto_rawDescData
+}
+
+var file_nutrientdb_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_nutrientdb_proto_goTypes = []any{
+	(*GetFoodRequest)(nil),         // 0: ndb.GetFoodRequest
+	(*SearchRequest)(nil),          // 1: ndb.SearchRequest
+	(*SearchResponse)(nil),         // 2: ndb.SearchResponse
+	(*SearchResult)(nil),           // 3: ndb.SearchResult
+	(*ListFoodGroupsRequest)(nil),  // 4: ndb.ListFoodGroupsRequest
+	(*ListFoodGroupsResponse)(nil), // 5: ndb.ListFoodGroupsResponse
+	(*ListNutrientsRequest)(nil),   // 6: ndb.ListNutrientsRequest
+	(*ListNutrientsResponse)(nil),  // 7: ndb.ListNutrientsResponse
+	(*ListFoodsRequest)(nil),       // 8: ndb.ListFoodsRequest
+	(*FoodGroup)(nil),              // 9: ndb.FoodGroup
+	(*Nutrient)(nil),               // 10: ndb.Nutrient
+	(*Food)(nil),                   // 11: ndb.Food
+}
+var file_nutrientdb_proto_depIdxs = []int32{
+	3,  // 0: ndb.SearchResponse.results:type_name -> ndb.SearchResult
+	9,  // 1: ndb.ListFoodGroupsResponse.food_groups:type_name -> ndb.FoodGroup
+	10, // 2: ndb.ListNutrientsResponse.nutrients:type_name -> ndb.Nutrient
+	0,  // 3: ndb.NutrientDB.GetFood:input_type -> ndb.GetFoodRequest
+	1,  // 4: ndb.NutrientDB.Search:input_type -> ndb.SearchRequest
+	4,  // 5: ndb.NutrientDB.ListFoodGroups:input_type -> ndb.ListFoodGroupsRequest
+	6,  // 6: ndb.NutrientDB.ListNutrients:input_type -> ndb.ListNutrientsRequest
+	8,  // 7: ndb.NutrientDB.ListFoods:input_type -> ndb.ListFoodsRequest
+	11, // 8: ndb.NutrientDB.GetFood:output_type -> ndb.Food
+	2,  // 9: ndb.NutrientDB.Search:output_type -> ndb.SearchResponse
+	5,  // 10: ndb.NutrientDB.ListFoodGroups:output_type -> ndb.ListFoodGroupsResponse
+	7,  // 11: ndb.NutrientDB.ListNutrients:output_type -> ndb.ListNutrientsResponse
+	11, // 12: ndb.NutrientDB.ListFoods:output_type -> ndb.Food
+	8,  // [8:13] is the sub-list for method output_type
+	3,  // [3:8] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_nutrientdb_proto_init() }
+func file_nutrientdb_proto_init() {
+	if File_nutrientdb_proto != nil {
+		return
+	}
+	file_ndb_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nutrientdb_proto_rawDesc), len(file_nutrientdb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nutrientdb_proto_goTypes,
+		DependencyIndexes: file_nutrientdb_proto_depIdxs,
+		MessageInfos:      file_nutrientdb_proto_msgTypes,
+	}.Build()
+	File_nutrientdb_proto = out.File
+	file_nutrientdb_proto_goTypes = nil
+	file_nutrientdb_proto_depIdxs = nil
+}
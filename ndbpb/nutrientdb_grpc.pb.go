@@ -0,0 +1,302 @@
+//
+// USDA-NDB Viewer
+// Copyright 2013 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// The NutrientDB service, which serves the nutrient database.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: nutrientdb.proto
+
+package ndbpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NutrientDB_GetFood_FullMethodName        = "/ndb.NutrientDB/GetFood"
+	NutrientDB_Search_FullMethodName         = "/ndb.NutrientDB/Search"
+	NutrientDB_ListFoodGroups_FullMethodName = "/ndb.NutrientDB/ListFoodGroups"
+	NutrientDB_ListNutrients_FullMethodName  = "/ndb.NutrientDB/ListNutrients"
+	NutrientDB_ListFoods_FullMethodName      = "/ndb.NutrientDB/ListFoods"
+)
+
+// NutrientDBClient is the client API for NutrientDB service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NutrientDBClient interface {
+	// Returns the food with an NDBID, or NOT_FOUND.
+	GetFood(ctx context.Context, in *GetFoodRequest, opts ...grpc.CallOption) (*Food, error)
+	// Finds the foods matching any of the words of a query, best first.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	ListFoodGroups(ctx context.Context, in *ListFoodGroupsRequest, opts ...grpc.CallOption) (*ListFoodGroupsResponse, error)
+	ListNutrients(ctx context.Context, in *ListNutrientsRequest, opts ...grpc.CallOption) (*ListNutrientsResponse, error)
+	// Streams the foods, sorted by NDBID.
+	ListFoods(ctx context.Context, in *ListFoodsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Food], error)
+}
+
+type nutrientDBClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNutrientDBClient(cc grpc.ClientConnInterface) NutrientDBClient {
+	return &nutrientDBClient{cc}
+}
+
+func (c *nutrientDBClient) GetFood(ctx context.Context, in *GetFoodRequest, opts ...grpc.CallOption) (*Food, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Food)
+	err := c.cc.Invoke(ctx, NutrientDB_GetFood_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nutrientDBClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, NutrientDB_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nutrientDBClient) ListFoodGroups(ctx context.Context, in *ListFoodGroupsRequest, opts ...grpc.CallOption) (*ListFoodGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFoodGroupsResponse)
+	err := c.cc.Invoke(ctx, NutrientDB_ListFoodGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nutrientDBClient) ListNutrients(ctx context.Context, in *ListNutrientsRequest, opts ...grpc.CallOption) (*ListNutrientsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNutrientsResponse)
+	err := c.cc.Invoke(ctx, NutrientDB_ListNutrients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nutrientDBClient) ListFoods(ctx context.Context, in *ListFoodsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Food], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NutrientDB_ServiceDesc.Streams[0], NutrientDB_ListFoods_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListFoodsRequest, Food]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NutrientDB_ListFoodsClient = grpc.ServerStreamingClient[Food]
+
+// NutrientDBServer is the server API for NutrientDB service.
+// All implementations must embed UnimplementedNutrientDBServer
+// for forward compatibility.
+type NutrientDBServer interface {
+	// Returns the food with an NDBID, or NOT_FOUND.
+	GetFood(context.Context, *GetFoodRequest) (*Food, error)
+	// Finds the foods matching any of the words of a query, best first.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	ListFoodGroups(context.Context, *ListFoodGroupsRequest) (*ListFoodGroupsResponse, error)
+	ListNutrients(context.Context, *ListNutrientsRequest) (*ListNutrientsResponse, error)
+	// Streams the foods, sorted by NDBID.
+	ListFoods(*ListFoodsRequest, grpc.ServerStreamingServer[Food]) error
+	mustEmbedUnimplementedNutrientDBServer()
+}
+
+// UnimplementedNutrientDBServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNutrientDBServer struct{}
+
+func (UnimplementedNutrientDBServer) GetFood(context.Context, *GetFoodRequest) (*Food, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFood not implemented")
+}
+func (UnimplementedNutrientDBServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedNutrientDBServer) ListFoodGroups(context.Context, *ListFoodGroupsRequest) (*ListFoodGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFoodGroups not implemented")
+}
+func (UnimplementedNutrientDBServer) ListNutrients(context.Context, *ListNutrientsRequest) (*ListNutrientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNutrients not implemented")
+}
+func (UnimplementedNutrientDBServer) ListFoods(*ListFoodsRequest, grpc.ServerStreamingServer[Food]) error {
+	return status.Errorf(codes.Unimplemented, "method ListFoods not implemented")
+}
+func (UnimplementedNutrientDBServer) mustEmbedUnimplementedNutrientDBServer() {}
+func (UnimplementedNutrientDBServer) testEmbeddedByValue()                    {}
+
+// UnsafeNutrientDBServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NutrientDBServer will
+// result in compilation errors.
+type UnsafeNutrientDBServer interface {
+	mustEmbedUnimplementedNutrientDBServer()
+}
+
+func RegisterNutrientDBServer(s grpc.ServiceRegistrar, srv NutrientDBServer) {
+	// If the following call pancis, it indicates UnimplementedNutrientDBServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NutrientDB_ServiceDesc, srv)
+}
+
+func _NutrientDB_GetFood_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFoodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NutrientDBServer).GetFood(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NutrientDB_GetFood_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NutrientDBServer).GetFood(ctx, req.(*GetFoodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NutrientDB_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NutrientDBServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NutrientDB_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NutrientDBServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NutrientDB_ListFoodGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFoodGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NutrientDBServer).ListFoodGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NutrientDB_ListFoodGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NutrientDBServer).ListFoodGroups(ctx, req.(*ListFoodGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NutrientDB_ListNutrients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNutrientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NutrientDBServer).ListNutrients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NutrientDB_ListNutrients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NutrientDBServer).ListNutrients(ctx, req.(*ListNutrientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NutrientDB_ListFoods_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListFoodsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NutrientDBServer).ListFoods(m, &grpc.GenericServerStream[ListFoodsRequest, Food]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NutrientDB_ListFoodsServer = grpc.ServerStreamingServer[Food]
+
+// NutrientDB_ServiceDesc is the grpc.ServiceDesc for NutrientDB service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NutrientDB_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ndb.NutrientDB",
+	HandlerType: (*NutrientDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetFood",
+			Handler:    _NutrientDB_GetFood_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _NutrientDB_Search_Handler,
+		},
+		{
+			MethodName: "ListFoodGroups",
+			Handler:    _NutrientDB_ListFoodGroups_Handler,
+		},
+		{
+			MethodName: "ListNutrients",
+			Handler:    _NutrientDB_ListNutrients_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListFoods",
+			Handler:       _NutrientDB_ListFoods_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nutrientdb.proto",
+}